@@ -1,8 +1,26 @@
 package main
 
-import "fluxio-backend/pkg/server"
+import (
+	"fmt"
+	"os"
+
+	"fluxio-backend/pkg/server"
+)
 
 func main() {
-	server.NewServer()
-	// app.Start()
+	command := "serve"
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+
+	switch command {
+	case "serve":
+		server.NewServer()
+	case "worker":
+		// Runs only the processing pipeline so transcoding can be scaled apart from the API.
+		server.StartWorker()
+	default:
+		fmt.Printf("Unknown command %q. Usage: %s [serve|worker]\n", command, os.Args[0])
+		os.Exit(1)
+	}
 }
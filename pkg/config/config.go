@@ -16,6 +16,8 @@ type Config struct {
 	Database DatabaseConfig `env:"DB"`
 	JWT      JWTConfig      `env:"JWT"`
 	VideoCfg VideoConfig    `env:"VIDEO"`
+	Storage  StorageConfig  `env:"STORAGE"`
+	Worker   WorkerConfig   `env:"WORKER"`
 }
 
 type ServerConfig struct {
@@ -41,11 +43,31 @@ type VideoConfig struct {
 	S3RawVideoBucketName    string `env:"BUCKET_RAW_NAME" default:""`
 	S3PublicVideoBucketName string `env:"BUCKET_PUBLIC_NAME" default:""`
 	S3ThumbnailBucketName   string `env:"THUMBNAIL_BUCKET_NAME" default:""`
+	S3StagingBucketName     string `env:"BUCKET_STAGING_NAME" default:""` // Private bucket without storage events the partial tus parts are buffered in
 	S3Region                string `env:"BUCKET_REGION" default:""`
 	S3AccessKey             string `env:"BUCKET_ACCESS_KEY" default:""`
 	S3SecretKey             string `env:"BUCKET_SECRET_KEY" default:""`
 	S3UploadCallbackSecret  string `env:"BUCKET_UPLOAD_CALLBACK_SECRET" default:""`
 	S3Endpoint              string `env:"BUCKET_ENDPOINT" default:""`
+	PublicBaseURL           string `env:"PUBLIC_BASE_URL" default:""`                // Base URL (e.g. a CDN) the public bucket is served from
+	ThumbnailWidths         string `env:"THUMBNAIL_WIDTHS" default:"320,640,1280"`   // Comma separated widths every thumbnail is stored in
+	ThumbnailFormats        string `env:"THUMBNAIL_FORMATS" default:"jpg,webp,avif"` // Comma separated formats out of jpg, webp and avif
+}
+
+type StorageConfig struct {
+	Driver             string `env:"DRIVER" default:"s3"`                                    // s3 for AWS S3 or MinIO, local to keep the files on the disk
+	LocalRoot          string `env:"LOCAL_ROOT" default:"./data/storage"`                    // Directory the local driver stores the buckets in
+	LocalBaseURL       string `env:"LOCAL_BASE_URL" default:"http://localhost:8080/storage"` // URL the API serves the local files at
+	LocalSigningSecret string `env:"LOCAL_SIGNING_SECRET" default:""`                        // Secret the local file URLs are signed with
+	EventSource        string `env:"EVENT_SOURCE" default:"webhook"`                         // webhook to receive the upload events, poll to list the raw bucket for them
+	EventPollSeconds   int    `env:"EVENT_POLL_SECONDS" default:"10"`                        // Interval the event source is polled at once it is drained, at least a second
+}
+
+type WorkerConfig struct {
+	Concurrency              int  `env:"CONCURRENCY" default:"2"`                  // Number of jobs processed in parallel by a worker process
+	Embedded                 bool `env:"EMBEDDED" default:"false"`                 // Also run a worker inside the API server. Meant for local setups, deployments run the worker command
+	SweepIntervalSeconds     int  `env:"SWEEP_INTERVAL_SECONDS" default:"300"`     // Interval of the stalled video sweeper. Zero disables it
+	ProcessingTimeoutMinutes int  `env:"PROCESSING_TIMEOUT_MINUTES" default:"120"` // Videos processing without an active job for longer are failed
 }
 
 const envPrefix = "FLUXIO"
@@ -13,9 +13,33 @@ const (
 	GinUserContextKey = "user"
 )
 
+// Storage callback related constants
+const (
+	CallbackSignatureHeader = "X-Fluxio-Signature"
+	CallbackTimestampHeader = "X-Fluxio-Timestamp"
+	CallbackSignatureMaxAge = 5 * time.Minute // Signed callbacks older or newer than this are rejected
+	MaxCallbackBodySize     = 1 * 1024 * 1024
+
+	StorageEventPollBatchSize   = 100             // Maximum number of objects or queue messages handled per poll
+	StorageEventPollClockSkew   = 5 * time.Minute // Allowed difference between the clocks of the storage and the backend
+	MinStorageEventPollInterval = time.Second     // Shorter poll intervals are raised to this so a drained source is not polled in a loop
+)
+
+// Video event stream related constants
+const (
+	VideoEventBufferSize        = 32               // Events kept for a subscriber before newer ones are dropped
+	VideoEventKeepAliveInterval = 15 * time.Second // Comments are sent at this interval so that proxies keep the stream open
+	VideoProgressReportInterval = 5 * time.Second  // Minimum time between two stored progress updates of an ffmpeg run
+
+	VideoEventChannel          = "video_events"  // Postgres channel the events of every process are sent through
+	MaxVideoEventPayloadSize   = 7999            // Postgres rejects notification payloads of 8000 bytes or more
+	VideoEventListenRetryDelay = 5 * time.Second // Wait before listening again after the connection failed
+)
+
 const (
 	MaxVideoURLRegenerateRetryCount       = 4
 	MaxVideoThumbnailRegenerateRetryCount = 3
+	MaxVideoProcessingStageRetryCount     = 5
 )
 
 const (
@@ -23,9 +47,101 @@ const (
 	PreSignedVidTempDownloadURLExpireTime = 1 * time.Hour
 )
 
+// Multipart upload related constants
+const (
+	MultipartUploadPartSize = 64 * 1024 * 1024 // Recommended part size of 64 MB. S3 requires at least 5 MB except for the last part
+	MaxMultipartUploadParts = 10000
+
+	TusVersion          = "1.0.0"
+	TusUploadPartSize   = 16 * 1024 * 1024 // Chunks are buffered into parts of this size before they are sent to the storage
+	MaxTusUploadSize    = TusUploadPartSize * MaxMultipartUploadParts
+	TusUploadExpireTime = 24 * time.Hour // Extended with every received chunk
+
+	// Prefix of the objects in the staging bucket which hold the received bytes of a tus upload that do not fill a part yet
+	TusPendingDataPrefix = "tus-pending/"
+)
+
 const (
 	VidSizeDecimalPrecision = 3
 	TotalThumbnailCount     = 3
+	MinThumbnailWidth       = 16
+	MaxThumbnailWidth       = 3840 // Wider thumbnails are larger than the frames of most videos
+)
+
+// Thumbnail frame selection related constants
+const (
+	MaxThumbnailCandidateCount = 40   // Scene changes closer than length/count to the previous candidate are skipped
+	MinThumbnailCandidateCount = 10   // A candidate is taken at least every length/count even without a scene change
+	ThumbnailSceneThreshold    = 0.3  // Scene change score of ffmpeg between 0 and 1
+	ThumbnailAnalysisWidth     = 320  // Candidates are scaled down to this width before they are scored
+	ThumbnailMinBrightness     = 20   // Mean luma below which a frame counts as black
+	ThumbnailMaxBrightness     = 235  // Mean luma above which a frame counts as blown out
+	ThumbnailMinContrast       = 10   // Luma standard deviation below which a frame counts as uniform
+	ThumbnailSharpnessNorm     = 500. // Laplacian variance at which a frame counts as fully sharp
+)
+
+// Thumbnail placeholder related constants
+const (
+	BlurHashXComponents      = 4
+	BlurHashYComponents      = 3
+	BlurHashSampleWidth      = 32 // Thumbnails are scaled down to this width before the BlurHash is computed
+	ThumbnailLQIPWidth       = 16
+	ThumbnailLQIPJPEGQuality = 50
+)
+
+// Sprite sheet related constants
+const (
+	SpriteFrameInterval = 5 // Seconds between two frames sampled for the scrubbing previews
+	SpriteFrameWidth    = 160
+	SpriteFrameHeight   = 90
+	SpriteColumns       = 10
+	SpriteRows          = 10
+	SpriteFormat        = "jpg" // jpg or webp
+	SpriteDirectoryName = "sprites"
+	SpriteIndexName     = "sprites.vtt"
+)
+
+// Animated preview related constants
+const (
+	PreviewSegmentCount    = 4 // Segments of the video stitched into the teaser
+	PreviewSegmentDuration = 2 // Length of a segment in seconds
+	PreviewShortEdge       = 180
+	PreviewFrameRate       = 12
+	PreviewDirectoryName   = "preview"
+	PreviewWebPName        = "preview.webp"
+	PreviewMP4Name         = "preview.mp4"
+)
+
+// Job queue related constants
+const (
+	JobPollInterval                     = 2 * time.Second
+	JobLeaseDuration                    = 2 * time.Minute
+	JobRetryDelay                       = 30 * time.Second
+	MaxVideoProcessingJobAttempts       = 5
+	MaxThumbnailRegenerationJobAttempts = 3
+
+	VideoProcessingRetryBaseDelay = 30 * time.Second // Doubled after every failed attempt of a stage
+	VideoProcessingRetryMaxDelay  = 30 * time.Minute
+
+	StalledVideoSweepBatchSize    = 100
+	UnqueuedProcessingGracePeriod = 5 * time.Minute // Videos processing for longer without any job get their processing queued
+)
+
+// Remote video import related constants
+const (
+	MaxVideoImportJobAttempts = 3
+	MaxVideoImportSize        = 10 * 1024 * 1024 * 1024 // 10 GB
+	VideoImportTimeout        = 2 * time.Hour           // Covers the whole download of a single attempt
+	VideoImportSniffLength    = 512                     // Bytes inspected to detect the type of the file
+	DefaultVideoImportMime    = "video/mp4"             // Used until the downloaded file is sniffed
+)
+
+// Transcoding and packaging related constants
+const (
+	StreamSegmentDuration = 6 // Segment duration in seconds
+	StreamDirectoryName   = "stream"
+	HLSMasterPlaylistName = "master.m3u8"
+	DASHManifestName      = "manifest.mpd"
 )
 
 var (
@@ -43,3 +159,23 @@ var (
 		"video/mp2t",       // MPEG Transport Stream (.ts files)
 	}
 )
+
+// Outgoing webhook related constants
+const (
+	WebhookEventHeader    = "X-Fluxio-Event"
+	WebhookDeliveryHeader = "X-Fluxio-Delivery"
+
+	WebhookRelayInterval           = 5 * time.Second
+	WebhookRelayBatchSize          = 100
+	WebhookStrandedDeliveryAge     = 5 * time.Minute // Pending deliveries never attempted for longer get their job queued again
+	WebhookDeliveryTimeout         = 10 * time.Second
+	MaxWebhookDeliveryAttempts     = 8
+	MaxWebhookDeliveryJobAttempts  = 3                // Guards against a delivery which keeps crashing the worker
+	WebhookRetryBaseDelay          = 30 * time.Second // Doubled after every failed attempt
+	WebhookRetryMaxDelay           = 6 * time.Hour
+	MaxWebhookSubscriptionsPerUser = 10
+	MinWebhookSecretLength         = 16
+	WebhookSecretLength            = 32 // Bytes of a generated secret
+	DefaultWebhookDeliveryPageSize = 50
+	MaxWebhookDeliveryPageSize     = 200
+)
@@ -51,6 +51,8 @@ var (
 
 	ErrVideoURLGenerationFailed = errors.New("failed to generate video upload URL")
 	ErrVideoUploadNotAllowed    = errors.New("video upload not allowed")
+	ErrVideoRawFileMissing      = errors.New("uploaded video file no longer exists")
+	ErrVideoUploadRetryExceeded = errors.New("video upload retry limit reached")
 	ErrVideoMetaUpdateFailed    = errors.New("failed to update the video meta")
 
 	ErrMalformedStoragePath = errors.New("storage path is malformed")
@@ -59,6 +61,55 @@ var (
 
 	ErrVideoStreamCountNotSupported = errors.New("video stream count not supported")
 	ErrInvalidVideoExtension        = errors.New("video extension is not supported")
+
+	ErrVideoTranscodingFailed     = errors.New("failed to transcode the video")
+	ErrVideoFileUploadFailed      = errors.New("failed to upload the processed video file")
+	ErrInvalidVideoFileName       = errors.New("video file name is invalid")
+	ErrVideoFileURLGenerateFailed = errors.New("failed to generate processed video file upload URL")
+	ErrVideoPackagingFailed       = errors.New("failed to package the video renditions")
+	ErrInvalidPackagingFormat     = errors.New("packaging format is not valid")
+	ErrVideoManifestSaveFailed    = errors.New("failed to save the video manifests")
+	ErrVideoProcessingFailed      = errors.New("video processing failed permanently")
+	ErrVideoFileDeleteFailed      = errors.New("failed to delete the video file")
+
+	ErrVideoEventTooLarge     = errors.New("video event is too large to be sent")
+	ErrVideoEventListenFailed = errors.New("failed to listen to the video events")
+)
+
+// Upload errors
+var (
+	ErrVideoUploadNotFound        = errors.New("no multipart upload in progress for the video")
+	ErrVideoUploadAlreadyActive   = errors.New("a multipart upload is already in progress for the video")
+	ErrInvalidUploadPartNumber    = errors.New("upload part number is not valid")
+	ErrMultipartUploadFailed      = errors.New("failed to perform the multipart upload operation")
+	ErrVideoUploadSaveFailed      = errors.New("failed to save the video upload")
+	ErrVideoUploadPartsIncomplete = errors.New("no uploaded parts found for the video upload")
+	ErrUploadOffsetMismatch       = errors.New("upload offset does not match")
+	ErrInvalidUploadLength        = errors.New("upload length is not valid")
+	ErrVideoUploadExpired         = errors.New("video upload has expired")
+	ErrUploadPendingDataMissing   = errors.New("buffered data of the upload is missing")
+)
+
+// Storage errors
+var (
+	ErrUnsupportedStorageDriver  = errors.New("storage driver is not supported")
+	ErrObjectNotFound            = errors.New("object not found")
+	ErrMultipartUploadNotFound   = errors.New("multipart upload not found")
+	ErrInvalidObjectKey          = errors.New("object key is not valid")
+	ErrInvalidStorageSignature   = errors.New("storage url signature is not valid")
+	ErrStorageSigningKeyRequired = errors.New("storage signing secret is required")
+	ErrUnsupportedEventSource    = errors.New("storage event source is not supported")
+	ErrQueueMessageNotFound      = errors.New("queue message not found")
+)
+
+// Import errors
+var (
+	ErrInvalidVideoImportURL     = errors.New("video import url is not valid")
+	ErrVideoImportFailed         = errors.New("video import failed")
+	ErrVideoImportTooLarge       = errors.New("imported video exceeds the size limit")
+	ErrVideoImportSourceRejected = errors.New("video import source rejected the request")
+	ErrVideoImportNotVideo       = errors.New("imported file is not a supported video")
+	ErrVideoFileStoreFailed      = errors.New("failed to store the video file")
 )
 
 // Thumbnail errors
@@ -66,4 +117,38 @@ var (
 	ErrInvalidThumbnailDimensions   = errors.New("thumbnail dimensions are not valid")
 	ErrThumbnailCreationFailed      = errors.New("failed to create thumbnail")
 	ErrThumbnailURLGenerationFailed = errors.New("failed to generate thumbnail upload URL")
+	ErrThumbnailDeleteFailed        = errors.New("failed to delete thumbnails")
+	ErrInvalidThumbnailTimestamp    = errors.New("thumbnail timestamp is not valid")
+	ErrThumbnailRetryExceeded       = errors.New("thumbnail regeneration limit reached")
+	ErrThumbnailAlreadyQueued       = errors.New("thumbnail regeneration is already in progress")
+	ErrInvalidThumbnailProfile      = errors.New("thumbnail profile is not valid")
+	ErrThumbnailSpriteFailed        = errors.New("failed to create the thumbnail sprites")
+	ErrThumbnailSpriteNotFound      = errors.New("thumbnail sprites not found")
+	ErrThumbnailPreviewFailed       = errors.New("failed to create the preview clip")
+	ErrThumbnailPreviewNotFound     = errors.New("preview clip not found")
+)
+
+// Job errors
+var (
+	ErrInvalidJobID         = errors.New("job id is invalid")
+	ErrInvalidJobType       = errors.New("job type is not valid")
+	ErrInvalidJobPayload    = errors.New("job payload is not valid")
+	ErrJobEnqueueFailed     = errors.New("failed to enqueue the job")
+	ErrJobAlreadyQueued     = errors.New("an active job already exists")
+	ErrNoJobAvailable       = errors.New("no job available to claim")
+	ErrJobClaimFailed       = errors.New("failed to claim a job")
+	ErrJobLeaseLost         = errors.New("job lease was lost")
+	ErrJobStateUpdateFailed = errors.New("failed to update the job state")
+)
+
+// Webhook errors
+var (
+	ErrWebhookSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrWebhookDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrInvalidWebhookURL           = errors.New("webhook url must be an absolute http or https url")
+	ErrWebhookURLNotAllowed        = errors.New("webhook url must point to a public address")
+	ErrInvalidWebhookEventType     = errors.New("webhook event type is not valid")
+	ErrWebhookSecretTooShort       = errors.New("webhook secret is too short")
+	ErrWebhookLimitReached         = errors.New("webhook subscription limit reached")
+	ErrWebhookDeliveryFailed       = errors.New("webhook delivery failed")
 )
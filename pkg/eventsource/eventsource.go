@@ -0,0 +1,144 @@
+package eventsource
+
+import (
+	"context"
+	"fluxio-backend/pkg/common/schema"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/storage"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Message is a group of storage event records which is acknowledged together.
+type Message struct {
+	ID      string
+	Records []events.S3EventRecord
+}
+
+// Source delivers storage events to the backend when the storage cannot call the webhook.
+// A message which is not acknowledged is delivered again by a later Receive.
+type Source interface {
+	Receive(ctx context.Context) (messages []Message, err error)
+	Ack(ctx context.Context, message Message) (err error)
+}
+
+const (
+	SourceWebhook = "webhook"
+	SourcePoll    = "poll"
+)
+
+// CheckpointStore keeps the position of a source which reads the storage itself so that it survives a restart.
+type CheckpointStore interface {
+	GetEventSourceCheckpoint(ctx context.Context, name string) (value []byte, err error)
+	SaveEventSourceCheckpoint(ctx context.Context, name string, value []byte) (err error)
+}
+
+type Config struct {
+	Source      string
+	Store       storage.ObjectStore
+	Bucket      string
+	Checkpoints CheckpointStore
+}
+
+// Builds the configured event source. The webhook needs no source since the storage calls the API,
+// in which case the source is nil.
+func New(cfg Config) (source Source, err error) {
+	switch strings.ToLower(cfg.Source) {
+	case "", SourceWebhook:
+		return
+	case SourcePoll:
+		source = NewBucketPoller(cfg.Store, cfg.Bucket, cfg.Checkpoints)
+		return
+	default:
+		err = fluxerrors.ErrUnsupportedEventSource
+		return
+	}
+}
+
+// RecordHandler applies the storage event records. Handler is the one the webhook uses as well.
+type RecordHandler interface {
+	// Returns the number of records which failed and can succeed when delivered again.
+	HandleRecords(ctx context.Context, records []events.S3EventRecord) (failedCount int)
+}
+
+// Consumer reads the messages of a source and feeds their records into the same handler the webhook uses.
+type Consumer struct {
+	source       Source
+	handler      RecordHandler
+	pollInterval time.Duration
+
+	l schema.Logger
+}
+
+func NewConsumer(source Source, handler RecordHandler, pollInterval time.Duration, logger schema.Logger) *Consumer {
+	return &Consumer{
+		source:       source,
+		handler:      handler,
+		pollInterval: max(pollInterval, constants.MinStorageEventPollInterval),
+		l:            logger,
+	}
+}
+
+// Start consumes the source until the context is cancelled. The source is polled again right away
+// while every received message is handled and waits for the poll interval otherwise.
+func (c *Consumer) Start(ctx context.Context) {
+	logger := c.l.With("poll_interval", c.pollInterval.String())
+	logger.Info("Storage event consumer started")
+
+	for {
+		drained, err := c.consume(ctx)
+		if ctx.Err() != nil {
+			logger.Info("Storage event consumer stopped")
+			return
+		}
+
+		if err != nil {
+			logger.Error("Failed to receive storage events", err)
+		}
+
+		if !drained && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Info("Storage event consumer stopped")
+			return
+		case <-time.After(c.pollInterval):
+		}
+	}
+}
+
+// Handles one batch of messages. The source counts as drained when it had nothing to deliver or
+// some message could not be handled, so that a failing message is not retried in a tight loop.
+func (c *Consumer) consume(ctx context.Context) (drained bool, err error) {
+	messages, err := c.source.Receive(ctx)
+	if err != nil {
+		return
+	}
+
+	drained = len(messages) == 0
+
+	for _, message := range messages {
+		logger := c.l.With("message_id", message.ID)
+
+		// Leave the message unacknowledged so that it is delivered again.
+		failedCount := c.handler.HandleRecords(ctx, message.Records)
+		if failedCount > 0 {
+			logger.Warn("Storage event message will be redelivered")
+			drained = true
+			continue
+		}
+
+		ackErr := c.source.Ack(ctx, message)
+		if ackErr != nil {
+			logger.Error("Failed to acknowledge the storage event message", ackErr)
+			drained = true
+		}
+	}
+
+	return
+}
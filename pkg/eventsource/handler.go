@@ -0,0 +1,109 @@
+package eventsource
+
+import (
+	"context"
+	"fluxio-backend/pkg/common/schema"
+	"fluxio-backend/pkg/model"
+	"fluxio-backend/pkg/service"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Handler applies the storage events of the raw bucket to the videos. It is shared by the webhook and the
+// event sources so that the events are handled the same way however they reach the backend.
+type Handler struct {
+	bucketName string
+	vidSvc     *service.VideoService
+
+	l schema.Logger
+}
+
+func NewHandler(bucketName string, videoSvc *service.VideoService, logger schema.Logger) *Handler {
+	return &Handler{
+		bucketName: bucketName,
+		vidSvc:     videoSvc,
+		l:          logger,
+	}
+}
+
+// Handles the records and returns the number of records which failed and can succeed when delivered again.
+func (h *Handler) HandleRecords(ctx context.Context, records []events.S3EventRecord) (failedCount int) {
+	logger := h.l.With("bucket", h.bucketName)
+
+	logger.Info("Processing S3 event", "record_count", len(records))
+
+	// TODO: Add Concurrent handling of events
+	for _, record := range records {
+		recordLogger := logger.With("event_name", record.EventName)
+
+		err := h.handleRecord(ctx, record, recordLogger)
+		if err != nil {
+			recordLogger.Error("Failed to handle the storage event", err)
+			failedCount++
+		}
+	}
+
+	return
+}
+
+func (h *Handler) handleRecord(ctx context.Context, record events.S3EventRecord, logger schema.Logger) (err error) {
+	if !strings.EqualFold(record.S3.Bucket.Name, h.bucketName) {
+		logger.Info("Skipping non-matching event",
+			"event_bucket", record.S3.Bucket.Name,
+			"expected_bucket", h.bucketName)
+		return
+	}
+
+	// Keys are URL encoded in the event.
+	objectKey, err := url.QueryUnescape(record.S3.Object.Key)
+	if err != nil {
+		logger.Error("Failed to decode the object key", err)
+		err = nil
+		return
+	}
+
+	// Clean the object name to get the key with ID.
+	videoSlug := strings.Replace(objectKey, fmt.Sprintf("%s/", h.bucketName), "", 1)
+	objectSize := record.S3.Object.Size
+
+	logger = logger.With("video_slug", videoSlug).With("object_size", objectSize)
+
+	storageEvent := model.StorageEvent{
+		Bucket:    record.S3.Bucket.Name,
+		Key:       objectKey,
+		Sequencer: record.S3.Object.Sequencer,
+		ETag:      record.S3.Object.ETag,
+		EventName: record.EventName,
+		Size:      objectSize,
+	}
+
+	var duplicate bool
+
+	// AWS sends the event names without the "s3:" prefix while MinIO includes it.
+	eventName := strings.TrimPrefix(record.EventName, "s3:")
+
+	switch {
+	case strings.HasPrefix(eventName, "ObjectCreated:"):
+		logger.Info("Processing video upload")
+		duplicate, err = h.vidSvc.HandleVideoUploadedEvent(ctx, videoSlug, storageEvent)
+	case strings.HasPrefix(eventName, "ObjectRemoved:"):
+		logger.Info("Processing video file removal")
+		duplicate, err = h.vidSvc.HandleVideoFileRemovedEvent(ctx, videoSlug, storageEvent)
+	default:
+		logger.Info("Skipping unsupported event")
+		return
+	}
+
+	if err != nil {
+		return
+	}
+
+	if !duplicate {
+		logger.Info("Storage event handled")
+	}
+
+	return
+}
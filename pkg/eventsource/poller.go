@@ -0,0 +1,207 @@
+package eventsource
+
+import (
+	"context"
+	"encoding/json"
+	"fluxio-backend/pkg/constants"
+	"fluxio-backend/pkg/storage"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// BucketPoller turns the objects written to a bucket into object created events. Removed objects cannot be
+// detected by listing so only uploads are delivered.
+//
+// The bucket is listed in passes of bounded pages in key order. An object is delivered when it was modified
+// after the previous pass started, so an object written behind the listing is picked up by the next pass and
+// no object is delivered by more than two passes, where the storage event ledger skips the second delivery.
+// The position is kept in the checkpoint store so that a restart resumes the pass instead of starting over.
+type BucketPoller struct {
+	store       storage.ObjectStore
+	bucket      string
+	checkpoints CheckpointStore
+
+	mu     sync.Mutex
+	loaded bool
+	state  pollerState
+	// Page delivered by the last Receive whose objects are not all handled yet.
+	pending *polledPage
+}
+
+// Position of the poller in the bucket.
+type pollerState struct {
+	Marker        string    `json:"marker"`          // Last key of the current pass up to which every object was handled
+	Since         time.Time `json:"since"`           // Start of the last completed pass. Older objects were delivered by it
+	PassStartedAt time.Time `json:"pass_started_at"` // Start of the current pass
+}
+
+type polledPage struct {
+	lastKey string
+	passEnd bool
+	unacked map[string]bool
+}
+
+// The checkpoint store is optional, without it the position is only kept in memory.
+func NewBucketPoller(store storage.ObjectStore, bucket string, checkpoints CheckpointStore) *BucketPoller {
+	return &BucketPoller{
+		store:       store,
+		bucket:      bucket,
+		checkpoints: checkpoints,
+	}
+}
+
+// Delivers the objects of the next page with new objects. Pages without them are passed over until the pass
+// ends so that a large bucket of handled objects does not hold back the new ones.
+func (p *BucketPoller) Receive(ctx context.Context) (messages []Message, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err = p.load(ctx)
+	if err != nil {
+		return
+	}
+
+	for {
+		if p.state.PassStartedAt.IsZero() {
+			p.state.PassStartedAt = time.Now()
+		}
+
+		objects, listErr := p.store.List(ctx, p.bucket, "", p.state.Marker, constants.StorageEventPollBatchSize)
+		if listErr != nil {
+			err = listErr
+			return
+		}
+
+		page := &polledPage{
+			passEnd: len(objects) < constants.StorageEventPollBatchSize,
+			unacked: map[string]bool{},
+		}
+
+		if len(objects) > 0 {
+			page.lastKey = objects[len(objects)-1].Key
+		}
+
+		since := p.state.Since.Add(-constants.StorageEventPollClockSkew)
+
+		for _, object := range objects {
+			if object.LastModified.Before(since) {
+				continue
+			}
+
+			id := polledObjectID(object)
+			page.unacked[id] = true
+
+			messages = append(messages, Message{
+				ID:      id,
+				Records: []events.S3EventRecord{p.objectCreatedRecord(object)},
+			})
+		}
+
+		if len(messages) > 0 {
+			p.pending = page
+			return
+		}
+
+		err = p.advance(ctx, page)
+		if err != nil || page.passEnd {
+			return
+		}
+	}
+}
+
+// Moves past the page once all of its objects are handled. A page with an unhandled object is listed
+// again by the next Receive and the objects handled in the meantime are skipped by the ledger.
+func (p *BucketPoller) Ack(ctx context.Context, message Message) (err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pending == nil || !p.pending.unacked[message.ID] {
+		return
+	}
+
+	delete(p.pending.unacked, message.ID)
+	if len(p.pending.unacked) > 0 {
+		return
+	}
+
+	page := p.pending
+	p.pending = nil
+
+	return p.advance(ctx, page)
+}
+
+func (p *BucketPoller) advance(ctx context.Context, page *polledPage) (err error) {
+	if page.passEnd {
+		p.state = pollerState{Since: p.state.PassStartedAt}
+	} else {
+		p.state.Marker = page.lastKey
+	}
+
+	if p.checkpoints == nil {
+		return
+	}
+
+	value, err := json.Marshal(p.state)
+	if err != nil {
+		return
+	}
+
+	return p.checkpoints.SaveEventSourceCheckpoint(ctx, p.checkpointName(), value)
+}
+
+// Reads the stored position once before the first listing.
+func (p *BucketPoller) load(ctx context.Context) (err error) {
+	if p.loaded || p.checkpoints == nil {
+		return
+	}
+
+	value, err := p.checkpoints.GetEventSourceCheckpoint(ctx, p.checkpointName())
+	if err != nil {
+		return
+	}
+
+	if len(value) > 0 {
+		err = json.Unmarshal(value, &p.state)
+		if err != nil {
+			return
+		}
+	}
+
+	p.loaded = true
+	return
+}
+
+func (p *BucketPoller) checkpointName() string {
+	return fmt.Sprintf("bucket_poller:%s", p.bucket)
+}
+
+// Builds the record the storage would have sent for the object. The sequencer is derived from the
+// modification time so that an overwritten object is a new event.
+func (p *BucketPoller) objectCreatedRecord(object storage.ObjectInfo) events.S3EventRecord {
+	return events.S3EventRecord{
+		EventVersion: "2.1",
+		EventSource:  "fluxio:poller",
+		EventTime:    object.LastModified,
+		EventName:    "ObjectCreated:Put",
+		S3: events.S3Entity{
+			Bucket: events.S3Bucket{
+				Name: p.bucket,
+			},
+			Object: events.S3Object{
+				Key:       url.QueryEscape(object.Key),
+				Size:      object.Size,
+				ETag:      strings.Trim(object.ETag, `"`),
+				Sequencer: fmt.Sprintf("%016X", object.LastModified.UnixNano()),
+			},
+		},
+	}
+}
+
+func polledObjectID(object storage.ObjectInfo) string {
+	return fmt.Sprintf("%s@%s", object.Key, strings.Trim(object.ETag, `"`))
+}
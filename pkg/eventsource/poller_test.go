@@ -0,0 +1,202 @@
+package eventsource
+
+import (
+	"context"
+	"fluxio-backend/pkg/constants"
+	"fluxio-backend/pkg/storage"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testBucket = "raw"
+
+type memoryCheckpointStore struct {
+	values map[string][]byte
+}
+
+func (m *memoryCheckpointStore) GetEventSourceCheckpoint(ctx context.Context, name string) (value []byte, err error) {
+	return m.values[name], nil
+}
+
+func (m *memoryCheckpointStore) SaveEventSourceCheckpoint(ctx context.Context, name string, value []byte) (err error) {
+	m.values[name] = value
+	return
+}
+
+// Records the listings so that the tests can check they stay bounded.
+type countingStore struct {
+	storage.ObjectStore
+	listCount int
+	maxLimit  int
+}
+
+func (c *countingStore) List(ctx context.Context, bucket string, prefix string, startAfter string, limit int) (objects []storage.ObjectInfo, err error) {
+	c.listCount++
+	c.maxLimit = max(c.maxLimit, limit)
+	return c.ObjectStore.List(ctx, bucket, prefix, startAfter, limit)
+}
+
+type pollerTestStore struct {
+	*countingStore
+	root string
+}
+
+func newPollerTestStore(t *testing.T) *pollerTestStore {
+	t.Helper()
+
+	root := t.TempDir()
+	store, err := storage.NewLocalStore(storage.LocalConfig{
+		Root:          root,
+		BaseURL:       "http://localhost/storage",
+		SigningSecret: "secret",
+	})
+	if err != nil {
+		t.Fatalf("failed to create the local store: %v", err)
+	}
+
+	return &pollerTestStore{countingStore: &countingStore{ObjectStore: store}, root: root}
+}
+
+// Writes an object which was last modified the given time ago.
+func (s *pollerTestStore) put(t *testing.T, key string, age time.Duration) {
+	t.Helper()
+
+	err := s.Put(context.Background(), testBucket, key, strings.NewReader(key), "video/mp4")
+	if err != nil {
+		t.Fatalf("failed to put %s: %v", key, err)
+	}
+
+	modifiedAt := time.Now().Add(-age)
+	err = os.Chtimes(filepath.Join(s.root, testBucket, key), modifiedAt, modifiedAt)
+	if err != nil {
+		t.Fatalf("failed to set the modification time of %s: %v", key, err)
+	}
+}
+
+// Receives once and acknowledges every message. Returns the keys which were delivered.
+func receiveAll(t *testing.T, poller *BucketPoller) (keys []string) {
+	t.Helper()
+
+	messages, err := poller.Receive(context.Background())
+	if err != nil {
+		t.Fatalf("receive failed: %v", err)
+	}
+
+	for _, message := range messages {
+		keys = append(keys, strings.Split(message.ID, "@")[0])
+
+		err = poller.Ack(context.Background(), message)
+		if err != nil {
+			t.Fatalf("ack failed: %v", err)
+		}
+	}
+
+	sort.Strings(keys)
+	return
+}
+
+func TestBucketPollerSkipsObjectsHandledByAPass(t *testing.T) {
+	store := newPollerTestStore(t)
+	store.put(t, "video-a", time.Hour)
+	store.put(t, "video-b", time.Hour)
+
+	poller := NewBucketPoller(store, testBucket, nil)
+
+	if got := receiveAll(t, poller); strings.Join(got, ",") != "video-a,video-b" {
+		t.Fatalf("first pass delivered %v", got)
+	}
+
+	if got := receiveAll(t, poller); len(got) != 0 {
+		t.Fatalf("second pass delivered handled objects %v", got)
+	}
+
+	store.put(t, "video-0", 0)
+
+	if got := receiveAll(t, poller); strings.Join(got, ",") != "video-0" {
+		t.Fatalf("delivered %v, want the new object", got)
+	}
+}
+
+func TestBucketPollerRedeliversAnUnacknowledgedPage(t *testing.T) {
+	store := newPollerTestStore(t)
+	store.put(t, "video-a", time.Hour)
+	store.put(t, "video-b", time.Hour)
+
+	poller := NewBucketPoller(store, testBucket, nil)
+
+	messages, err := poller.Receive(context.Background())
+	if err != nil || len(messages) != 2 {
+		t.Fatalf("got %d messages, %v", len(messages), err)
+	}
+
+	// The second object fails and is left unacknowledged.
+	err = poller.Ack(context.Background(), messages[0])
+	if err != nil {
+		t.Fatalf("ack failed: %v", err)
+	}
+
+	if got := receiveAll(t, poller); strings.Join(got, ",") != "video-a,video-b" {
+		t.Fatalf("delivered %v, want the page again", got)
+	}
+
+	if got := receiveAll(t, poller); len(got) != 0 {
+		t.Fatalf("delivered %v after the page was handled", got)
+	}
+}
+
+func TestBucketPollerResumesFromTheCheckpoint(t *testing.T) {
+	store := newPollerTestStore(t)
+	store.put(t, "video-a", time.Hour)
+
+	checkpoints := &memoryCheckpointStore{values: map[string][]byte{}}
+
+	if got := receiveAll(t, NewBucketPoller(store, testBucket, checkpoints)); len(got) != 1 {
+		t.Fatalf("first poller delivered %v", got)
+	}
+
+	// A restarted poller does not deliver the handled objects again.
+	if got := receiveAll(t, NewBucketPoller(store, testBucket, checkpoints)); len(got) != 0 {
+		t.Fatalf("restarted poller delivered %v", got)
+	}
+}
+
+func TestBucketPollerListsBoundedPages(t *testing.T) {
+	store := newPollerTestStore(t)
+
+	objectCount := constants.StorageEventPollBatchSize*2 + 10
+	for i := 0; i < objectCount; i++ {
+		store.put(t, fmt.Sprintf("video-%04d", i), time.Hour)
+	}
+
+	poller := NewBucketPoller(store, testBucket, nil)
+
+	delivered := 0
+	for i := 0; i < 3; i++ {
+		delivered += len(receiveAll(t, poller))
+	}
+
+	if delivered != objectCount {
+		t.Fatalf("delivered %d objects, want %d", delivered, objectCount)
+	}
+
+	// The new object is behind every handled one and is found by passing over their pages.
+	store.put(t, "video-9999", 0)
+	store.listCount = 0
+
+	if got := receiveAll(t, poller); strings.Join(got, ",") != "video-9999" {
+		t.Fatalf("delivered %v, want the new object", got)
+	}
+
+	if store.maxLimit != constants.StorageEventPollBatchSize {
+		t.Errorf("listed up to %d objects at once, want %d", store.maxLimit, constants.StorageEventPollBatchSize)
+	}
+
+	if wantListCount := objectCount/constants.StorageEventPollBatchSize + 1; store.listCount != wantListCount {
+		t.Errorf("listed %d pages, want %d", store.listCount, wantListCount)
+	}
+}
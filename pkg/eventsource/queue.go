@@ -0,0 +1,181 @@
+package eventsource
+
+import (
+	"context"
+	"encoding/json"
+	"fluxio-backend/pkg/common/schema"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// A message read from a queue. The receipt is handed back to the queue to delete the message.
+type QueueMessage struct {
+	ID      string
+	Receipt string
+	Body    []byte
+}
+
+// Queue is a message queue the storage publishes its event notifications to, such as SQS or a MinIO
+// notification target. A received message stays hidden from other receivers until it is deleted or
+// its visibility timeout runs out.
+type Queue interface {
+	Receive(ctx context.Context, maxMessages int) (messages []QueueMessage, err error)
+	Delete(ctx context.Context, receipt string) (err error)
+}
+
+// QueueSource reads the storage events published to a queue. Every queue message holds one S3 event
+// notification in the same shape the webhook receives.
+type QueueSource struct {
+	queue Queue
+
+	l schema.Logger
+}
+
+func NewQueueSource(queue Queue, logger schema.Logger) *QueueSource {
+	return &QueueSource{
+		queue: queue,
+		l:     logger,
+	}
+}
+
+func (q *QueueSource) Receive(ctx context.Context) (messages []Message, err error) {
+	queueMessages, err := q.queue.Receive(ctx, constants.StorageEventPollBatchSize)
+	if err != nil {
+		return
+	}
+
+	for _, queueMessage := range queueMessages {
+		logger := q.l.With("queue_message_id", queueMessage.ID)
+
+		var event events.S3Event
+
+		parseErr := json.Unmarshal(queueMessage.Body, &event)
+		if parseErr != nil {
+			// A malformed message never succeeds so drop it instead of receiving it forever.
+			logger.Error("Failed to parse S3 event", parseErr)
+
+			deleteErr := q.queue.Delete(ctx, queueMessage.Receipt)
+			if deleteErr != nil {
+				logger.Error("Failed to delete the malformed queue message", deleteErr)
+			}
+			continue
+		}
+
+		messages = append(messages, Message{
+			ID:      queueMessage.Receipt,
+			Records: event.Records,
+		})
+	}
+
+	return
+}
+
+func (q *QueueSource) Ack(ctx context.Context, message Message) (err error) {
+	return q.queue.Delete(ctx, message.ID)
+}
+
+// MemoryQueue is a queue kept in memory for tests and local development.
+type MemoryQueue struct {
+	visibilityTimeout time.Duration
+
+	mu       sync.Mutex
+	messages []*memoryQueueMessage
+	sequence int
+}
+
+type memoryQueueMessage struct {
+	id             string
+	receipt        string
+	body           []byte
+	invisibleUntil time.Time
+}
+
+func NewMemoryQueue(visibilityTimeout time.Duration) *MemoryQueue {
+	return &MemoryQueue{
+		visibilityTimeout: visibilityTimeout,
+	}
+}
+
+// Adds a message to the queue and returns its ID.
+func (m *MemoryQueue) Send(body []byte) (id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sequence++
+	id = fmt.Sprintf("msg-%d", m.sequence)
+
+	m.messages = append(m.messages, &memoryQueueMessage{
+		id:   id,
+		body: append([]byte(nil), body...),
+	})
+	return
+}
+
+// Adds the event notification to the queue the way the storage publishes it.
+func (m *MemoryQueue) SendEvent(event events.S3Event) (id string, err error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	id = m.Send(body)
+	return
+}
+
+func (m *MemoryQueue) Receive(ctx context.Context, maxMessages int) (messages []QueueMessage, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+
+	for _, message := range m.messages {
+		if len(messages) >= maxMessages {
+			break
+		}
+
+		if now.Before(message.invisibleUntil) {
+			continue
+		}
+
+		// Every receive gets a new receipt so that a stale receiver cannot delete a redelivered message.
+		m.sequence++
+		message.receipt = fmt.Sprintf("%s-%d", message.id, m.sequence)
+		message.invisibleUntil = now.Add(m.visibilityTimeout)
+
+		messages = append(messages, QueueMessage{
+			ID:      message.id,
+			Receipt: message.receipt,
+			Body:    message.body,
+		})
+	}
+
+	return
+}
+
+func (m *MemoryQueue) Delete(ctx context.Context, receipt string) (err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, message := range m.messages {
+		if message.receipt != "" && message.receipt == receipt {
+			m.messages = append(m.messages[:i], m.messages[i+1:]...)
+			return
+		}
+	}
+
+	err = fluxerrors.ErrQueueMessageNotFound
+	return
+}
+
+// Returns the number of messages which have not been deleted yet.
+func (m *MemoryQueue) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.messages)
+}
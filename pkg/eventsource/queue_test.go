@@ -0,0 +1,223 @@
+package eventsource
+
+import (
+	"context"
+	"fluxio-backend/pkg/constants"
+	"fluxio-backend/pkg/logger"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Handles the records the way the video service does: the event is recorded in the ledger first, a recorded
+// event is skipped and a failed one is removed from the ledger again so that its redelivery is handled.
+type ledgerRecordHandler struct {
+	ledger   map[string]bool
+	handled  map[string]int
+	failures map[string]int // Number of times handling the key still fails
+}
+
+func newLedgerRecordHandler() *ledgerRecordHandler {
+	return &ledgerRecordHandler{
+		ledger:   map[string]bool{},
+		handled:  map[string]int{},
+		failures: map[string]int{},
+	}
+}
+
+func (h *ledgerRecordHandler) HandleRecords(ctx context.Context, records []events.S3EventRecord) (failedCount int) {
+	for _, record := range records {
+		object := record.S3.Object
+		identity := fmt.Sprintf("%s/%s/%s/%s", record.S3.Bucket.Name, object.Key, object.Sequencer, object.ETag)
+
+		if h.ledger[identity] {
+			continue
+		}
+		h.ledger[identity] = true
+
+		if h.failures[object.Key] > 0 {
+			h.failures[object.Key]--
+			delete(h.ledger, identity)
+			failedCount++
+			continue
+		}
+
+		h.handled[object.Key]++
+	}
+
+	return
+}
+
+func testUploadEvent(key string, sequencer string) events.S3Event {
+	return events.S3Event{
+		Records: []events.S3EventRecord{{
+			EventName: "s3:ObjectCreated:Put",
+			S3: events.S3Entity{
+				Bucket: events.S3Bucket{Name: testBucket},
+				Object: events.S3Object{Key: key, Sequencer: sequencer, ETag: "etag"},
+			},
+		}},
+	}
+}
+
+func newQueueTestConsumer(queue *MemoryQueue, handler RecordHandler) *Consumer {
+	logr := logger.NewDefaultLogger()
+	return NewConsumer(NewQueueSource(queue, logr), handler, time.Second, logr)
+}
+
+func sendTestEvent(t *testing.T, queue *MemoryQueue, event events.S3Event) {
+	t.Helper()
+
+	_, err := queue.SendEvent(event)
+	if err != nil {
+		t.Fatalf("failed to send the event: %v", err)
+	}
+}
+
+func TestConsumerAcksHandledMessages(t *testing.T) {
+	queue := NewMemoryQueue(time.Minute)
+	handler := newLedgerRecordHandler()
+	consumer := newQueueTestConsumer(queue, handler)
+
+	sendTestEvent(t, queue, testUploadEvent("video-a", "01"))
+	sendTestEvent(t, queue, testUploadEvent("video-b", "01"))
+
+	drained, err := consumer.consume(context.Background())
+	if err != nil || drained {
+		t.Fatalf("got drained %v, %v after handling messages", drained, err)
+	}
+
+	if handler.handled["video-a"] != 1 || handler.handled["video-b"] != 1 {
+		t.Errorf("handled %v, want every event once", handler.handled)
+	}
+
+	if queue.Len() != 0 {
+		t.Errorf("%d messages left in the queue, want them deleted", queue.Len())
+	}
+
+	drained, err = consumer.consume(context.Background())
+	if err != nil || !drained {
+		t.Errorf("got drained %v, %v for an empty queue", drained, err)
+	}
+}
+
+func TestConsumerSkipsRedeliveredEvents(t *testing.T) {
+	queue := NewMemoryQueue(time.Minute)
+	handler := newLedgerRecordHandler()
+	consumer := newQueueTestConsumer(queue, handler)
+
+	// The storage publishes at least once, so the same event can arrive in two messages.
+	sendTestEvent(t, queue, testUploadEvent("video-a", "01"))
+	sendTestEvent(t, queue, testUploadEvent("video-a", "01"))
+
+	// An overwritten object is a new event.
+	sendTestEvent(t, queue, testUploadEvent("video-a", "02"))
+
+	_, err := consumer.consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume failed: %v", err)
+	}
+
+	if handler.handled["video-a"] != 2 {
+		t.Errorf("handled video-a %d times, want 2", handler.handled["video-a"])
+	}
+
+	if queue.Len() != 0 {
+		t.Errorf("%d messages left in the queue, want the duplicate acknowledged as well", queue.Len())
+	}
+}
+
+func TestConsumerRedeliversFailedMessages(t *testing.T) {
+	queue := NewMemoryQueue(0) // Received messages are visible again right away
+	handler := newLedgerRecordHandler()
+	handler.failures["video-a"] = 1
+	consumer := newQueueTestConsumer(queue, handler)
+
+	sendTestEvent(t, queue, testUploadEvent("video-a", "01"))
+	sendTestEvent(t, queue, testUploadEvent("video-b", "01"))
+
+	drained, err := consumer.consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume failed: %v", err)
+	}
+
+	// A failed message makes the consumer wait for the poll interval instead of retrying in a tight loop.
+	if !drained {
+		t.Errorf("consumer is not drained after a failed message")
+	}
+
+	if handler.handled["video-a"] != 0 || handler.handled["video-b"] != 1 {
+		t.Fatalf("handled %v after the first delivery", handler.handled)
+	}
+
+	if queue.Len() != 1 {
+		t.Fatalf("%d messages left in the queue, want the failed one kept", queue.Len())
+	}
+
+	_, err = consumer.consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume failed: %v", err)
+	}
+
+	if handler.handled["video-a"] != 1 || handler.handled["video-b"] != 1 {
+		t.Errorf("handled %v after the redelivery, want every event once", handler.handled)
+	}
+
+	if queue.Len() != 0 {
+		t.Errorf("%d messages left in the queue after the redelivery", queue.Len())
+	}
+}
+
+func TestConsumerWaitsForTheVisibilityTimeout(t *testing.T) {
+	queue := NewMemoryQueue(time.Minute)
+	handler := newLedgerRecordHandler()
+	handler.failures["video-a"] = 1
+	consumer := newQueueTestConsumer(queue, handler)
+
+	sendTestEvent(t, queue, testUploadEvent("video-a", "01"))
+
+	_, err := consumer.consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume failed: %v", err)
+	}
+
+	// The failed message stays hidden until its visibility timeout runs out.
+	drained, err := consumer.consume(context.Background())
+	if err != nil || !drained || handler.handled["video-a"] != 0 {
+		t.Errorf("got drained %v, %v, handled %v while the message is hidden", drained, err, handler.handled)
+	}
+
+	if queue.Len() != 1 {
+		t.Errorf("%d messages left in the queue, want the failed one kept", queue.Len())
+	}
+}
+
+func TestQueueSourceDropsMalformedMessages(t *testing.T) {
+	queue := NewMemoryQueue(0)
+	handler := newLedgerRecordHandler()
+	consumer := newQueueTestConsumer(queue, handler)
+
+	queue.Send([]byte("not an event"))
+
+	_, err := consumer.consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume failed: %v", err)
+	}
+
+	if queue.Len() != 0 {
+		t.Errorf("%d messages left in the queue, want the malformed one dropped", queue.Len())
+	}
+}
+
+func TestConsumerRaisesShortPollIntervals(t *testing.T) {
+	logr := logger.NewDefaultLogger()
+
+	for _, pollInterval := range []time.Duration{-time.Second, 0, time.Millisecond} {
+		consumer := NewConsumer(NewQueueSource(NewMemoryQueue(0), logr), newLedgerRecordHandler(), pollInterval, logr)
+		if consumer.pollInterval != constants.MinStorageEventPollInterval {
+			t.Errorf("got poll interval %v for %v, want %v", consumer.pollInterval, pollInterval, constants.MinStorageEventPollInterval)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type JobID string
+
+func (id JobID) String() string {
+	return string(id)
+}
+
+type JobType string
+
+const (
+	JobTypeVideoProcessing       JobType = "video_processing"
+	JobTypeThumbnailRegeneration JobType = "thumbnail_regeneration"
+	JobTypeVideoImport           JobType = "video_import"
+	JobTypeWebhookDelivery       JobType = "webhook_delivery"
+)
+
+func (t JobType) String() string {
+	return string(t)
+}
+
+type JobState string
+
+const (
+	JobStateQueued    JobState = "queued"
+	JobStateRunning   JobState = "running"
+	JobStateCompleted JobState = "completed"
+	JobStateFailed    JobState = "failed"
+)
+
+// This function checks if the job state is of a valid value.
+func (s JobState) IsAcceptable() bool {
+	switch s {
+	case JobStateQueued,
+		JobStateRunning,
+		JobStateCompleted,
+		JobStateFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s JobState) String() string {
+	return string(s)
+}
+
+type Job struct {
+	ID             JobID           `json:"id"`
+	Type           JobType         `json:"type"`
+	Payload        json.RawMessage `json:"payload"`
+	UniqueKey      string          `json:"-"`
+	State          JobState        `json:"state"`
+	Attempts       uint32          `json:"attempts"`
+	MaxAttempts    uint32          `json:"max_attempts"`
+	RunAt          time.Time       `json:"run_at"`
+	LeaseExpiresAt *time.Time      `json:"lease_expires_at,omitempty"`
+	LockedBy       string          `json:"locked_by,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      *time.Time      `json:"created_at"`
+	UpdatedAt      *time.Time      `json:"updated_at"`
+}
+
+// Payload of the job which runs the post upload processing of a video.
+type VideoProcessingJobPayload struct {
+	Slug string `json:"slug"`
+}
+
+// Payload of the job which regenerates the thumbnails of a processed video.
+type ThumbnailRegenerationJobPayload struct {
+	Slug       string   `json:"slug"`
+	Timestamps []uint64 `json:"timestamps,omitempty"` // Picked at random when empty
+}
+
+// Payload of the job which downloads a video file from a remote URL into the raw bucket.
+type VideoImportJobPayload struct {
+	Slug      string `json:"slug"`
+	SourceURL string `json:"source_url"`
+}
+
+// Payload of the job which delivers a lifecycle event to a webhook subscription.
+type WebhookDeliveryJobPayload struct {
+	DeliveryID string `json:"delivery_id"`
+}
+
+// Returned by a job handler to run the job again at the given time. The handler owns the retry policy
+// so the rescheduled run does not consume one of the job attempts.
+type JobRetryError struct {
+	Err   error
+	RunAt time.Time
+}
+
+func (e *JobRetryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *JobRetryError) Unwrap() error {
+	return e.Err
+}
+
+// Returned by a job handler when running the job again cannot succeed. The job is failed right away.
+type JobPermanentError struct {
+	Err error
+}
+
+func (e *JobPermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *JobPermanentError) Unwrap() error {
+	return e.Err
+}
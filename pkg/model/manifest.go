@@ -0,0 +1,30 @@
+package model
+
+type PackagingFormat string
+
+const (
+	PackagingFormatHLS  PackagingFormat = "hls"
+	PackagingFormatDASH PackagingFormat = "dash"
+)
+
+// This function checks if the packaging format is of a valid value.
+func (f PackagingFormat) IsAcceptable() bool {
+	switch f {
+	case PackagingFormatHLS,
+		PackagingFormatDASH:
+		return true
+	default:
+		return false
+	}
+}
+
+func (f PackagingFormat) String() string {
+	return string(f)
+}
+
+// VideoManifest is the entry point of a packaged stream for a player.
+type VideoManifest struct {
+	Format      PackagingFormat `json:"format"`
+	URL         string          `json:"url"`
+	StoragePath string          `json:"-"`
+}
@@ -0,0 +1,10 @@
+package model
+
+// VideoRendition describes a single rung of the adaptive bitrate ladder.
+type VideoRendition struct {
+	Name         string `json:"name"`
+	Width        uint32 `json:"width"`
+	Height       uint32 `json:"height"`
+	VideoBitrate uint32 `json:"video_bitrate"` // Bitrate in kbps
+	AudioBitrate uint32 `json:"audio_bitrate"` // Bitrate in kbps
+}
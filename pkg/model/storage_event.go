@@ -0,0 +1,11 @@
+package model
+
+// A notification of the object storage about a changed object.
+type StorageEvent struct {
+	Bucket    string `json:"bucket"`
+	Key       string `json:"key"`
+	Sequencer string `json:"sequencer"` // Orders the events of a key. Empty when the storage does not send it
+	ETag      string `json:"etag"`
+	EventName string `json:"event_name"`
+	Size      int64  `json:"size"`
+}
@@ -1,6 +1,10 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 type ThumbnailID string
 
@@ -8,17 +12,129 @@ func (t ThumbnailID) String() string {
 	return string(t)
 }
 
+// Supported thumbnail formats along with their content types.
+var thumbnailFormatMimeTypes = map[string]string{
+	"jpg":  "image/jpeg",
+	"webp": "image/webp",
+	"avif": "image/avif",
+}
+
+// Order the sources of a thumbnail are listed in. Browsers use the first source they support so the
+// smaller formats come first.
+var thumbnailSourceOrder = []string{"avif", "webp", "jpg"}
+
+// This function checks if the thumbnail format is supported.
+func IsAcceptableThumbnailFormat(format string) bool {
+	_, ok := thumbnailFormatMimeTypes[format]
+	return ok
+}
+
+// Returns the content type of a thumbnail format.
+func ThumbnailFormatMimeType(format string) string {
+	return thumbnailFormatMimeTypes[format]
+}
+
+// A size and format every thumbnail is stored in.
+type ThumbnailProfile struct {
+	Width  uint16
+	Height uint16
+	Format string
+}
+
+// A logical thumbnail of a video. The main fields describe the primary variant while the variants hold
+// every stored size and format of the same frame.
 type Thumbnail struct {
-	ID          ThumbnailID `json:"id"`
-	VideoID     VideoID     `json:"video_id"`
-	Width       uint16      `json:"width"`
-	Height      uint16      `json:"height"`
-	Format      string      `json:"format"`
-	Size        uint32      `json:"size"`
-	TimeStamp   uint64      `json:"timestamp"`
-	CreatedAt   *time.Time  `json:"created_at"`
-	UpdatedAt   *time.Time  `json:"updated_at"`
-	DeletedAt   *time.Time  `json:"deleted_at,omitempty"`
-	StoragePath string      `json:"-"`
-	IsDefault   bool        `json:"is_default"`
+	ID          ThumbnailID        `json:"id"`
+	VideoID     VideoID            `json:"video_id"`
+	Width       uint16             `json:"width"`
+	Height      uint16             `json:"height"`
+	Format      string             `json:"format"`
+	Size        uint32             `json:"size"`
+	TimeStamp   uint64             `json:"timestamp"`
+	CreatedAt   *time.Time         `json:"created_at"`
+	UpdatedAt   *time.Time         `json:"updated_at"`
+	DeletedAt   *time.Time         `json:"deleted_at,omitempty"`
+	StoragePath string             `json:"-"`
+	URL         string             `json:"url,omitempty"`
+	BlurHash    string             `json:"blurhash,omitempty"` // Placeholder shown while the thumbnail loads
+	LQIP        string             `json:"lqip,omitempty"`     // Tiny base64 data URI of the thumbnail
+	IsDefault   bool               `json:"is_default"`
+	Variants    []ThumbnailVariant `json:"variants,omitempty"`
+	Sources     []ThumbnailSource  `json:"sources,omitempty"`
+}
+
+type ThumbnailVariant struct {
+	Width       uint16 `json:"width"`
+	Height      uint16 `json:"height"`
+	Format      string `json:"format"`
+	Size        uint32 `json:"size"` // Size in KB
+	StoragePath string `json:"-"`
+	URL         string `json:"url"`
+}
+
+// The variants of a thumbnail in one format as a srcset, ready for a source element of a picture.
+type ThumbnailSource struct {
+	Format   string `json:"format"`
+	MimeType string `json:"type"`
+	SrcSet   string `json:"srcset"`
+}
+
+// Groups the variants by format into srcsets. The variants are expected to be ordered by width.
+func NewThumbnailSources(variants []ThumbnailVariant) (sources []ThumbnailSource) {
+	for _, format := range thumbnailSourceOrder {
+		candidates := []string{}
+		for _, variant := range variants {
+			if variant.Format == format {
+				candidates = append(candidates, fmt.Sprintf("%s %dw", variant.URL, variant.Width))
+			}
+		}
+
+		if len(candidates) == 0 {
+			continue
+		}
+
+		sources = append(sources, ThumbnailSource{
+			Format:   format,
+			MimeType: ThumbnailFormatMimeType(format),
+			SrcSet:   strings.Join(candidates, ", "),
+		})
+	}
+
+	return
+}
+
+// Sprite sheets of a video used for the scrubbing previews of the player. Frames are sampled at a fixed
+// interval and tiled into sheets, the WebVTT index maps every interval to its region on a sheet.
+type ThumbnailSprite struct {
+	VideoID     VideoID    `json:"-"`
+	Format      string     `json:"format"`
+	Interval    uint32     `json:"interval"` // Seconds between two sampled frames
+	FrameWidth  uint16     `json:"frame_width"`
+	FrameHeight uint16     `json:"frame_height"`
+	Columns     uint16     `json:"columns"`
+	Rows        uint16     `json:"rows"`
+	SheetCount  uint32     `json:"sheet_count"`
+	FrameCount  uint32     `json:"frame_count"`
+	StoragePath string     `json:"-"`   // Path of the WebVTT index in the thumbnail bucket
+	URL         string     `json:"url"` // URL of the WebVTT index, the sheets are referenced relative to it
+	CreatedAt   *time.Time `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+}
+
+// Short muted teaser of a video shown on hover. It is stitched from a few segments spread over the video and
+// stored both as an animated WebP and as a small MP4.
+type ThumbnailPreview struct {
+	VideoID      VideoID    `json:"-"`
+	Width        uint16     `json:"width"`
+	Height       uint16     `json:"height"`
+	Duration     uint32     `json:"duration"` // Length of the teaser in seconds
+	SegmentCount uint8      `json:"segment_count"`
+	WebPSize     uint32     `json:"webp_size"` // Size in KB
+	MP4Size      uint32     `json:"mp4_size"`  // Size in KB
+	WebPPath     string     `json:"-"`
+	MP4Path      string     `json:"-"`
+	WebPURL      string     `json:"webp_url"`
+	MP4URL       string     `json:"mp4_url"`
+	CreatedAt    *time.Time `json:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at"`
 }
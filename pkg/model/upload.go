@@ -0,0 +1,83 @@
+package model
+
+import "time"
+
+type VideoUploadID string
+
+func (id VideoUploadID) String() string {
+	return string(id)
+}
+
+type VideoUploadProtocol string
+
+const (
+	VideoUploadProtocolS3  VideoUploadProtocol = "s3"
+	VideoUploadProtocolTus VideoUploadProtocol = "tus"
+)
+
+func (p VideoUploadProtocol) String() string {
+	return string(p)
+}
+
+type VideoUploadState string
+
+const (
+	VideoUploadStateActive    VideoUploadState = "active"
+	VideoUploadStateCompleted VideoUploadState = "completed"
+	VideoUploadStateAborted   VideoUploadState = "aborted"
+)
+
+// This function checks if the upload state is of a valid value.
+func (s VideoUploadState) IsAcceptable() bool {
+	switch s {
+	case VideoUploadStateActive,
+		VideoUploadStateCompleted,
+		VideoUploadStateAborted:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s VideoUploadState) String() string {
+	return string(s)
+}
+
+// VideoUpload tracks a multipart upload of the raw video file.
+type VideoUpload struct {
+	ID          VideoUploadID       `json:"id"`
+	VideoID     VideoID             `json:"video_id"`
+	UploadID    string              `json:"-"` // Upload ID assigned by the object storage
+	StoragePath string              `json:"-"`
+	State       VideoUploadState    `json:"state"`
+	PartSize    int64               `json:"part_size"` // Size in bytes every part except the last one should have
+	MaxParts    int64               `json:"max_parts"`
+	Parts       []UploadedPart      `json:"parts"`
+	Protocol    VideoUploadProtocol `json:"protocol"`
+	Length      int64               `json:"length,omitempty"`
+	Offset      int64               `json:"offset,omitempty"`
+	ExpiresAt   *time.Time          `json:"expires_at,omitempty"`
+	CreatedAt   *time.Time          `json:"created_at"`
+	UpdatedAt   *time.Time          `json:"updated_at"`
+}
+
+// Returns the number of received bytes of a tus upload which were not sent to the storage as a part yet.
+func (u VideoUpload) PendingLength() int64 {
+	if u.Protocol != VideoUploadProtocolTus {
+		return 0
+	}
+
+	stored := int64(0)
+	for _, part := range u.Parts {
+		stored += part.Size
+	}
+
+	return u.Offset - stored
+}
+
+// UploadedPart is a part of a multipart upload which reached the object storage.
+type UploadedPart struct {
+	PartNumber int64  `json:"part_number"`
+	ETag       string `json:"etag"`
+	Size       int64  `json:"size"`
+}
@@ -1,7 +1,9 @@
 package model
 
 import (
+	"fmt"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -67,12 +69,19 @@ func (s VideoVisibility) IsAcceptable() bool {
 
 const (
 	VidInternalStatusUploadPending       VideoInternalStatus = "upload_pending"
+	VidInternalStatusProbed              VideoInternalStatus = "probed"
 	VidInternalStatusMetaExtracted       VideoInternalStatus = "meta_extracted"
 	VidInternalStatusThumbnailGenerated  VideoInternalStatus = "thumbnail_generated"
+	VidInternalStatusSpritesGenerated    VideoInternalStatus = "sprites_generated"
+	VidInternalStatusPreviewGenerated    VideoInternalStatus = "preview_generated"
+	VidInternalStatusTranscoded          VideoInternalStatus = "transcoded"
 	VidInternalStatusProcessingCompleted VideoInternalStatus = "completed"
 
 	VidInternalStatusThumbnailFailed VideoInternalStatus = "thumbnail_failed"
+	VidInternalStatusSpritesFailed   VideoInternalStatus = "sprites_failed"
+	VidInternalStatusPreviewFailed   VideoInternalStatus = "preview_failed"
 	VidInternalStatusMetaFailed      VideoInternalStatus = "meta_failed"
+	VidInternalStatusTranscodeFailed VideoInternalStatus = "transcode_failed"
 )
 
 type VideoInternalStatus string
@@ -81,11 +90,18 @@ type VideoInternalStatus string
 func (s VideoInternalStatus) IsAcceptable() bool {
 	switch s {
 	case VidInternalStatusUploadPending,
+		VidInternalStatusProbed,
 		VidInternalStatusMetaExtracted,
 		VidInternalStatusThumbnailGenerated,
+		VidInternalStatusSpritesGenerated,
+		VidInternalStatusPreviewGenerated,
+		VidInternalStatusTranscoded,
 		VidInternalStatusProcessingCompleted,
 		VidInternalStatusThumbnailFailed,
-		VidInternalStatusMetaFailed:
+		VidInternalStatusSpritesFailed,
+		VidInternalStatusPreviewFailed,
+		VidInternalStatusMetaFailed,
+		VidInternalStatusTranscodeFailed:
 		return true
 	default:
 		return false
@@ -97,29 +113,58 @@ func (s VideoInternalStatus) String() string {
 }
 
 type Video struct {
-	ID              VideoID             `json:"id"`
-	Title           string              `json:"title"`
-	Description     string              `json:"description"`
-	ParentID        *uuid.UUID          `json:"parent_id,omitempty"`
-	Width           uint32              `json:"width"`
-	Height          uint32              `json:"height"`
-	UserID          uuid.UUID           `json:"user_id"`
-	Format          string              `json:"format"`
-	Length          uint64              `json:"length"`
-	AudioSampleRate uint32              `json:"audio_sample_rate"`
-	AudioCodec      string              `json:"audio_codec"`
-	RetryCount      uint8               `json:"retry_count"`
-	Status          VideoStatus         `json:"status"`
-	InternalStatus  VideoInternalStatus `json:"-"`
-	CreatedAt       *time.Time          `json:"created_at"`
-	UpdatedAt       *time.Time          `json:"updated_at"`
-	DeletedAt       *time.Time          `json:"deleted_at,omitempty"`
-	IsFeatured      bool                `json:"is_featured,omitempty"`
-	Visibility      VideoVisibility     `json:"visibility"`
-	Slug            string              `json:"slug"`
-	Size            float32             `json:"size"`
-	Language        string              `json:"language"`
-	ResourceURL     url.URL             `json:"resource_url"`
-	StoragePath     string              `json:"-"`
-	Thumbnails      []Thumbnail         `json:"thumbnails,omitempty"`
+	ID                  VideoID             `json:"id"`
+	Title               string              `json:"title"`
+	Description         string              `json:"description"`
+	ParentID            *uuid.UUID          `json:"parent_id,omitempty"`
+	Width               uint32              `json:"width"`
+	Height              uint32              `json:"height"`
+	UserID              uuid.UUID           `json:"user_id"`
+	Format              string              `json:"format"`
+	Length              uint64              `json:"length"`
+	AudioSampleRate     uint32              `json:"audio_sample_rate"`
+	AudioCodec          string              `json:"audio_codec"`
+	RetryCount          uint8               `json:"retry_count"`
+	ThumbnailRetryCount uint8               `json:"thumbnail_retry_count"`
+	Status              VideoStatus         `json:"status"`
+	InternalStatus      VideoInternalStatus `json:"-"`
+	CreatedAt           *time.Time          `json:"created_at"`
+	UpdatedAt           *time.Time          `json:"updated_at"`
+	DeletedAt           *time.Time          `json:"deleted_at,omitempty"`
+	IsFeatured          bool                `json:"is_featured,omitempty"`
+	Visibility          VideoVisibility     `json:"visibility"`
+	Slug                string              `json:"slug"`
+	Size                float32             `json:"size"`
+	Language            string              `json:"language"`
+	ResourceURL         url.URL             `json:"resource_url"`
+	StoragePath         string              `json:"-"`
+	ProbeData           string              `json:"-"` // Raw ffprobe output kept so processing can resume after the probe
+	StageAttempts       uint8               `json:"-"`
+	LastError           string              `json:"-"`
+	RawFileDeletedAt    *time.Time          `json:"raw_file_deleted_at,omitempty"` // Set once the uploaded file is removed from the raw bucket
+	Progress            *VideoProgress      `json:"progress,omitempty"`            // Progress of the running ffmpeg step while the video is processed
+	Thumbnails          []Thumbnail         `json:"thumbnails,omitempty"`
+	Preview             *ThumbnailPreview   `json:"preview,omitempty"`
+	Sprite              *ThumbnailSprite    `json:"sprite,omitempty"`
+	Manifests           []VideoManifest     `json:"manifests,omitempty"`
+}
+
+// Progress of the ffmpeg run of a processing stage. A stage can run ffmpeg more than once, like once for
+// every rendition, so the step tells the runs apart.
+type VideoProgress struct {
+	Stage      string     `json:"stage"`
+	Step       string     `json:"step,omitempty"`
+	Percent    float64    `json:"percent"`
+	ETASeconds *uint64    `json:"eta_seconds,omitempty"` // Unknown until ffmpeg reported some progress
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+// Returns the progress in a readable form like "transcode 720p: 43%".
+func (p VideoProgress) String() string {
+	name := p.Stage
+	if !strings.EqualFold(p.Step, "") {
+		name = fmt.Sprintf("%s %s", p.Stage, p.Step)
+	}
+
+	return fmt.Sprintf("%s: %d%%", name, int(p.Percent))
 }
@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+type VideoEventType string
+
+const (
+	VideoEventStatus        VideoEventType = "status"         // The public status of the video changed
+	VideoEventStage         VideoEventType = "stage"          // A processing stage finished or failed
+	VideoEventThumbnail     VideoEventType = "thumbnail"      // A thumbnail of the video was stored
+	VideoEventProgress      VideoEventType = "progress"       // Processing moved forward
+	VideoEventStageProgress VideoEventType = "stage_progress" // The ffmpeg run of a stage moved forward
+)
+
+func (t VideoEventType) String() string {
+	return string(t)
+}
+
+// A change of a video pushed to the clients watching it.
+type VideoEvent struct {
+	Type          VideoEventType      `json:"type"`
+	VideoID       VideoID             `json:"-"`
+	Slug          string              `json:"slug"`
+	Status        VideoStatus         `json:"status,omitempty"`
+	Stage         VideoInternalStatus `json:"stage,omitempty"`
+	Progress      *float64            `json:"progress,omitempty"` // Percentage of the processing which is done
+	StageProgress *VideoProgress      `json:"stage_progress,omitempty"`
+	Thumbnail     *Thumbnail          `json:"thumbnail,omitempty"`
+	Message       string              `json:"message,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+}
+
+// Reports whether no more events follow for the video.
+func (e VideoEvent) IsFinal() bool {
+	return e.Type == VideoEventStatus && (e.Status == VideoStatusCompleted || e.Status == VideoStatusFailed || e.Status == VideoStatusDeleted)
+}
+
+// Returns the status event describing the stored state of the video.
+func NewVideoStatusEvent(video Video) VideoEvent {
+	return VideoEvent{
+		Type:          VideoEventStatus,
+		VideoID:       video.ID,
+		Slug:          video.Slug,
+		Status:        video.Status,
+		Stage:         video.InternalStatus,
+		StageProgress: video.Progress,
+		CreatedAt:     time.Now(),
+	}
+}
@@ -0,0 +1,118 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type WebhookSubscriptionID string
+
+func (id WebhookSubscriptionID) String() string {
+	return string(id)
+}
+
+type WebhookEventID string
+
+func (id WebhookEventID) String() string {
+	return string(id)
+}
+
+type WebhookDeliveryID string
+
+func (id WebhookDeliveryID) String() string {
+	return string(id)
+}
+
+type WebhookEventType string
+
+const (
+	WebhookEventVideoCompleted WebhookEventType = "video.completed"
+	WebhookEventVideoFailed    WebhookEventType = "video.failed"
+)
+
+// This function checks if the webhook event type is of a valid value.
+func (t WebhookEventType) IsAcceptable() bool {
+	switch t {
+	case WebhookEventVideoCompleted,
+		WebhookEventVideoFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (t WebhookEventType) String() string {
+	return string(t)
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+func (s WebhookDeliveryStatus) String() string {
+	return string(s)
+}
+
+// An endpoint of a user which is notified about the lifecycle events of the videos of the user.
+type WebhookSubscription struct {
+	ID         WebhookSubscriptionID `json:"id"`
+	UserID     UserID                `json:"-"`
+	URL        string                `json:"url"`
+	Secret     string                `json:"secret,omitempty"` // Only returned when the subscription is created
+	EventTypes []WebhookEventType    `json:"event_types"`
+	CreatedAt  *time.Time            `json:"created_at"`
+	UpdatedAt  *time.Time            `json:"updated_at"`
+}
+
+// A lifecycle event of a video waiting in the outbox to be delivered to the subscriptions.
+type WebhookEvent struct {
+	ID        WebhookEventID   `json:"id"`
+	UserID    UserID           `json:"-"`
+	VideoID   VideoID          `json:"-"`
+	Type      WebhookEventType `json:"type"`
+	Payload   json.RawMessage  `json:"payload"`
+	CreatedAt *time.Time       `json:"created_at"`
+}
+
+// Body sent to the subscriptions. The ID stays the same across the retries so receivers can drop duplicates.
+type WebhookPayload struct {
+	ID        WebhookEventID     `json:"id"`
+	Type      WebhookEventType   `json:"type"`
+	CreatedAt time.Time          `json:"created_at"`
+	Data      WebhookPayloadData `json:"data"`
+}
+
+type WebhookPayloadData struct {
+	Video WebhookVideo `json:"video"`
+}
+
+type WebhookVideo struct {
+	ID     VideoID     `json:"id"`
+	Slug   string      `json:"slug"`
+	Title  string      `json:"title"`
+	Status VideoStatus `json:"status"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// The delivery of an event to a subscription along with the outcome of its last attempt.
+type WebhookDelivery struct {
+	ID             WebhookDeliveryID     `json:"id"`
+	SubscriptionID WebhookSubscriptionID `json:"subscription_id"`
+	EventID        WebhookEventID        `json:"event_id"`
+	EventType      WebhookEventType      `json:"event_type"`
+	UserID         UserID                `json:"-"`
+	URL            string                `json:"url"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       uint32                `json:"attempts"`
+	ResponseStatus int                   `json:"response_status,omitempty"`
+	LastError      string                `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time            `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty"`
+	Payload        json.RawMessage       `json:"payload,omitempty"`
+	CreatedAt      *time.Time            `json:"created_at"`
+	UpdatedAt      *time.Time            `json:"updated_at"`
+}
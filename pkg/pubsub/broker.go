@@ -0,0 +1,86 @@
+package pubsub
+
+import (
+	"fluxio-backend/pkg/common/schema"
+	"sync"
+)
+
+// Broker fans the published messages out to the subscribers of a topic within the process.
+// Publishing never blocks so a subscriber which does not keep up misses messages instead of
+// holding back the publisher.
+type Broker[T any] struct {
+	bufferSize int
+
+	mu          sync.RWMutex
+	subscribers map[string]map[*Subscription[T]]struct{}
+
+	l schema.Logger
+}
+
+// A subscription to a topic. Messages are read from C which is closed once the subscription is closed.
+type Subscription[T any] struct {
+	C <-chan T
+
+	topic  string
+	ch     chan T
+	broker *Broker[T]
+	once   sync.Once
+}
+
+func NewBroker[T any](bufferSize int, logger schema.Logger) *Broker[T] {
+	return &Broker[T]{
+		bufferSize:  bufferSize,
+		subscribers: map[string]map[*Subscription[T]]struct{}{},
+		l:           logger,
+	}
+}
+
+func (b *Broker[T]) Subscribe(topic string) *Subscription[T] {
+	ch := make(chan T, b.bufferSize)
+	sub := &Subscription[T]{
+		C:      ch,
+		topic:  topic,
+		ch:     ch,
+		broker: b,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscribers[topic] == nil {
+		b.subscribers[topic] = map[*Subscription[T]]struct{}{}
+	}
+	b.subscribers[topic][sub] = struct{}{}
+
+	return sub
+}
+
+func (b *Broker[T]) Publish(topic string, message T) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subscribers[topic] {
+		select {
+		case sub.ch <- message:
+		default:
+			b.l.With("topic", topic).Warn("Dropped message for a slow subscriber")
+		}
+	}
+}
+
+// Stops the delivery to the subscription. Closing more than once is a no-op.
+func (s *Subscription[T]) Close() {
+	s.once.Do(func() {
+		b := s.broker
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subscribers[s.topic], s)
+		if len(b.subscribers[s.topic]) == 0 {
+			delete(b.subscribers, s.topic)
+		}
+
+		close(s.ch)
+	})
+}
@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fluxio-backend/pkg/common/schema"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fluxio-backend/pkg/repository/pgsql"
+	"fluxio-backend/pkg/repository/pgsql/tables"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JobRepository struct {
+	db *pgsql.PgSQL
+	l  schema.Logger
+}
+
+func NewJobRepository(db *pgsql.PgSQL, logger schema.Logger) *JobRepository {
+	return &JobRepository{
+		db: db,
+		l:  logger,
+	}
+}
+
+// Inserts a new job in the queued state.
+func (r *JobRepository) Enqueue(ctx context.Context, job model.Job) (id model.JobID, err error) {
+	logger := r.l.With("job_type", job.Type.String())
+
+	if strings.EqualFold(job.Type.String(), "") {
+		err = fluxerrors.ErrInvalidJobType
+		return
+	}
+
+	if len(job.Payload) == 0 {
+		job.Payload = json.RawMessage("{}")
+	}
+
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = 1
+	}
+
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+
+	row := tables.Job{
+		Type:        job.Type.String(),
+		Payload:     string(job.Payload),
+		UniqueKey:   job.UniqueKey,
+		State:       model.JobStateQueued.String(),
+		MaxAttempts: job.MaxAttempts,
+		RunAt:       job.RunAt,
+	}
+
+	tx := r.db.DB.WithContext(ctx).Create(&row)
+	if tx.Error != nil {
+		if strings.Contains(tx.Error.Error(), "idx_jobs_active_unique_key") {
+			err = fluxerrors.ErrJobAlreadyQueued
+			return
+		}
+
+		logger.Error("Failed to enqueue a job", tx.Error)
+		err = fluxerrors.ErrJobEnqueueFailed
+		return
+	}
+
+	id = model.JobID(row.ID.String())
+	return
+}
+
+// Checks whether a queued or running job exists for the unique key.
+func (r *JobRepository) HasActiveJob(ctx context.Context, uniqueKey string) (exists bool, err error) {
+	tx := r.db.DB.WithContext(ctx).Model(&tables.Job{}).Select("count(*) > 0").
+		Where("unique_key = ? AND state IN ?", uniqueKey, []string{model.JobStateQueued.String(), model.JobStateRunning.String()}).
+		Find(&exists)
+
+	if tx.Error != nil {
+		r.l.With("unique_key", uniqueKey).Error("Failed to check for an active job", tx.Error)
+		err = tx.Error
+		return
+	}
+
+	return
+}
+
+// Checks whether a job in any state was ever queued for the unique key.
+func (r *JobRepository) HasJob(ctx context.Context, uniqueKey string) (exists bool, err error) {
+	tx := r.db.DB.WithContext(ctx).Model(&tables.Job{}).Select("count(*) > 0").
+		Where("unique_key = ?", uniqueKey).
+		Find(&exists)
+
+	if tx.Error != nil {
+		r.l.With("unique_key", uniqueKey).Error("Failed to check for a job", tx.Error)
+		err = tx.Error
+		return
+	}
+
+	return
+}
+
+// Claims the next runnable job of the given types. A job is runnable when it is queued and due, or when
+// it is running but its lease has expired because the worker holding it died.
+// The row is locked with SKIP LOCKED so concurrent workers never claim the same job.
+func (r *JobRepository) Claim(ctx context.Context, workerID string, jobTypes []model.JobType, lease time.Duration) (job model.Job, err error) {
+	if len(jobTypes) == 0 {
+		err = fluxerrors.ErrNoJobAvailable
+		return
+	}
+
+	types := make([]string, 0, len(jobTypes))
+	for _, jobType := range jobTypes {
+		types = append(types, jobType.String())
+	}
+
+	claimed := false
+
+	err = r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		row := tables.Job{}
+		now := time.Now()
+
+		res := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("type IN ?", types).
+			Where("(state = ? AND run_at <= ?) OR (state = ? AND lease_expires_at < ?)",
+				model.JobStateQueued.String(), now, model.JobStateRunning.String(), now).
+			Order("run_at").
+			Limit(1).
+			Find(&row)
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return nil
+		}
+
+		// The job kept losing its lease, most likely because it crashes the worker. Stop retrying it.
+		if row.State == model.JobStateRunning.String() && row.Attempts >= row.MaxAttempts {
+			res = tx.Model(&tables.Job{}).Where("id = ?", row.ID).Updates(map[string]interface{}{
+				"state":            model.JobStateFailed.String(),
+				"lease_expires_at": nil,
+				"last_error":       "job lease expired on the final attempt",
+			})
+
+			return res.Error
+		}
+
+		leaseExpiresAt := now.Add(lease)
+
+		res = tx.Model(&tables.Job{}).Where("id = ?", row.ID).Updates(map[string]interface{}{
+			"state":            model.JobStateRunning.String(),
+			"attempts":         gorm.Expr("attempts + 1"),
+			"lease_expires_at": leaseExpiresAt,
+			"locked_by":        workerID,
+		})
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		row.State = model.JobStateRunning.String()
+		row.Attempts++
+		row.LeaseExpiresAt = &leaseExpiresAt
+		row.LockedBy = workerID
+
+		job = r.toJobModel(&row)
+		claimed = true
+		return nil
+	})
+
+	if err != nil {
+		r.l.With("worker_id", workerID).Error("Failed to claim a job", err)
+		err = fluxerrors.ErrJobClaimFailed
+		return
+	}
+
+	if !claimed {
+		err = fluxerrors.ErrNoJobAvailable
+		return
+	}
+
+	return
+}
+
+// Extends the lease of a running job held by the worker.
+func (r *JobRepository) ExtendLease(ctx context.Context, id model.JobID, workerID string, lease time.Duration) (err error) {
+	return r.updateHeldJob(ctx, id, workerID, map[string]interface{}{
+		"lease_expires_at": time.Now().Add(lease),
+	})
+}
+
+// Marks a running job held by the worker as completed.
+func (r *JobRepository) Complete(ctx context.Context, id model.JobID, workerID string) (err error) {
+	return r.updateHeldJob(ctx, id, workerID, map[string]interface{}{
+		"state":            model.JobStateCompleted.String(),
+		"lease_expires_at": nil,
+		"last_error":       "",
+	})
+}
+
+// Puts a running job held by the worker back in the queue to be run again at the given time.
+func (r *JobRepository) Retry(ctx context.Context, id model.JobID, workerID string, runAt time.Time, lastError string) (err error) {
+	return r.updateHeldJob(ctx, id, workerID, map[string]interface{}{
+		"state":            model.JobStateQueued.String(),
+		"run_at":           runAt,
+		"lease_expires_at": nil,
+		"locked_by":        "",
+		"last_error":       lastError,
+	})
+}
+
+// Puts a running job held by the worker back in the queue without consuming an attempt.
+func (r *JobRepository) Reschedule(ctx context.Context, id model.JobID, workerID string, runAt time.Time, lastError string) (err error) {
+	return r.updateHeldJob(ctx, id, workerID, map[string]interface{}{
+		"state":            model.JobStateQueued.String(),
+		"attempts":         gorm.Expr("GREATEST(attempts - 1, 0)"),
+		"run_at":           runAt,
+		"lease_expires_at": nil,
+		"locked_by":        "",
+		"last_error":       lastError,
+	})
+}
+
+// Marks a running job held by the worker as permanently failed.
+func (r *JobRepository) Fail(ctx context.Context, id model.JobID, workerID string, lastError string) (err error) {
+	return r.updateHeldJob(ctx, id, workerID, map[string]interface{}{
+		"state":            model.JobStateFailed.String(),
+		"lease_expires_at": nil,
+		"last_error":       lastError,
+	})
+}
+
+// Updates a running job only if the worker still holds its lease.
+func (r *JobRepository) updateHeldJob(ctx context.Context, id model.JobID, workerID string, updateData map[string]interface{}) (err error) {
+	logger := r.l.With("job_id", id.String()).With("worker_id", workerID)
+
+	parsedID, err := uuid.Parse(id.String())
+	if err != nil {
+		err = fluxerrors.ErrInvalidJobID
+		return
+	}
+
+	tx := r.db.DB.WithContext(ctx).Model(&tables.Job{}).
+		Where("id = ? AND state = ? AND locked_by = ?", parsedID, model.JobStateRunning.String(), workerID).
+		Updates(updateData)
+
+	if tx.Error != nil {
+		logger.Error("Failed to update the job", tx.Error)
+		err = fluxerrors.ErrJobStateUpdateFailed
+		return
+	}
+
+	if tx.RowsAffected == 0 {
+		logger.Debug("Job is no longer held by the worker.")
+		err = fluxerrors.ErrJobLeaseLost
+		return
+	}
+
+	return
+}
+
+func (r *JobRepository) toJobModel(row *tables.Job) (job model.Job) {
+	job = model.Job{
+		ID:             model.JobID(row.ID.String()),
+		Type:           model.JobType(row.Type),
+		Payload:        json.RawMessage(row.Payload),
+		UniqueKey:      row.UniqueKey,
+		State:          model.JobState(row.State),
+		Attempts:       row.Attempts,
+		MaxAttempts:    row.MaxAttempts,
+		RunAt:          row.RunAt,
+		LeaseExpiresAt: row.LeaseExpiresAt,
+		LockedBy:       row.LockedBy,
+		LastError:      row.LastError,
+		CreatedAt:      &row.CreatedAt,
+		UpdatedAt:      &row.UpdatedAt,
+	}
+
+	return
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fluxio-backend/pkg/repository/pgsql/tables"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// Stores the manifests of a packaged video. Existing manifests of the same format are replaced.
+func (r *VideoRepository) SaveVideoManifests(ctx context.Context, id model.VideoID, manifests []model.VideoManifest) (err error) {
+	logger := r.l.With("video_id", id.String())
+
+	parsedVidID, err := uuid.Parse(id.String())
+	if err != nil {
+		err = fluxerrors.ErrInvalidVideoID
+		return
+	}
+
+	if len(manifests) == 0 {
+		return
+	}
+
+	rows := make([]tables.VideoManifest, 0, len(manifests))
+	for _, manifest := range manifests {
+		if !manifest.Format.IsAcceptable() {
+			err = fluxerrors.ErrInvalidPackagingFormat
+			return
+		}
+
+		rows = append(rows, tables.VideoManifest{
+			VideoID:     parsedVidID,
+			Format:      manifest.Format.String(),
+			StoragePath: manifest.StoragePath,
+		})
+	}
+
+	tx := r.db.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "video_id"}, {Name: "format"}},
+		DoUpdates: clause.AssignmentColumns([]string{"storage_path", "updated_at"}),
+	}).Create(&rows)
+
+	if tx.Error != nil {
+		logger.Error("Failed to save the video manifests", tx.Error)
+		err = fluxerrors.ErrVideoManifestSaveFailed
+		return
+	}
+
+	return
+}
+
+// Returns the manifests of all the packaging formats available for a video.
+func (r *VideoRepository) GetVideoManifests(ctx context.Context, id model.VideoID) (manifests []model.VideoManifest, err error) {
+	parsedVidID, err := uuid.Parse(id.String())
+	if err != nil {
+		err = fluxerrors.ErrInvalidVideoID
+		return
+	}
+
+	rows := []tables.VideoManifest{}
+
+	tx := r.db.DB.WithContext(ctx).Where("video_id = ?", parsedVidID).Order("format").Find(&rows)
+	if tx.Error != nil {
+		r.l.With("video_id", id.String()).Error("Failed to get the video manifests", tx.Error)
+		err = tx.Error
+		return
+	}
+
+	manifests = make([]model.VideoManifest, 0, len(rows))
+	for _, row := range rows {
+		manifests = append(manifests, model.VideoManifest{
+			Format:      model.PackagingFormat(row.Format),
+			StoragePath: row.StoragePath,
+			URL:         r.GetPublicVideoFileURL(row.StoragePath),
+		})
+	}
+
+	return
+}
@@ -39,6 +39,24 @@ func NewPgSQL(cfg PgSQLConfig) (*PgSQL, error) {
 	db.AutoMigrate(&tables.User{})
 	db.AutoMigrate(&tables.Video{})
 	db.AutoMigrate(&tables.Thumbnail{})
+	db.AutoMigrate(&tables.ThumbnailVariant{})
+	db.AutoMigrate(&tables.ThumbnailSprite{})
+	db.AutoMigrate(&tables.ThumbnailPreview{})
+	db.AutoMigrate(&tables.VideoManifest{})
+	db.AutoMigrate(&tables.Job{})
+	db.AutoMigrate(&tables.VideoUpload{})
+	db.AutoMigrate(&tables.VideoUploadPart{})
+	db.AutoMigrate(&tables.StorageEvent{})
+	db.AutoMigrate(&tables.EventSourceCheckpoint{})
+	db.AutoMigrate(&tables.WebhookSubscription{})
+	db.AutoMigrate(&tables.WebhookEvent{})
+	db.AutoMigrate(&tables.WebhookDelivery{})
+
+	// Only a single active job is allowed per unique key. GORM cannot declare partial indexes so create it here.
+	db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_active_unique_key ON jobs (unique_key) WHERE unique_key <> '' AND state IN ('queued', 'running')")
+
+	// A video can only have one multipart upload in progress.
+	db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_video_uploads_active_video ON video_uploads (video_id) WHERE state = 'active'")
 
 	return &PgSQL{
 		DB: db,
@@ -0,0 +1,27 @@
+package tables
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Job struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Type           string     `gorm:"not null;index"`
+	Payload        string     `gorm:"type:jsonb;not null;default:'{}'"`
+	UniqueKey      string     `gorm:"not null;default:''"` // Prevents duplicate active jobs for the same entity
+	State          string     `gorm:"not null;index:idx_jobs_state_run_at,priority:1"`
+	Attempts       uint32     `gorm:"not null;default:0"`
+	MaxAttempts    uint32     `gorm:"not null;default:1"`
+	RunAt          time.Time  `gorm:"not null;index:idx_jobs_state_run_at,priority:2"` // Job is not claimed before this time
+	LeaseExpiresAt *time.Time // Running jobs whose lease expired are claimed again
+	LockedBy       string     `gorm:"not null;default:''"`
+	LastError      string     `gorm:"not null;default:''"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime:nano"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime:nano"`
+}
+
+func (Job) TableName() string {
+	return "jobs"
+}
@@ -0,0 +1,20 @@
+package tables
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type VideoManifest struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	VideoID     uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_video_manifest_format"`
+	Format      string    `gorm:"not null;uniqueIndex:idx_video_manifest_format"`
+	StoragePath string    `gorm:"not null"` // Path of the manifest in the public bucket
+	CreatedAt   time.Time `gorm:"autoCreateTime:nano"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime:nano"`
+}
+
+func (VideoManifest) TableName() string {
+	return "video_manifests"
+}
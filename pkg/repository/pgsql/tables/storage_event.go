@@ -0,0 +1,36 @@
+package tables
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Ledger of the storage events which were handled. Storage webhooks are delivered at least once so a
+// redelivered event is recognized by its identity and acknowledged without being handled again.
+type StorageEvent struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Bucket    string    `gorm:"not null;uniqueIndex:idx_storage_event_identity"`
+	Key       string    `gorm:"not null;uniqueIndex:idx_storage_event_identity"`
+	Sequencer string    `gorm:"not null;default:'';uniqueIndex:idx_storage_event_identity"`
+	ETag      string    `gorm:"column:etag;not null;default:'';uniqueIndex:idx_storage_event_identity"`
+	EventName string    `gorm:"not null"`
+	Size      int64     `gorm:"not null;default:0"`
+	CreatedAt time.Time `gorm:"autoCreateTime:nano;index"`
+}
+
+func (StorageEvent) TableName() string {
+	return "storage_events"
+}
+
+// Position of an event source which finds the storage events by reading the storage itself, like the
+// bucket poller, so that it resumes where it stopped after a restart.
+type EventSourceCheckpoint struct {
+	Name      string    `gorm:"primaryKey"`
+	Value     string    `gorm:"type:text;not null"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime:nano"`
+}
+
+func (EventSourceCheckpoint) TableName() string {
+	return "event_source_checkpoints"
+}
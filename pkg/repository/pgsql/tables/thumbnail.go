@@ -8,20 +8,80 @@ import (
 )
 
 type Thumbnail struct {
-	ID          uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	VideoID     uuid.UUID      `gorm:"type:uuid;not null;index"`
-	Width       uint16         `gorm:"not null"`
-	Height      uint16         `gorm:"not null"`
-	Format      string         `gorm:"not null"`
-	Size        uint32         `gorm:"not null"` // Size in bytes
-	TimeStamp   uint64         `gorm:"not null"` // Position in video where thumbnail was taken
-	CreatedAt   time.Time      `gorm:"autoCreateTime:nano"`
-	UpdatedAt   time.Time      `gorm:"autoUpdateTime:nano"`
-	DeletedAt   gorm.DeletedAt `gorm:"index"`
-	StoragePath string         `gorm:"default:''"`
-	IsDefault   bool           `gorm:"default:false;not null"`
+	ID          uuid.UUID          `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	VideoID     uuid.UUID          `gorm:"type:uuid;not null;index"`
+	Width       uint16             `gorm:"not null"`
+	Height      uint16             `gorm:"not null"`
+	Format      string             `gorm:"not null"`
+	Size        uint32             `gorm:"not null"` // Size in bytes
+	TimeStamp   uint64             `gorm:"not null"` // Position in video where thumbnail was taken
+	CreatedAt   time.Time          `gorm:"autoCreateTime:nano"`
+	UpdatedAt   time.Time          `gorm:"autoUpdateTime:nano"`
+	DeletedAt   gorm.DeletedAt     `gorm:"index"`
+	StoragePath string             `gorm:"default:''"`
+	IsDefault   bool               `gorm:"default:false;not null"`
+	BlurHash    string             `gorm:"default:''"`
+	LQIP        string             `gorm:"column:lqip;type:text;default:''"` // Base64 data URI of a tiny version of the thumbnail
+	Variants    []ThumbnailVariant `gorm:"foreignKey:ThumbnailID;references:ID;constraint:OnDelete:CASCADE"`
 }
 
 func (Thumbnail) TableName() string {
 	return "thumbnails"
 }
+
+// A size and format a thumbnail is stored in.
+type ThumbnailVariant struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ThumbnailID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_thumbnail_variant,priority:1"`
+	Width       uint16    `gorm:"not null;uniqueIndex:idx_thumbnail_variant,priority:2"`
+	Height      uint16    `gorm:"not null"`
+	Format      string    `gorm:"not null;uniqueIndex:idx_thumbnail_variant,priority:3"`
+	Size        uint32    `gorm:"not null"` // Size in KB
+	StoragePath string    `gorm:"not null"`
+	CreatedAt   time.Time `gorm:"autoCreateTime:nano"`
+}
+
+func (ThumbnailVariant) TableName() string {
+	return "thumbnail_variants"
+}
+
+// One set of sprite sheets per video. Regenerating the sheets replaces the row.
+type ThumbnailSprite struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	VideoID     uuid.UUID `gorm:"type:uuid;not null;uniqueIndex"`
+	Format      string    `gorm:"not null"`
+	Interval    uint32    `gorm:"not null"` // Seconds between two sampled frames
+	FrameWidth  uint16    `gorm:"not null"`
+	FrameHeight uint16    `gorm:"not null"`
+	Columns     uint16    `gorm:"not null"`
+	Rows        uint16    `gorm:"not null"`
+	SheetCount  uint32    `gorm:"not null"`
+	FrameCount  uint32    `gorm:"not null"`
+	StoragePath string    `gorm:"not null"` // Path of the WebVTT index in the thumbnail bucket
+	CreatedAt   time.Time `gorm:"autoCreateTime:nano"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime:nano"`
+}
+
+func (ThumbnailSprite) TableName() string {
+	return "thumbnail_sprites"
+}
+
+// One teaser per video. Regenerating the teaser replaces the row.
+type ThumbnailPreview struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	VideoID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex"`
+	Width        uint16    `gorm:"not null"`
+	Height       uint16    `gorm:"not null"`
+	Duration     uint32    `gorm:"not null"` // Length of the teaser in seconds
+	SegmentCount uint8     `gorm:"not null"`
+	WebPSize     uint32    `gorm:"column:webp_size;not null"` // Size in KB
+	MP4Size      uint32    `gorm:"not null"`                  // Size in KB
+	WebPPath     string    `gorm:"column:webp_path;not null"`
+	MP4Path      string    `gorm:"not null"`
+	CreatedAt    time.Time `gorm:"autoCreateTime:nano"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime:nano"`
+}
+
+func (ThumbnailPreview) TableName() string {
+	return "thumbnail_previews"
+}
@@ -0,0 +1,41 @@
+package tables
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type VideoUpload struct {
+	ID          uuid.UUID         `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	VideoID     uuid.UUID         `gorm:"type:uuid;not null;index"`
+	UploadID    string            `gorm:"not null"` // Upload ID assigned by the object storage
+	StoragePath string            `gorm:"not null"`
+	State       string            `gorm:"not null"`
+	PartSize    int64             `gorm:"not null"`
+	Protocol    string            `gorm:"not null;default:'s3'"` // s3 when the client uploads the parts itself, tus when they are streamed through the API
+	Length      int64             `gorm:"not null;default:0"`    // Total size declared by a tus client
+	Offset      int64             `gorm:"column:upload_offset;not null;default:0"`
+	ExpiresAt   *time.Time        `gorm:"index"`
+	CreatedAt   time.Time         `gorm:"autoCreateTime:nano"`
+	UpdatedAt   time.Time         `gorm:"autoUpdateTime:nano"`
+	Parts       []VideoUploadPart `gorm:"foreignKey:VideoUploadID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (VideoUpload) TableName() string {
+	return "video_uploads"
+}
+
+type VideoUploadPart struct {
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	VideoUploadID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_video_upload_part_number"`
+	PartNumber    int64     `gorm:"not null;uniqueIndex:idx_video_upload_part_number"`
+	ETag          string    `gorm:"column:etag;not null"`
+	Size          int64     `gorm:"not null"`
+	CreatedAt     time.Time `gorm:"autoCreateTime:nano"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime:nano"`
+}
+
+func (VideoUploadPart) TableName() string {
+	return "video_upload_parts"
+}
@@ -8,30 +8,41 @@ import (
 )
 
 type Video struct {
-	ID              uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	Title           string         `gorm:"not null" json:"title"` // Removed unique constraint
-	Description     string         `gorm:"not null" json:"description"`
-	ParentID        *uuid.UUID     `gorm:"type:uuid" json:"parent_id,omitempty"` // Should be nullable for original videos
-	Width           uint32         `json:"width"`                                // Will be unknown during upload
-	Height          uint32         `json:"height"`                               // Will be unknown during upload
-	UserID          uuid.UUID      `gorm:"type:uuid;not null" json:"user_id"`
-	Format          string         `json:"format"`            // Will be unknown initially
-	Length          uint64         `json:"length"`            // Will be unknown during upload
-	AudioSampleRate uint32         `json:"audio_sample_rate"` // Will be unknown during upload
-	AudioCodec      string         `json:"audio_codec"`       // Will be unknown during upload
-	RetryCount      uint8          `gorm:"default:0" json:"retry_count"`
-	Status          string         `gorm:"not null" json:"status"`
-	InternalStatus  string         `gorm:"not null default:'upload_pending'" json:"internal_status"` // Added to track internal processing status
-	CreatedAt       time.Time      `gorm:"autoCreateTime:nano" json:"created_at"`
-	UpdatedAt       time.Time      `gorm:"autoUpdateTime:nano" json:"updated_at"`
-	DeletedAt       gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"` // Should be nullable
-	IsFeatured      bool           `gorm:"default:false" json:"is_featured,omitempty"`
-	Visibility      string         `gorm:"not null" json:"visibility"`
-	Slug            string         `gorm:"unique;not null" json:"slug"` // Already has unique and not null constraints
-	Size            float32        `json:"size"`                        // Will be unknown during initial upload. Size is in kb
-	Language        string         `json:"language"`                    // Might be unknown initially
-	StoragePath     string         `gorm:"default:''" json:"storage_path"`
-	Thumbnails      []Thumbnail    `gorm:"foreignKey:VideoID;references:ID;constraint:OnDelete:CASCADE"`
+	ID                  uuid.UUID       `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Title               string          `gorm:"not null" json:"title"` // Removed unique constraint
+	Description         string          `gorm:"not null" json:"description"`
+	ParentID            *uuid.UUID      `gorm:"type:uuid" json:"parent_id,omitempty"` // Should be nullable for original videos
+	Width               uint32          `json:"width"`                                // Will be unknown during upload
+	Height              uint32          `json:"height"`                               // Will be unknown during upload
+	UserID              uuid.UUID       `gorm:"type:uuid;not null" json:"user_id"`
+	Format              string          `json:"format"`            // Will be unknown initially
+	Length              uint64          `json:"length"`            // Will be unknown during upload
+	AudioSampleRate     uint32          `json:"audio_sample_rate"` // Will be unknown during upload
+	AudioCodec          string          `json:"audio_codec"`       // Will be unknown during upload
+	RetryCount          uint8           `gorm:"default:0" json:"retry_count"`
+	ThumbnailRetryCount uint8           `gorm:"default:0" json:"thumbnail_retry_count"`
+	Status              string          `gorm:"not null" json:"status"`
+	InternalStatus      string          `gorm:"not null default:'upload_pending'" json:"internal_status"` // Added to track internal processing status
+	CreatedAt           time.Time       `gorm:"autoCreateTime:nano" json:"created_at"`
+	UpdatedAt           time.Time       `gorm:"autoUpdateTime:nano" json:"updated_at"`
+	DeletedAt           gorm.DeletedAt  `gorm:"index" json:"deleted_at,omitempty"` // Should be nullable
+	IsFeatured          bool            `gorm:"default:false" json:"is_featured,omitempty"`
+	Visibility          string          `gorm:"not null" json:"visibility"`
+	Slug                string          `gorm:"unique;not null" json:"slug"` // Already has unique and not null constraints
+	Size                float32         `json:"size"`                        // Will be unknown during initial upload. Size is in kb
+	Language            string          `json:"language"`                    // Might be unknown initially
+	StoragePath         string          `gorm:"default:''" json:"storage_path"`
+	ProbeData           string          `gorm:"type:text;default:''" json:"-"`   // Raw ffprobe output of the uploaded file
+	StageAttempts       uint8           `gorm:"default:0" json:"stage_attempts"` // Failed attempts of the current processing stage
+	LastError           string          `gorm:"type:text;default:''" json:"last_error"`
+	RawFileDeletedAt    *time.Time      `json:"raw_file_deleted_at"`
+	ProgressStage       string          `gorm:"default:''" json:"progress_stage"` // Stage of the running ffmpeg step, empty when nothing runs
+	ProgressStep        string          `gorm:"default:''" json:"progress_step"`
+	ProgressPercent     float64         `gorm:"default:0" json:"progress_percent"`
+	ProgressETASeconds  *uint64         `json:"progress_eta_seconds"`
+	ProgressUpdatedAt   *time.Time      `json:"progress_updated_at"`
+	Thumbnails          []Thumbnail     `gorm:"foreignKey:VideoID;references:ID;constraint:OnDelete:CASCADE"`
+	Manifests           []VideoManifest `gorm:"foreignKey:VideoID;references:ID;constraint:OnDelete:CASCADE"`
 }
 
 func (Video) TableName() string {
@@ -0,0 +1,60 @@
+package tables
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WebhookSubscription struct {
+	ID         uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID     uuid.UUID      `gorm:"type:uuid;not null;index"`
+	URL        string         `gorm:"not null"`
+	Secret     string         `gorm:"not null"` // Kept in plain text since every payload is signed with it
+	EventTypes []string       `gorm:"type:jsonb;serializer:json;not null;default:'[]'"`
+	CreatedAt  time.Time      `gorm:"autoCreateTime:nano"`
+	UpdatedAt  time.Time      `gorm:"autoUpdateTime:nano"`
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+}
+
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// Outbox of the video lifecycle events. Events are written in the same transaction as the status change
+// and relayed to the matching subscriptions afterwards.
+type WebhookEvent struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index"`
+	VideoID   uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Type      string     `gorm:"not null"`
+	Payload   string     `gorm:"type:jsonb;not null;default:'{}'"`
+	RelayedAt *time.Time `gorm:"index"` // Null until the deliveries of the event are created
+	CreatedAt time.Time  `gorm:"autoCreateTime:nano;index"`
+}
+
+func (WebhookEvent) TableName() string {
+	return "webhook_events"
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	SubscriptionID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_webhook_delivery_event,priority:1"`
+	EventID        uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_webhook_delivery_event,priority:2"`
+	EventType      string    `gorm:"not null"`
+	UserID         uuid.UUID `gorm:"type:uuid;not null;index"`
+	URL            string    `gorm:"not null"`
+	Status         string    `gorm:"not null;index"`
+	Attempts       uint32    `gorm:"not null;default:0"`
+	ResponseStatus int       `gorm:"not null;default:0"`
+	LastError      string    `gorm:"not null;default:''"`
+	NextAttemptAt  *time.Time
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time `gorm:"autoCreateTime:nano;index"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime:nano"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
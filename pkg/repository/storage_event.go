@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"fluxio-backend/pkg/model"
+	"fluxio-backend/pkg/repository/pgsql/tables"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Records a storage event in the ledger. Recorded is false when the same event was recorded before.
+func (v *VideoRepository) RecordStorageEvent(ctx context.Context, event model.StorageEvent) (recorded bool, err error) {
+	row := tables.StorageEvent{
+		Bucket:    event.Bucket,
+		Key:       event.Key,
+		Sequencer: event.Sequencer,
+		ETag:      event.ETag,
+		EventName: event.EventName,
+		Size:      event.Size,
+	}
+
+	tx := v.db.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&row)
+	if tx.Error != nil {
+		v.l.With("bucket", event.Bucket).With("key", event.Key).Error("Failed to record the storage event", tx.Error)
+		err = tx.Error
+		return
+	}
+
+	recorded = tx.RowsAffected > 0
+	return
+}
+
+// Removes a storage event from the ledger so that a redelivery handles it again.
+func (v *VideoRepository) DeleteStorageEvent(ctx context.Context, event model.StorageEvent) (err error) {
+	tx := v.db.DB.WithContext(ctx).
+		Where("bucket = ? AND key = ? AND sequencer = ? AND etag = ?", event.Bucket, event.Key, event.Sequencer, event.ETag).
+		Delete(&tables.StorageEvent{})
+
+	if tx.Error != nil {
+		v.l.With("bucket", event.Bucket).With("key", event.Key).Error("Failed to delete the storage event", tx.Error)
+		err = tx.Error
+		return
+	}
+
+	return
+}
+
+// Returns the stored position of an event source. The value is empty when the source never stored one.
+func (v *VideoRepository) GetEventSourceCheckpoint(ctx context.Context, name string) (value []byte, err error) {
+	row := tables.EventSourceCheckpoint{}
+
+	tx := v.db.DB.WithContext(ctx).Where("name = ?", name).First(&row)
+	if tx.Error != nil {
+		if tx.Error == gorm.ErrRecordNotFound {
+			return
+		}
+
+		v.l.With("checkpoint", name).Error("Failed to get the event source checkpoint", tx.Error)
+		err = tx.Error
+		return
+	}
+
+	value = []byte(row.Value)
+	return
+}
+
+// Stores the position of an event source, replacing the earlier one.
+func (v *VideoRepository) SaveEventSourceCheckpoint(ctx context.Context, name string, value []byte) (err error) {
+	row := tables.EventSourceCheckpoint{
+		Name:  name,
+		Value: string(value),
+	}
+
+	tx := v.db.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&row)
+
+	if tx.Error != nil {
+		v.l.With("checkpoint", name).Error("Failed to save the event source checkpoint", tx.Error)
+		err = tx.Error
+		return
+	}
+
+	return
+}
@@ -2,17 +2,15 @@ package repository
 
 import (
 	"context"
-	"fluxio-backend/pkg/constants"
 	fluxerrors "fluxio-backend/pkg/errors"
 	"fluxio-backend/pkg/model"
 	"fluxio-backend/pkg/repository/pgsql/tables"
-	"fmt"
-	"net/url"
+	"io"
 	"strings"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func (v *VideoRepository) CreateThumbnail(ctx context.Context, thumbnail model.Thumbnail) (id model.ThumbnailID, err error) {
@@ -38,9 +36,23 @@ func (v *VideoRepository) CreateThumbnail(ctx context.Context, thumbnail model.T
 		Format:      thumbnail.Format,
 		StoragePath: thumbnail.StoragePath,
 		TimeStamp:   thumbnail.TimeStamp,
+		IsDefault:   thumbnail.IsDefault,
+		BlurHash:    thumbnail.BlurHash,
+		LQIP:        thumbnail.LQIP,
 	}
 
-	tx := v.db.DB.Create(&insertData)
+	for _, variant := range thumbnail.Variants {
+		insertData.Variants = append(insertData.Variants, tables.ThumbnailVariant{
+			Width:       variant.Width,
+			Height:      variant.Height,
+			Format:      variant.Format,
+			Size:        variant.Size,
+			StoragePath: variant.StoragePath,
+		})
+	}
+
+	// The variants are created in the same transaction as the thumbnail.
+	tx := v.db.DB.WithContext(ctx).Create(&insertData)
 
 	if tx.Error != nil {
 		logger.Error("Error when creating a new thumbnail in repo", tx.Error)
@@ -53,32 +65,314 @@ func (v *VideoRepository) CreateThumbnail(ctx context.Context, thumbnail model.T
 	return
 }
 
-func (v *VideoRepository) GenerateThumbnailUploadURL(ctx context.Context, id model.VideoID, timestamp uint64, extension string) (url *url.URL, err error) {
+// Uploads a variant of a thumbnail image to the thumbnail bucket.
+func (v *VideoRepository) UploadThumbnailVariantFile(ctx context.Context, id model.VideoID, timestamp uint64, width uint16, format string, body io.Reader) (err error) {
+	logger := v.l.With("video_id", id.String()).With("width", width).With("format", format)
+	path := v.GetThumbnailVariantFilePath(id, timestamp, width, format)
+
+	if strings.EqualFold(path, "") || !model.IsAcceptableThumbnailFormat(format) {
+		err = fluxerrors.ErrThumbnailCreationFailed
+		return
+	}
+
+	err = v.store.Put(ctx, v.thumbnailBucketName, path, body, model.ThumbnailFormatMimeType(format))
+	if err != nil {
+		logger.Error("Failed to upload the thumbnail", err)
+		err = fluxerrors.ErrThumbnailCreationFailed
+		return
+	}
+
+	return
+}
+
+// Returns the thumbnails of a video with their variants, the default thumbnail first.
+func (v *VideoRepository) GetVideoThumbnails(ctx context.Context, id model.VideoID) (thumbnails []model.Thumbnail, err error) {
+	parsedVidId, err := uuid.Parse(id.String())
+	if err != nil {
+		err = fluxerrors.ErrInvalidVideoID
+		return
+	}
+
+	rows := []tables.Thumbnail{}
+
+	tx := v.db.DB.WithContext(ctx).
+		Preload("Variants", func(db *gorm.DB) *gorm.DB {
+			return db.Order("width, format")
+		}).
+		Where("video_id = ?", parsedVidId).
+		Order("is_default DESC, time_stamp").
+		Find(&rows)
+
+	if tx.Error != nil {
+		v.l.With("video_id", id.String()).Error("Failed to get the video thumbnails", tx.Error)
+		err = tx.Error
+		return
+	}
+
+	thumbnails = make([]model.Thumbnail, 0, len(rows))
+	for _, row := range rows {
+		thumbnail := model.Thumbnail{
+			ID:          model.ThumbnailID(row.ID.String()),
+			VideoID:     id,
+			Width:       row.Width,
+			Height:      row.Height,
+			Format:      row.Format,
+			Size:        row.Size,
+			TimeStamp:   row.TimeStamp,
+			CreatedAt:   &row.CreatedAt,
+			UpdatedAt:   &row.UpdatedAt,
+			StoragePath: row.StoragePath,
+			URL:         v.GetThumbnailFileURL(row.StoragePath),
+			BlurHash:    row.BlurHash,
+			LQIP:        row.LQIP,
+			IsDefault:   row.IsDefault,
+			Variants:    make([]model.ThumbnailVariant, 0, len(row.Variants)),
+		}
+
+		for _, variant := range row.Variants {
+			thumbnail.Variants = append(thumbnail.Variants, model.ThumbnailVariant{
+				Width:       variant.Width,
+				Height:      variant.Height,
+				Format:      variant.Format,
+				Size:        variant.Size,
+				StoragePath: variant.StoragePath,
+				URL:         v.GetThumbnailFileURL(variant.StoragePath),
+			})
+		}
+
+		thumbnail.Sources = model.NewThumbnailSources(thumbnail.Variants)
+		thumbnails = append(thumbnails, thumbnail)
+	}
+
+	return
+}
+
+// Soft deletes the thumbnails of a video except the ones to keep.
+func (v *VideoRepository) DeleteVideoThumbnails(ctx context.Context, id model.VideoID, keepIDs []model.ThumbnailID) (err error) {
 	logger := v.l.With("video_id", id.String())
-	path := v.generateThumbnailFileS3Path(id, timestamp, extension)
 
-	if strings.EqualFold(path, "") {
-		err = fluxerrors.ErrThumbnailURLGenerationFailed
+	parsedVidId, err := uuid.Parse(id.String())
+	if err != nil {
+		err = fluxerrors.ErrInvalidVideoID
+		return
+	}
+
+	query := v.db.DB.WithContext(ctx).Where("video_id = ?", parsedVidId)
+
+	if len(keepIDs) > 0 {
+		rawKeepIDs := make([]string, 0, len(keepIDs))
+		for _, keepID := range keepIDs {
+			rawKeepIDs = append(rawKeepIDs, keepID.String())
+		}
+
+		query = query.Where("id NOT IN ?", rawKeepIDs)
+	}
+
+	tx := query.Delete(&tables.Thumbnail{})
+	if tx.Error != nil {
+		logger.Error("Failed to delete the thumbnails of the video", tx.Error)
+		err = fluxerrors.ErrThumbnailDeleteFailed
+		return
+	}
+
+	return
+}
+
+// Uploads a sprite sheet or the WebVTT index of the sprites of a video to the thumbnail bucket.
+func (v *VideoRepository) UploadThumbnailSpriteFile(ctx context.Context, id model.VideoID, fileName string, contentType string, body io.Reader) (err error) {
+	logger := v.l.With("video_id", id.String()).With("file_name", fileName)
+
+	if strings.EqualFold(strings.Trim(fileName, "/"), "") {
+		err = fluxerrors.ErrInvalidVideoFileName
+		return
+	}
+
+	err = v.store.Put(ctx, v.thumbnailBucketName, v.GetThumbnailSpriteFilePath(id, fileName), body, contentType)
+	if err != nil {
+		logger.Error("Failed to upload the sprite file", err)
+		err = fluxerrors.ErrThumbnailSpriteFailed
 		return
 	}
-	// Remove the bucket name from the path to avoid double prefixing.
-	path = strings.TrimLeft(path, fmt.Sprintf("%s/", v.thumbnailBucketName))
 
-	s3Request, _ := v.s3Client.PutObjectRequest(&s3.PutObjectInput{
-		Bucket:      aws.String(v.thumbnailBucketName),
-		Key:         aws.String(path),
-		ContentType: aws.String(fmt.Sprintf("image/%s", extension)),
-	})
+	return
+}
+
+// Uploads the animated WebP or the MP4 of the preview clip of a video to the thumbnail bucket.
+func (v *VideoRepository) UploadThumbnailPreviewFile(ctx context.Context, id model.VideoID, fileName string, contentType string, body io.Reader) (err error) {
+	logger := v.l.With("video_id", id.String()).With("file_name", fileName)
 
-	rawURL, err := s3Request.Presign(constants.PreSignedVidUploadURLExpireTime)
+	if strings.EqualFold(strings.Trim(fileName, "/"), "") {
+		err = fluxerrors.ErrInvalidVideoFileName
+		return
+	}
 
+	err = v.store.Put(ctx, v.thumbnailBucketName, v.GetThumbnailPreviewFilePath(id, fileName), body, contentType)
 	if err != nil {
-		logger.Error("Failed to create a presigned URL for thumbnail upload url", err)
-		err = fluxerrors.ErrThumbnailURLGenerationFailed
+		logger.Error("Failed to upload the preview file", err)
+		err = fluxerrors.ErrThumbnailPreviewFailed
 		return
 	}
 
-	url, _ = url.Parse(rawURL)
+	return
+}
+
+// Stores the sprite metadata of a video. The sprites of an earlier run are replaced.
+func (v *VideoRepository) SaveThumbnailSprite(ctx context.Context, sprite model.ThumbnailSprite) (err error) {
+	logger := v.l.With("video_id", sprite.VideoID.String())
+
+	parsedVidId, err := uuid.Parse(sprite.VideoID.String())
+	if err != nil {
+		err = fluxerrors.ErrInvalidVideoID
+		return
+	}
+
+	row := tables.ThumbnailSprite{
+		VideoID:     parsedVidId,
+		Format:      sprite.Format,
+		Interval:    sprite.Interval,
+		FrameWidth:  sprite.FrameWidth,
+		FrameHeight: sprite.FrameHeight,
+		Columns:     sprite.Columns,
+		Rows:        sprite.Rows,
+		SheetCount:  sprite.SheetCount,
+		FrameCount:  sprite.FrameCount,
+		StoragePath: sprite.StoragePath,
+	}
+
+	tx := v.db.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "video_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"format", "interval", "frame_width", "frame_height", "columns", "rows",
+			"sheet_count", "frame_count", "storage_path", "updated_at",
+		}),
+	}).Create(&row)
+
+	if tx.Error != nil {
+		logger.Error("Failed to save the thumbnail sprite", tx.Error)
+		err = fluxerrors.ErrThumbnailSpriteFailed
+		return
+	}
+
+	return
+}
+
+// Returns the sprite metadata of a video along with the URL of its WebVTT index.
+func (v *VideoRepository) GetThumbnailSprite(ctx context.Context, id model.VideoID) (sprite model.ThumbnailSprite, err error) {
+	parsedVidId, err := uuid.Parse(id.String())
+	if err != nil {
+		err = fluxerrors.ErrInvalidVideoID
+		return
+	}
+
+	row := tables.ThumbnailSprite{}
+
+	tx := v.db.DB.WithContext(ctx).Where("video_id = ?", parsedVidId).Limit(1).Find(&row)
+	if tx.Error != nil {
+		v.l.With("video_id", id.String()).Error("Failed to get the thumbnail sprite", tx.Error)
+		err = tx.Error
+		return
+	}
+
+	if tx.RowsAffected == 0 {
+		err = fluxerrors.ErrThumbnailSpriteNotFound
+		return
+	}
+
+	sprite = model.ThumbnailSprite{
+		VideoID:     id,
+		Format:      row.Format,
+		Interval:    row.Interval,
+		FrameWidth:  row.FrameWidth,
+		FrameHeight: row.FrameHeight,
+		Columns:     row.Columns,
+		Rows:        row.Rows,
+		SheetCount:  row.SheetCount,
+		FrameCount:  row.FrameCount,
+		StoragePath: row.StoragePath,
+		URL:         v.GetThumbnailFileURL(row.StoragePath),
+		CreatedAt:   &row.CreatedAt,
+		UpdatedAt:   &row.UpdatedAt,
+	}
+
+	return
+}
+
+// Stores the preview clip metadata of a video. The preview of an earlier run is replaced.
+func (v *VideoRepository) SaveThumbnailPreview(ctx context.Context, preview model.ThumbnailPreview) (err error) {
+	logger := v.l.With("video_id", preview.VideoID.String())
+
+	parsedVidId, err := uuid.Parse(preview.VideoID.String())
+	if err != nil {
+		err = fluxerrors.ErrInvalidVideoID
+		return
+	}
+
+	row := tables.ThumbnailPreview{
+		VideoID:      parsedVidId,
+		Width:        preview.Width,
+		Height:       preview.Height,
+		Duration:     preview.Duration,
+		SegmentCount: preview.SegmentCount,
+		WebPSize:     preview.WebPSize,
+		MP4Size:      preview.MP4Size,
+		WebPPath:     preview.WebPPath,
+		MP4Path:      preview.MP4Path,
+	}
+
+	tx := v.db.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "video_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"width", "height", "duration", "segment_count", "webp_size", "mp4_size",
+			"webp_path", "mp4_path", "updated_at",
+		}),
+	}).Create(&row)
+
+	if tx.Error != nil {
+		logger.Error("Failed to save the preview clip", tx.Error)
+		err = fluxerrors.ErrThumbnailPreviewFailed
+		return
+	}
+
+	return
+}
+
+// Returns the preview clip metadata of a video along with the URLs of its files.
+func (v *VideoRepository) GetThumbnailPreview(ctx context.Context, id model.VideoID) (preview model.ThumbnailPreview, err error) {
+	parsedVidId, err := uuid.Parse(id.String())
+	if err != nil {
+		err = fluxerrors.ErrInvalidVideoID
+		return
+	}
+
+	row := tables.ThumbnailPreview{}
+
+	tx := v.db.DB.WithContext(ctx).Where("video_id = ?", parsedVidId).Limit(1).Find(&row)
+	if tx.Error != nil {
+		v.l.With("video_id", id.String()).Error("Failed to get the preview clip", tx.Error)
+		err = tx.Error
+		return
+	}
+
+	if tx.RowsAffected == 0 {
+		err = fluxerrors.ErrThumbnailPreviewNotFound
+		return
+	}
+
+	preview = model.ThumbnailPreview{
+		VideoID:      id,
+		Width:        row.Width,
+		Height:       row.Height,
+		Duration:     row.Duration,
+		SegmentCount: row.SegmentCount,
+		WebPSize:     row.WebPSize,
+		MP4Size:      row.MP4Size,
+		WebPPath:     row.WebPPath,
+		MP4Path:      row.MP4Path,
+		WebPURL:      v.GetThumbnailFileURL(row.WebPPath),
+		MP4URL:       v.GetThumbnailFileURL(row.MP4Path),
+		CreatedAt:    &row.CreatedAt,
+		UpdatedAt:    &row.UpdatedAt,
+	}
 
 	return
 }
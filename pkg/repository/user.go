@@ -101,6 +101,16 @@ func (u *UserRepository) GetUserByID(id model.UserID) (user model.User, err erro
 		return
 	}
 
+	user = model.User{
+		ID:            model.UserID(userTable.ID.String()),
+		Username:      userTable.Username,
+		Email:         userTable.Email,
+		Password:      userTable.Password,
+		UpdatedAt:     userTable.UpdatedAt,
+		CreatedAt:     userTable.CreatedAt,
+		IsBlackListed: userTable.IsBlackListed,
+	}
+
 	return
 }
 
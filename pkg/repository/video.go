@@ -7,64 +7,55 @@ import (
 	"fluxio-backend/pkg/model"
 	"fluxio-backend/pkg/repository/pgsql"
 	"fluxio-backend/pkg/repository/pgsql/tables"
+	"fluxio-backend/pkg/storage"
 	"fluxio-backend/pkg/utils"
 	"net/url"
 	"strings"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type VideoRepository struct {
 	db *pgsql.PgSQL
 	l  schema.Logger
 
-	s3Client            *s3.S3
+	store               storage.ObjectStore
 	rawVidBketName      string
 	pubVidBketName      string
 	thumbnailBucketName string
+	stagingBucketName   string
+	publicBaseURL       *url.URL
+	thumbnailBaseURL    *url.URL
 }
 
 type VideoRepositoryConfig struct {
 	S3RawVideoBucketName    string
 	S3PublicVideoBucketName string
 	S3ThumbnailBucketName   string
-	S3Region                string
-	S3AccessKey             string
-	S3SecretKey             string
-	S3Endpoint              string
+	S3StagingBucketName     string
+	PublicBaseURL           string
 }
 
-func NewVideoRepository(db *pgsql.PgSQL, cfg VideoRepositoryConfig, logger schema.Logger) *VideoRepository {
+func NewVideoRepository(db *pgsql.PgSQL, store storage.ObjectStore, cfg VideoRepositoryConfig, logger schema.Logger) *VideoRepository {
+	publicBaseURL, _ := url.Parse(cfg.PublicBaseURL)
 
-	url, _ := url.Parse(cfg.S3Endpoint)
-
-	awsConfig := &aws.Config{
-		Region:      aws.String(cfg.S3Region),
-		Credentials: credentials.NewStaticCredentials(cfg.S3AccessKey, cfg.S3SecretKey, ""),
-	}
-
-	if !strings.EqualFold(url.Host, "") {
-
-		awsConfig.Endpoint = aws.String(url.String())
-		awsConfig.DisableSSL = aws.Bool(strings.EqualFold(url.Scheme, "http"))
-		awsConfig.S3ForcePathStyle = aws.Bool(true)
+	// Serve the public bucket from the storage itself when no explicit base URL (e.g. a CDN) is configured.
+	if strings.EqualFold(publicBaseURL.Host, "") {
+		publicBaseURL = store.BucketURL(cfg.S3PublicVideoBucketName)
 	}
 
-	awsSession, _ := session.NewSession(awsConfig)
-
-	s3Client := s3.New(awsSession)
-
 	return &VideoRepository{
 		db:                  db,
-		s3Client:            s3Client,
+		store:               store,
 		rawVidBketName:      cfg.S3RawVideoBucketName,
 		pubVidBketName:      cfg.S3PublicVideoBucketName,
 		thumbnailBucketName: cfg.S3ThumbnailBucketName,
+		stagingBucketName:   cfg.S3StagingBucketName,
+		publicBaseURL:       publicBaseURL,
+		thumbnailBaseURL:    store.BucketURL(cfg.S3ThumbnailBucketName),
 		l:                   logger,
 	}
 }
@@ -159,6 +150,51 @@ func (r *VideoRepository) IncrementVideoRetryCount(ctx context.Context, videoID
 	return
 }
 
+// Records that the uploaded file of the video was removed from the raw bucket.
+func (r *VideoRepository) MarkRawFileDeleted(ctx context.Context, videoID model.VideoID, deletedAt time.Time) (err error) {
+	logger := r.l.With("video_id", videoID.String())
+
+	tx := r.db.DB.WithContext(ctx).Model(&tables.Video{}).
+		Where("id = ?", videoID).
+		Update("raw_file_deleted_at", deletedAt)
+
+	err = tx.Error
+
+	if err != nil {
+		logger.Error("Failed to mark the raw file of the video as deleted", err)
+		return
+	}
+
+	if tx.RowsAffected == 0 {
+		err = fluxerrors.ErrVideoNotFound
+		return
+	}
+	return
+}
+
+func (r *VideoRepository) IncrementThumbnailRetryCount(ctx context.Context, videoID model.VideoID) (err error) {
+	logger := r.l.With("video_id", videoID.String())
+
+	tx := r.db.DB.WithContext(ctx).Model(&tables.Video{}).
+		Where("id = ?", videoID).
+		Update("thumbnail_retry_count", gorm.Expr("thumbnail_retry_count + 1"))
+
+	err = tx.Error
+
+	if err != nil {
+		logger.Error("Failed to increment the thumbnail retry count for a video", err)
+		return
+	}
+
+	if tx.RowsAffected == 0 {
+		logger.Debug("No rows found to increment the thumbnail retry count.")
+		err = fluxerrors.ErrVideoNotFound
+		return
+	}
+
+	return
+}
+
 // UpdateMeta updates video metadata with the provided parameters
 func (r *VideoRepository) UpdateMeta(ctx context.Context, id model.VideoID, status model.VideoStatus, params model.Video) (err error) {
 	logger := r.l.With("video_id", id.String())
@@ -181,8 +217,7 @@ func (r *VideoRepository) UpdateMeta(ctx context.Context, id model.VideoID, stat
 	updateData := r.buildUpdateVideoDataMap(params)
 
 	// Execute the update
-	tx := r.db.DB.WithContext(ctx).Model(&tables.Video{}).Where("id = ?", uuid).Updates(updateData)
-	err = tx.Error
+	rowsAffected, err := r.updateVideoWithEvent(ctx, uuid, "", updateData)
 
 	if err != nil {
 		logger.Error("Failed to update meta for a video", err)
@@ -190,7 +225,7 @@ func (r *VideoRepository) UpdateMeta(ctx context.Context, id model.VideoID, stat
 		return
 	}
 
-	if tx.RowsAffected == 0 {
+	if rowsAffected == 0 {
 		logger.Debug("No rows found to when trying to update the meta.")
 		err = fluxerrors.ErrVideoNotFound
 		return
@@ -237,6 +272,283 @@ func (r *VideoRepository) UpdateInternalStatus(ctx context.Context, id model.Vid
 	return nil
 }
 
+// Stores the raw probe output of the uploaded file.
+func (r *VideoRepository) SaveProbeData(ctx context.Context, id model.VideoID, probeData string) (err error) {
+	logger := r.l.With("video_id", id.String())
+
+	uuid, err := uuid.Parse(id.String())
+	if err != nil {
+		return fluxerrors.ErrInvalidVideoID
+	}
+
+	tx := r.db.DB.WithContext(ctx).Model(&tables.Video{}).Where("id = ?", uuid).Update("probe_data", probeData)
+	err = tx.Error
+
+	if err != nil {
+		logger.Error("Failed to save the probe data for a video", err)
+		err = fluxerrors.ErrVideoMetaUpdateFailed
+		return
+	}
+
+	if tx.RowsAffected == 0 {
+		logger.Debug("No record matched when saving the probe data.")
+		err = fluxerrors.ErrVideoNotFound
+		return
+	}
+
+	return nil
+}
+
+// Records that the current processing stage of a video succeeded and the next stage starts with a clean slate.
+func (r *VideoRepository) AdvanceProcessingStage(ctx context.Context, id model.VideoID, internalStatus model.VideoInternalStatus) (err error) {
+	if !internalStatus.IsAcceptable() {
+		return fluxerrors.ErrInvalidVideoStatus
+	}
+
+	updateData := map[string]interface{}{
+		"internal_status": internalStatus.String(),
+		"stage_attempts":  0,
+		"last_error":      "",
+	}
+
+	clearProcessingProgress(updateData)
+
+	return r.updateProcessingState(ctx, id, updateData)
+}
+
+// Records a failed attempt of the current processing stage along with the status the video moves to.
+// An empty internal status keeps the current one.
+func (r *VideoRepository) RecordProcessingFailure(ctx context.Context, id model.VideoID, status model.VideoStatus, internalStatus model.VideoInternalStatus, stageAttempts uint8, lastError string) (err error) {
+	if !status.IsAcceptable() {
+		return fluxerrors.ErrInvalidVideoStatus
+	}
+
+	updateData := map[string]interface{}{
+		"status":         status.String(),
+		"stage_attempts": stageAttempts,
+		"last_error":     lastError,
+	}
+
+	if !strings.EqualFold(internalStatus.String(), "") {
+		updateData["internal_status"] = internalStatus.String()
+	}
+
+	clearProcessingProgress(updateData)
+
+	return r.updateProcessingState(ctx, id, updateData)
+}
+
+// Stores the progress of the running ffmpeg step of a video. The progress is only kept while the video is
+// processed since every stage outcome clears it.
+func (r *VideoRepository) UpdateProcessingProgress(ctx context.Context, id model.VideoID, progress model.VideoProgress) (err error) {
+	logger := r.l.With("video_id", id.String())
+
+	uuid, err := uuid.Parse(id.String())
+	if err != nil {
+		return fluxerrors.ErrInvalidVideoID
+	}
+
+	updatedAt := time.Now()
+	if progress.UpdatedAt != nil {
+		updatedAt = *progress.UpdatedAt
+	}
+
+	tx := r.db.DB.WithContext(ctx).Model(&tables.Video{}).
+		Where("id = ? AND status = ?", uuid, model.VideoStatusProcessing.String()).
+		Updates(map[string]interface{}{
+			"progress_stage":       progress.Stage,
+			"progress_step":        progress.Step,
+			"progress_percent":     progress.Percent,
+			"progress_eta_seconds": progress.ETASeconds,
+			"progress_updated_at":  updatedAt,
+		})
+
+	err = tx.Error
+
+	if err != nil {
+		logger.Error("Failed to update the processing progress of a video", err)
+		err = fluxerrors.ErrVideoMetaUpdateFailed
+		return
+	}
+
+	if tx.RowsAffected == 0 {
+		logger.Debug("No processing video matched when updating the progress.")
+		err = fluxerrors.ErrVideoNotFound
+		return
+	}
+
+	return nil
+}
+
+// Adds the columns which reset the stored ffmpeg progress to the update.
+func clearProcessingProgress(updateData map[string]interface{}) {
+	updateData["progress_stage"] = ""
+	updateData["progress_step"] = ""
+	updateData["progress_percent"] = 0
+	updateData["progress_eta_seconds"] = nil
+	updateData["progress_updated_at"] = nil
+}
+
+// Returns the videos in one of the statuses which were last updated before the given time, oldest first.
+// When a video is given the listing continues after it so that the callers can page through the videos.
+func (r *VideoRepository) ListVideosUpdatedBefore(ctx context.Context, statuses []model.VideoStatus, updatedBefore time.Time, after *model.Video, limit int) (videos []model.Video, err error) {
+	rawStatuses := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		rawStatuses = append(rawStatuses, status.String())
+	}
+
+	rows := []tables.Video{}
+
+	tx := r.db.DB.WithContext(ctx).Where("status IN ? AND updated_at < ?", rawStatuses, updatedBefore)
+
+	if after != nil && after.UpdatedAt != nil {
+		afterID, parseErr := uuid.Parse(after.ID.String())
+		if parseErr != nil {
+			err = fluxerrors.ErrInvalidVideoID
+			return
+		}
+
+		// The id breaks the ties between the videos updated at the same time.
+		tx = tx.Where("(updated_at, id) > (?, ?)", *after.UpdatedAt, afterID)
+	}
+
+	tx = tx.Order("updated_at, id").
+		Limit(limit).
+		Find(&rows)
+
+	if tx.Error != nil {
+		r.l.Error("Failed to list the videos by status", tx.Error)
+		err = tx.Error
+		return
+	}
+
+	videos = make([]model.Video, 0, len(rows))
+	for idx := range rows {
+		videos = append(videos, r.toVideoModel(&rows[idx]))
+	}
+
+	return
+}
+
+// Moves a video to a new status only while it is still in the expected status. Returns false when the
+// video has moved on in the meantime.
+func (r *VideoRepository) TransitionStatus(ctx context.Context, id model.VideoID, from model.VideoStatus, to model.VideoStatus, lastError string) (transitioned bool, err error) {
+	logger := r.l.With("video_id", id.String())
+
+	uuid, err := uuid.Parse(id.String())
+	if err != nil {
+		err = fluxerrors.ErrInvalidVideoID
+		return
+	}
+
+	if !to.IsAcceptable() {
+		err = fluxerrors.ErrInvalidVideoStatus
+		return
+	}
+
+	rowsAffected, err := r.updateVideoWithEvent(ctx, uuid, from, map[string]interface{}{
+		"status":     to.String(),
+		"last_error": lastError,
+	})
+
+	if err != nil {
+		logger.Error("Failed to transition the video status", err)
+		err = fluxerrors.ErrVideoMetaUpdateFailed
+		return
+	}
+
+	transitioned = rowsAffected > 0
+	return
+}
+
+// Updates the public status of a video without touching the rest of the meta.
+func (r *VideoRepository) UpdateStatus(ctx context.Context, id model.VideoID, status model.VideoStatus) (err error) {
+	if !status.IsAcceptable() {
+		return fluxerrors.ErrInvalidVideoStatus
+	}
+
+	return r.updateProcessingState(ctx, id, map[string]interface{}{
+		"status": status.String(),
+	})
+}
+
+func (r *VideoRepository) updateProcessingState(ctx context.Context, id model.VideoID, updateData map[string]interface{}) (err error) {
+	logger := r.l.With("video_id", id.String())
+
+	uuid, err := uuid.Parse(id.String())
+	if err != nil {
+		return fluxerrors.ErrInvalidVideoID
+	}
+
+	rowsAffected, err := r.updateVideoWithEvent(ctx, uuid, "", updateData)
+
+	if err != nil {
+		logger.Error("Failed to update the processing state of a video", err)
+		err = fluxerrors.ErrVideoMetaUpdateFailed
+		return
+	}
+
+	if rowsAffected == 0 {
+		logger.Debug("No record matched when updating the processing state.")
+		err = fluxerrors.ErrVideoNotFound
+		return
+	}
+
+	return nil
+}
+
+// Updates the video while it is in the expected status, or in any status when none is given. A status change
+// is written into the webhook outbox and sent to the event watchers in the same transaction so that no
+// lifecycle event is lost.
+func (r *VideoRepository) updateVideoWithEvent(ctx context.Context, id uuid.UUID, from model.VideoStatus, updateData map[string]interface{}) (rowsAffected int64, err error) {
+	err = r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		before := tables.Video{}
+
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "user_id", "slug", "title", "status").
+			Where("id = ?", id)
+
+		if !strings.EqualFold(from.String(), "") {
+			query = query.Where("status = ?", from.String())
+		}
+
+		res := query.Limit(1).Find(&before)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return nil
+		}
+
+		res = tx.Model(&tables.Video{}).Where("id = ?", id).Updates(updateData)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		rowsAffected = res.RowsAffected
+
+		status, ok := updateData["status"].(string)
+		if !ok {
+			return nil
+		}
+
+		lastError, _ := updateData["last_error"].(string)
+		err := recordVideoLifecycleEvent(tx, before, model.VideoStatus(status), lastError)
+		if err != nil {
+			return err
+		}
+
+		return notifyVideoStatus(tx, before, model.VideoStatus(status), lastError)
+	})
+
+	if err != nil {
+		rowsAffected = 0
+	}
+
+	return
+}
+
 // buildUpdateDataMap is a private helper method that constructs the update data map
 // from the provided status and UpdateVideoMeta parameters
 func (r *VideoRepository) buildUpdateVideoDataMap(params model.Video) map[string]interface{} {
@@ -351,18 +663,7 @@ func (r *VideoRepository) GetVideoByID(ctx context.Context, id model.VideoID) (v
 		return
 	}
 
-	video = model.Video{
-		ID:         model.VideoID(data.ID.String()),
-		Title:      data.Title,
-		UserID:     data.UserID,
-		Status:     model.VideoStatus(data.Status),
-		Visibility: model.VideoVisibility(data.Visibility),
-		Slug:       data.Slug,
-		RetryCount: data.RetryCount,
-		CreatedAt:  &data.CreatedAt,
-		UpdatedAt:  &data.UpdatedAt,
-		IsFeatured: data.IsFeatured,
-	}
+	video = r.toVideoModel(data)
 
 	return
 }
@@ -382,17 +683,55 @@ func (r *VideoRepository) GetVideoBySlug(ctx context.Context, slug string) (vide
 		return
 	}
 
+	video = r.toVideoModel(data)
+
+	return
+}
+
+// Converts the video table row to the video model.
+func (r *VideoRepository) toVideoModel(data *tables.Video) (video model.Video) {
 	video = model.Video{
-		ID:         model.VideoID(data.ID.String()),
-		Title:      data.Title,
-		UserID:     data.UserID,
-		Status:     model.VideoStatus(data.Status),
-		Visibility: model.VideoVisibility(data.Visibility),
-		Slug:       data.Slug,
-		RetryCount: data.RetryCount,
-		CreatedAt:  &data.CreatedAt,
-		UpdatedAt:  &data.UpdatedAt,
-		IsFeatured: data.IsFeatured,
+		ID:                  model.VideoID(data.ID.String()),
+		Title:               data.Title,
+		Description:         data.Description,
+		ParentID:            data.ParentID,
+		Width:               data.Width,
+		Height:              data.Height,
+		UserID:              data.UserID,
+		Format:              data.Format,
+		Length:              data.Length,
+		AudioSampleRate:     data.AudioSampleRate,
+		AudioCodec:          data.AudioCodec,
+		Status:              model.VideoStatus(data.Status),
+		InternalStatus:      model.VideoInternalStatus(data.InternalStatus),
+		Visibility:          model.VideoVisibility(data.Visibility),
+		Slug:                data.Slug,
+		Size:                data.Size,
+		Language:            data.Language,
+		StoragePath:         data.StoragePath,
+		ProbeData:           data.ProbeData,
+		StageAttempts:       data.StageAttempts,
+		LastError:           data.LastError,
+		RetryCount:          data.RetryCount,
+		ThumbnailRetryCount: data.ThumbnailRetryCount,
+		RawFileDeletedAt:    data.RawFileDeletedAt,
+		CreatedAt:           &data.CreatedAt,
+		UpdatedAt:           &data.UpdatedAt,
+		IsFeatured:          data.IsFeatured,
+	}
+
+	if data.DeletedAt.Valid {
+		video.DeletedAt = &data.DeletedAt.Time
+	}
+
+	if !strings.EqualFold(data.ProgressStage, "") {
+		video.Progress = &model.VideoProgress{
+			Stage:      data.ProgressStage,
+			Step:       data.ProgressStep,
+			Percent:    data.ProgressPercent,
+			ETASeconds: data.ProgressETASeconds,
+			UpdatedAt:  data.ProgressUpdatedAt,
+		}
 	}
 
 	return
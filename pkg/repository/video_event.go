@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fluxio-backend/pkg/repository/pgsql/tables"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+// Body of a video event notification. The video ID is not part of the event sent to the clients.
+type videoEventNotification struct {
+	VideoID model.VideoID    `json:"video_id"`
+	Event   model.VideoEvent `json:"event"`
+}
+
+// Sends the event to the listeners of every process.
+func (v *VideoRepository) PublishVideoEvent(ctx context.Context, event model.VideoEvent) (err error) {
+	payload, err := encodeVideoEvent(event)
+	if err != nil {
+		return
+	}
+
+	return v.db.DB.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", constants.VideoEventChannel, payload).Error
+}
+
+// Passes the events published by any process to the handler until the context is cancelled or the connection
+// fails. A connection is taken from the pool for as long as the listening goes on.
+func (v *VideoRepository) ListenVideoEvents(ctx context.Context, handle func(event model.VideoEvent)) (err error) {
+	sqlDB, err := v.db.DB.DB()
+	if err != nil {
+		return
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	rawErr := conn.Raw(func(driverConn any) error {
+		pgConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			err = fluxerrors.ErrVideoEventListenFailed
+			return driver.ErrBadConn
+		}
+
+		_, err = pgConn.Conn().Exec(ctx, "LISTEN "+constants.VideoEventChannel)
+		if err != nil {
+			return driver.ErrBadConn
+		}
+
+		for {
+			notification, waitErr := pgConn.Conn().WaitForNotification(ctx)
+			if waitErr != nil {
+				err = waitErr
+
+				// Discard the connection so that it does not go back to the pool while it still listens.
+				return driver.ErrBadConn
+			}
+
+			var body videoEventNotification
+			if json.Unmarshal([]byte(notification.Payload), &body) != nil {
+				v.l.Warn("Dropped a malformed video event notification")
+				continue
+			}
+
+			body.Event.VideoID = body.VideoID
+			handle(body.Event)
+		}
+	})
+
+	if err == nil {
+		err = rawErr
+	}
+
+	return
+}
+
+// Sends the status change along with the transaction so that the watchers learn about it once it is committed.
+func notifyVideoStatus(tx *gorm.DB, before tables.Video, status model.VideoStatus, lastError string) (err error) {
+	if before.Status == status.String() {
+		return
+	}
+
+	event := model.VideoEvent{
+		Type:      model.VideoEventStatus,
+		VideoID:   model.VideoID(before.ID.String()),
+		Slug:      before.Slug,
+		Status:    status,
+		Message:   lastError,
+		CreatedAt: time.Now(),
+	}
+
+	payload, err := encodeVideoEvent(event)
+	if err == fluxerrors.ErrVideoEventTooLarge {
+		// The error stays readable from the video.
+		event.Message = ""
+		payload, err = encodeVideoEvent(event)
+	}
+
+	if err != nil {
+		return
+	}
+
+	return tx.Exec("SELECT pg_notify(?, ?)", constants.VideoEventChannel, payload).Error
+}
+
+func encodeVideoEvent(event model.VideoEvent) (payload string, err error) {
+	raw, err := json.Marshal(videoEventNotification{
+		VideoID: event.VideoID,
+		Event:   event,
+	})
+	if err != nil {
+		return
+	}
+
+	if len(raw) > constants.MaxVideoEventPayloadSize {
+		err = fluxerrors.ErrVideoEventTooLarge
+		return
+	}
+
+	payload = string(raw)
+	return
+}
@@ -7,70 +7,127 @@ import (
 	"fluxio-backend/pkg/model"
 	"fluxio-backend/pkg/utils"
 	"fmt"
+	"io"
 	"net/url"
 	"strings"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/s3"
 )
 
 func (v *VideoRepository) GenerateUnProcessedVideoUploadURL(ctx context.Context, id model.VideoID, slug string, mimeType string) (url *url.URL, err error) {
 	logger := v.l.With("video_id", id.String())
-	path := v.generateVideoFileS3Path(slug)
-	// Remove the bucket name from the path to avoid double prefixing.
-	path = strings.TrimPrefix(path, fmt.Sprintf("%s/", v.rawVidBketName))
-
-	s3Request, _ := v.s3Client.PutObjectRequest(&s3.PutObjectInput{
-		Bucket:      aws.String(v.rawVidBketName),
-		Key:         aws.String(path),
-		ContentType: aws.String(mimeType),
-	})
-
-	rawURL, err := s3Request.Presign(constants.PreSignedVidUploadURLExpireTime)
 
+	url, err = v.store.PresignPut(ctx, v.rawVidBketName, v.GetUnProcessedVideoFilePath(slug), mimeType, constants.PreSignedVidUploadURLExpireTime)
 	if err != nil {
 		logger.Error("Failed to create a presigned URL for video upload", err)
 		err = fluxerrors.ErrVideoURLGenerationFailed
 		return
 	}
 
-	url, _ = url.Parse(rawURL)
-
 	return
 }
 
 func (v *VideoRepository) GetUnProcessedVideoDownloadURL(ctx context.Context, slug string) (url *url.URL, err error) {
 	logger := v.l.With("video_slug", slug)
-	path := v.generateVideoFileS3Path(slug)
-	// Remove the bucket name from the path to avoid double prefixing.
-	path = strings.TrimPrefix(path, fmt.Sprintf("%s/", v.rawVidBketName))
-
-	s3Request, _ := v.s3Client.GetObjectRequest(&s3.GetObjectInput{
-		Bucket: aws.String(v.rawVidBketName),
-		Key:    aws.String(path),
-	})
-
-	rawURL, err := s3Request.Presign(constants.PreSignedVidTempDownloadURLExpireTime)
 
+	url, err = v.store.PresignGet(ctx, v.rawVidBketName, v.GetUnProcessedVideoFilePath(slug), constants.PreSignedVidTempDownloadURLExpireTime)
 	if err != nil {
 		logger.Error("Failed to create a presigned URL for video download", err)
 		err = fluxerrors.ErrVideoURLGenerationFailed
 		return
 	}
 
-	url, _ = url.Parse(rawURL)
+	return
+}
+
+// Streams a video file into the raw bucket and returns the path it was stored at.
+func (v *VideoRepository) UploadUnProcessedVideoFile(ctx context.Context, slug string, mimeType string, body io.Reader) (path string, err error) {
+	logger := v.l.With("video_slug", slug)
+	path = v.GetUnProcessedVideoFilePath(slug)
+
+	err = v.store.Put(ctx, v.rawVidBketName, path, body, mimeType)
+	if err != nil {
+		logger.Error("Failed to upload the video file to the raw bucket", err)
+		path = ""
+		err = fluxerrors.ErrVideoFileStoreFailed
+		return
+	}
 
 	return
 }
 
+// Reports whether the uploaded file of a video is in the raw bucket.
+func (v *VideoRepository) UnProcessedVideoFileExists(ctx context.Context, slug string) (exists bool, err error) {
+	_, err = v.store.Head(ctx, v.rawVidBketName, v.GetUnProcessedVideoFilePath(slug))
+	if err != nil {
+		if err == fluxerrors.ErrObjectNotFound {
+			err = nil
+			return
+		}
+
+		v.l.With("video_slug", slug).Error("Failed to check the raw video file", err)
+		return
+	}
+
+	exists = true
+	return
+}
+
+// Uploads a processed video file (playlist, segment, etc) to the public bucket.
+func (v *VideoRepository) UploadPublicVideoFile(ctx context.Context, slug string, fileName string, contentType string, body io.Reader) (err error) {
+	logger := v.l.With("video_slug", slug).With("file_name", fileName)
+
+	if strings.EqualFold(strings.Trim(fileName, "/"), "") {
+		err = fluxerrors.ErrInvalidVideoFileName
+		return
+	}
+
+	err = v.store.Put(ctx, v.pubVidBketName, v.generatePublicVideoFileS3Path(slug, fileName), body, contentType)
+	if err != nil {
+		logger.Error("Failed to upload the processed video file", err)
+		err = fluxerrors.ErrVideoFileStoreFailed
+		return
+	}
+
+	return
+}
+
+// Returns the path of a processed video file inside the public bucket.
+func (v *VideoRepository) GetPublicVideoFilePath(slug string, fileName string) string {
+	return v.generatePublicVideoFileS3Path(slug, fileName)
+}
+
+// Returns the URL the players use to fetch a file from the public bucket.
+func (v *VideoRepository) GetPublicVideoFileURL(path string) string {
+	return v.publicBaseURL.JoinPath(path).String()
+}
+
 func (v *VideoRepository) generateVideoFileS3Path(slug string) string {
 	return strings.TrimRight(slug, "/")
 }
 
-func (v *VideoRepository) generateThumbnailFileS3Path(id model.VideoID, timestamp uint64, extension string) string {
-	path := utils.CreateURLSafeThumbnailFileName(id.String(), fmt.Sprint(timestamp))
+func (v *VideoRepository) generatePublicVideoFileS3Path(slug string, fileName string) string {
+	return fmt.Sprintf("%s/%s", strings.Trim(slug, "/"), strings.TrimLeft(fileName, "/"))
+}
+
+// Returns the path of a thumbnail variant inside the thumbnail bucket.
+func (v *VideoRepository) GetThumbnailVariantFilePath(id model.VideoID, timestamp uint64, width uint16, format string) string {
+	path := utils.CreateURLSafeThumbnailFileName(id.String(), fmt.Sprintf("%d-%d", timestamp, width))
 
 	// Add file extension to the path.
-	path = fmt.Sprintf("%s.%s", path, extension)
+	path = fmt.Sprintf("%s.%s", path, format)
 	return strings.TrimSpace(path)
 }
+
+// Returns the path of a sprite file of a video inside the thumbnail bucket.
+func (v *VideoRepository) GetThumbnailSpriteFilePath(id model.VideoID, fileName string) string {
+	return fmt.Sprintf("%s/%s/%s", strings.Trim(id.String(), "/"), constants.SpriteDirectoryName, strings.TrimLeft(fileName, "/"))
+}
+
+// Returns the path of a preview clip file of a video inside the thumbnail bucket.
+func (v *VideoRepository) GetThumbnailPreviewFilePath(id model.VideoID, fileName string) string {
+	return fmt.Sprintf("%s/%s/%s", strings.Trim(id.String(), "/"), constants.PreviewDirectoryName, strings.TrimLeft(fileName, "/"))
+}
+
+// Returns the URL the players use to fetch a file from the thumbnail bucket.
+func (v *VideoRepository) GetThumbnailFileURL(path string) string {
+	return v.thumbnailBaseURL.JoinPath(path).String()
+}
@@ -0,0 +1,493 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fluxio-backend/pkg/repository/pgsql/tables"
+	"fluxio-backend/pkg/storage"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Returns the path of the uploaded file of a video inside the raw bucket.
+func (v *VideoRepository) GetUnProcessedVideoFilePath(slug string) string {
+	path := v.generateVideoFileS3Path(slug)
+	return strings.TrimPrefix(path, fmt.Sprintf("%s/", v.rawVidBketName))
+}
+
+// Starts a multipart upload of the video file in the raw bucket and returns the upload ID.
+func (v *VideoRepository) CreateMultipartUpload(ctx context.Context, slug string, mimeType string) (uploadID string, err error) {
+	logger := v.l.With("video_slug", slug)
+
+	uploadID, err = v.store.CreateMultipartUpload(ctx, v.rawVidBketName, v.GetUnProcessedVideoFilePath(slug), mimeType)
+	if err != nil {
+		logger.Error("Failed to create a multipart upload", err)
+		err = fluxerrors.ErrMultipartUploadFailed
+		return
+	}
+
+	return
+}
+
+// Generates a presigned URL to upload a single part of a multipart upload.
+func (v *VideoRepository) GenerateMultipartPartUploadURL(ctx context.Context, slug string, uploadID string, partNumber int64) (url *url.URL, err error) {
+	logger := v.l.With("video_slug", slug).With("part_number", partNumber)
+
+	if partNumber < 1 || partNumber > constants.MaxMultipartUploadParts {
+		err = fluxerrors.ErrInvalidUploadPartNumber
+		return
+	}
+
+	url, err = v.store.PresignUploadPart(ctx, v.rawVidBketName, v.GetUnProcessedVideoFilePath(slug), uploadID, partNumber, constants.PreSignedVidUploadURLExpireTime)
+	if err != nil {
+		logger.Error("Failed to create a presigned URL for the upload part", err)
+		err = fluxerrors.ErrVideoURLGenerationFailed
+		return
+	}
+
+	return
+}
+
+// Lists the parts of a multipart upload which reached the object storage, ordered by part number.
+func (v *VideoRepository) ListMultipartUploadParts(ctx context.Context, slug string, uploadID string) (parts []model.UploadedPart, err error) {
+	logger := v.l.With("video_slug", slug)
+
+	storedParts, err := v.store.ListParts(ctx, v.rawVidBketName, v.GetUnProcessedVideoFilePath(slug), uploadID)
+	if err != nil {
+		logger.Error("Failed to list the parts of the multipart upload", err)
+		err = fluxerrors.ErrMultipartUploadFailed
+		return
+	}
+
+	for _, part := range storedParts {
+		parts = append(parts, model.UploadedPart{
+			PartNumber: part.Number,
+			ETag:       part.ETag,
+			Size:       part.Size,
+		})
+	}
+
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].PartNumber < parts[j].PartNumber
+	})
+
+	return
+}
+
+// Assembles the uploaded parts into the video file.
+func (v *VideoRepository) CompleteMultipartUpload(ctx context.Context, slug string, uploadID string, parts []model.UploadedPart) (err error) {
+	logger := v.l.With("video_slug", slug)
+
+	completedParts := make([]storage.Part, 0, len(parts))
+	for _, part := range parts {
+		completedParts = append(completedParts, storage.Part{
+			Number: part.PartNumber,
+			ETag:   part.ETag,
+			Size:   part.Size,
+		})
+	}
+
+	err = v.store.CompleteMultipartUpload(ctx, v.rawVidBketName, v.GetUnProcessedVideoFilePath(slug), uploadID, completedParts)
+	if err != nil {
+		logger.Error("Failed to complete the multipart upload", err)
+		err = fluxerrors.ErrMultipartUploadFailed
+		return
+	}
+
+	return
+}
+
+// Aborts a multipart upload and frees the storage of the uploaded parts.
+func (v *VideoRepository) AbortMultipartUpload(ctx context.Context, slug string, uploadID string) (err error) {
+	logger := v.l.With("video_slug", slug)
+
+	err = v.store.AbortMultipartUpload(ctx, v.rawVidBketName, v.GetUnProcessedVideoFilePath(slug), uploadID)
+	if err != nil {
+		logger.Error("Failed to abort the multipart upload", err)
+		err = fluxerrors.ErrMultipartUploadFailed
+		return
+	}
+
+	return
+}
+
+// Stores a new active multipart upload of a video.
+func (v *VideoRepository) CreateVideoUpload(ctx context.Context, upload model.VideoUpload) (created model.VideoUpload, err error) {
+	logger := v.l.With("video_id", upload.VideoID.String())
+
+	parsedVidID, err := uuid.Parse(upload.VideoID.String())
+	if err != nil {
+		err = fluxerrors.ErrInvalidVideoID
+		return
+	}
+
+	row := tables.VideoUpload{
+		VideoID:     parsedVidID,
+		UploadID:    upload.UploadID,
+		StoragePath: upload.StoragePath,
+		State:       model.VideoUploadStateActive.String(),
+		PartSize:    upload.PartSize,
+		Protocol:    upload.Protocol.String(),
+		Length:      upload.Length,
+		ExpiresAt:   upload.ExpiresAt,
+	}
+
+	if strings.EqualFold(row.Protocol, "") {
+		row.Protocol = model.VideoUploadProtocolS3.String()
+	}
+
+	tx := v.db.DB.WithContext(ctx).Create(&row)
+	if tx.Error != nil {
+		if strings.Contains(tx.Error.Error(), "idx_video_uploads_active_video") {
+			err = fluxerrors.ErrVideoUploadAlreadyActive
+			return
+		}
+
+		logger.Error("Failed to create the video upload", tx.Error)
+		err = fluxerrors.ErrVideoUploadSaveFailed
+		return
+	}
+
+	created = v.toVideoUploadModel(&row)
+	return
+}
+
+// Returns the multipart upload of a video which is still in progress along with its recorded parts.
+func (v *VideoRepository) GetActiveVideoUpload(ctx context.Context, id model.VideoID) (upload model.VideoUpload, err error) {
+	parsedVidID, err := uuid.Parse(id.String())
+	if err != nil {
+		err = fluxerrors.ErrInvalidVideoID
+		return
+	}
+
+	row := tables.VideoUpload{}
+
+	tx := v.db.DB.WithContext(ctx).Preload("Parts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("part_number")
+	}).Where("video_id = ? AND state = ?", parsedVidID, model.VideoUploadStateActive.String()).First(&row)
+
+	if tx.Error != nil {
+		if tx.Error == gorm.ErrRecordNotFound {
+			err = fluxerrors.ErrVideoUploadNotFound
+			return
+		}
+
+		v.l.With("video_id", id.String()).Error("Failed to get the active video upload", tx.Error)
+		err = tx.Error
+		return
+	}
+
+	upload = v.toVideoUploadModel(&row)
+	return
+}
+
+// Records the parts which reached the object storage. Parts uploaded again replace the previous record.
+// Touching the upload also marks it as recently active.
+func (v *VideoRepository) SaveVideoUploadParts(ctx context.Context, id model.VideoUploadID, parts []model.UploadedPart) (err error) {
+	logger := v.l.With("video_upload_id", id.String())
+
+	parsedID, err := uuid.Parse(id.String())
+	if err != nil {
+		err = fluxerrors.ErrVideoUploadNotFound
+		return
+	}
+
+	err = v.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&tables.VideoUpload{}).Where("id = ?", parsedID).Update("updated_at", gorm.Expr("now()"))
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if len(parts) == 0 {
+			return nil
+		}
+
+		rows := make([]tables.VideoUploadPart, 0, len(parts))
+		for _, part := range parts {
+			rows = append(rows, tables.VideoUploadPart{
+				VideoUploadID: parsedID,
+				PartNumber:    part.PartNumber,
+				ETag:          part.ETag,
+				Size:          part.Size,
+			})
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "video_upload_id"}, {Name: "part_number"}},
+			DoUpdates: clause.AssignmentColumns([]string{"etag", "size", "updated_at"}),
+		}).Create(&rows).Error
+	})
+
+	if err != nil {
+		logger.Error("Failed to save the uploaded parts", err)
+		err = fluxerrors.ErrVideoUploadSaveFailed
+		return
+	}
+
+	return
+}
+
+// Uploads a part of a multipart upload from the server and returns its ETag.
+func (v *VideoRepository) UploadMultipartPart(ctx context.Context, slug string, uploadID string, partNumber int64, data []byte) (etag string, err error) {
+	logger := v.l.With("video_slug", slug).With("part_number", partNumber)
+
+	if partNumber < 1 || partNumber > constants.MaxMultipartUploadParts {
+		err = fluxerrors.ErrInvalidUploadPartNumber
+		return
+	}
+
+	etag, err = v.store.UploadPart(ctx, v.rawVidBketName, v.GetUnProcessedVideoFilePath(slug), uploadID, partNumber, bytes.NewReader(data))
+	if err != nil {
+		logger.Error("Failed to upload the part", err)
+		err = fluxerrors.ErrMultipartUploadFailed
+		return
+	}
+
+	return
+}
+
+// Stores the received bytes of a tus upload which do not fill a part yet. They are kept in the staging bucket
+// rather than with the upload record so that every chunk does not rewrite them in the database, and outside
+// of the raw bucket so that they do not raise storage events.
+func (v *VideoRepository) PutTusPendingData(ctx context.Context, id model.VideoUploadID, offset int64, data []byte) (err error) {
+	logger := v.l.With("video_upload_id", id.String()).With("offset", offset)
+
+	err = v.store.Put(ctx, v.stagingBucketName, v.generateTusPendingDataPath(id, offset), bytes.NewReader(data), "application/octet-stream")
+	if err != nil {
+		logger.Error("Failed to store the pending data of the tus upload", err)
+		err = fluxerrors.ErrVideoUploadSaveFailed
+		return
+	}
+
+	return
+}
+
+// Returns the bytes of a tus upload which were stored when the upload reached the offset.
+func (v *VideoRepository) GetTusPendingData(ctx context.Context, id model.VideoUploadID, offset int64) (data []byte, err error) {
+	logger := v.l.With("video_upload_id", id.String()).With("offset", offset)
+
+	body, _, err := v.store.Get(ctx, v.stagingBucketName, v.generateTusPendingDataPath(id, offset))
+	if err != nil {
+		if err == fluxerrors.ErrObjectNotFound {
+			err = fluxerrors.ErrUploadPendingDataMissing
+			return
+		}
+
+		logger.Error("Failed to get the pending data of the tus upload", err)
+		return
+	}
+
+	defer body.Close()
+
+	data, err = io.ReadAll(body)
+	if err != nil {
+		logger.Error("Failed to read the pending data of the tus upload", err)
+		return
+	}
+
+	return
+}
+
+// Deletes the bytes of a tus upload which were stored when the upload reached the offset.
+func (v *VideoRepository) DeleteTusPendingData(ctx context.Context, id model.VideoUploadID, offset int64) (err error) {
+	err = v.store.Delete(ctx, v.stagingBucketName, v.generateTusPendingDataPath(id, offset))
+	if err != nil && err != fluxerrors.ErrObjectNotFound {
+		v.l.With("video_upload_id", id.String()).With("offset", offset).Error("Failed to delete the pending data of the tus upload", err)
+		return
+	}
+
+	err = nil
+	return
+}
+
+// The offset the bytes end at is part of the path so that a writer which lost the race for the offset
+// cannot replace the bytes of the one which won it.
+func (v *VideoRepository) generateTusPendingDataPath(id model.VideoUploadID, offset int64) string {
+	return fmt.Sprintf("%s%s/%d", constants.TusPendingDataPrefix, id.String(), offset)
+}
+
+// Returns a multipart upload by its ID along with its recorded parts.
+func (v *VideoRepository) GetVideoUploadByID(ctx context.Context, id model.VideoUploadID) (upload model.VideoUpload, err error) {
+	parsedID, err := uuid.Parse(id.String())
+	if err != nil {
+		err = fluxerrors.ErrVideoUploadNotFound
+		return
+	}
+
+	row := tables.VideoUpload{}
+
+	tx := v.db.DB.WithContext(ctx).Preload("Parts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("part_number")
+	}).Where("id = ?", parsedID).First(&row)
+
+	if tx.Error != nil {
+		if tx.Error == gorm.ErrRecordNotFound {
+			err = fluxerrors.ErrVideoUploadNotFound
+			return
+		}
+
+		v.l.With("video_upload_id", id.String()).Error("Failed to get the video upload", tx.Error)
+		err = tx.Error
+		return
+	}
+
+	upload = v.toVideoUploadModel(&row)
+	return
+}
+
+// Stores the progress of an upload streamed through the API. The update only applies while the stored
+// offset still matches the expected one so that concurrent writers cannot corrupt the upload.
+func (v *VideoRepository) AdvanceVideoUploadOffset(ctx context.Context, id model.VideoUploadID, expectedOffset int64, upload model.VideoUpload, newParts []model.UploadedPart) (err error) {
+	logger := v.l.With("video_upload_id", id.String())
+
+	parsedID, err := uuid.Parse(id.String())
+	if err != nil {
+		err = fluxerrors.ErrVideoUploadNotFound
+		return
+	}
+
+	err = v.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&tables.VideoUpload{}).
+			Where("id = ? AND state = ? AND upload_offset = ?", parsedID, model.VideoUploadStateActive.String(), expectedOffset).
+			Updates(map[string]interface{}{
+				"upload_offset": upload.Offset,
+				"expires_at":    upload.ExpiresAt,
+			})
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return fluxerrors.ErrUploadOffsetMismatch
+		}
+
+		if len(newParts) == 0 {
+			return nil
+		}
+
+		rows := make([]tables.VideoUploadPart, 0, len(newParts))
+		for _, part := range newParts {
+			rows = append(rows, tables.VideoUploadPart{
+				VideoUploadID: parsedID,
+				PartNumber:    part.PartNumber,
+				ETag:          part.ETag,
+				Size:          part.Size,
+			})
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "video_upload_id"}, {Name: "part_number"}},
+			DoUpdates: clause.AssignmentColumns([]string{"etag", "size", "updated_at"}),
+		}).Create(&rows).Error
+	})
+
+	if err != nil {
+		if err == fluxerrors.ErrUploadOffsetMismatch {
+			return
+		}
+
+		logger.Error("Failed to save the upload progress", err)
+		err = fluxerrors.ErrVideoUploadSaveFailed
+		return
+	}
+
+	return
+}
+
+// Returns the active uploads which expired before the given time.
+func (v *VideoRepository) ListExpiredVideoUploads(ctx context.Context, expiredBefore time.Time, limit int) (uploads []model.VideoUpload, err error) {
+	rows := []tables.VideoUpload{}
+
+	tx := v.db.DB.WithContext(ctx).
+		Where("state = ? AND expires_at IS NOT NULL AND expires_at < ?", model.VideoUploadStateActive.String(), expiredBefore).
+		Order("expires_at").
+		Limit(limit).
+		Find(&rows)
+
+	if tx.Error != nil {
+		v.l.Error("Failed to list the expired video uploads", tx.Error)
+		err = tx.Error
+		return
+	}
+
+	uploads = make([]model.VideoUpload, 0, len(rows))
+	for idx := range rows {
+		uploads = append(uploads, v.toVideoUploadModel(&rows[idx]))
+	}
+
+	return
+}
+
+// Moves an active multipart upload to its final state.
+func (v *VideoRepository) UpdateVideoUploadState(ctx context.Context, id model.VideoUploadID, state model.VideoUploadState) (err error) {
+	logger := v.l.With("video_upload_id", id.String())
+
+	if !state.IsAcceptable() {
+		err = fluxerrors.ErrInvalidVideoStatus
+		return
+	}
+
+	parsedID, err := uuid.Parse(id.String())
+	if err != nil {
+		err = fluxerrors.ErrVideoUploadNotFound
+		return
+	}
+
+	tx := v.db.DB.WithContext(ctx).Model(&tables.VideoUpload{}).
+		Where("id = ? AND state = ?", parsedID, model.VideoUploadStateActive.String()).
+		Update("state", state.String())
+
+	if tx.Error != nil {
+		logger.Error("Failed to update the video upload state", tx.Error)
+		err = fluxerrors.ErrVideoUploadSaveFailed
+		return
+	}
+
+	if tx.RowsAffected == 0 {
+		err = fluxerrors.ErrVideoUploadNotFound
+		return
+	}
+
+	return
+}
+
+func (v *VideoRepository) toVideoUploadModel(row *tables.VideoUpload) (upload model.VideoUpload) {
+	upload = model.VideoUpload{
+		ID:          model.VideoUploadID(row.ID.String()),
+		VideoID:     model.VideoID(row.VideoID.String()),
+		UploadID:    row.UploadID,
+		StoragePath: row.StoragePath,
+		State:       model.VideoUploadState(row.State),
+		PartSize:    row.PartSize,
+		Protocol:    model.VideoUploadProtocol(row.Protocol),
+		Length:      row.Length,
+		Offset:      row.Offset,
+		ExpiresAt:   row.ExpiresAt,
+		MaxParts:    constants.MaxMultipartUploadParts,
+		Parts:       make([]model.UploadedPart, 0, len(row.Parts)),
+		CreatedAt:   &row.CreatedAt,
+		UpdatedAt:   &row.UpdatedAt,
+	}
+
+	for _, part := range row.Parts {
+		upload.Parts = append(upload.Parts, model.UploadedPart{
+			PartNumber: part.PartNumber,
+			ETag:       part.ETag,
+			Size:       part.Size,
+		})
+	}
+
+	return
+}
@@ -0,0 +1,409 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fluxio-backend/pkg/common/schema"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fluxio-backend/pkg/repository/pgsql"
+	"fluxio-backend/pkg/repository/pgsql/tables"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepository struct {
+	db *pgsql.PgSQL
+	l  schema.Logger
+}
+
+func NewWebhookRepository(db *pgsql.PgSQL, logger schema.Logger) *WebhookRepository {
+	return &WebhookRepository{
+		db: db,
+		l:  logger,
+	}
+}
+
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, subscription model.WebhookSubscription) (created model.WebhookSubscription, err error) {
+	userID, err := uuid.Parse(subscription.UserID.String())
+	if err != nil {
+		err = fluxerrors.ErrInvalidUserID
+		return
+	}
+
+	row := tables.WebhookSubscription{
+		UserID:     userID,
+		URL:        subscription.URL,
+		Secret:     subscription.Secret,
+		EventTypes: webhookEventTypesToStrings(subscription.EventTypes),
+	}
+
+	tx := r.db.DB.WithContext(ctx).Create(&row)
+	if tx.Error != nil {
+		r.l.With("user_id", subscription.UserID.String()).Error("Failed to create the webhook subscription", tx.Error)
+		err = tx.Error
+		return
+	}
+
+	created = toWebhookSubscriptionModel(&row)
+	created.Secret = row.Secret
+	return
+}
+
+func (r *WebhookRepository) CountSubscriptions(ctx context.Context, userID model.UserID) (count int64, err error) {
+	tx := r.db.DB.WithContext(ctx).Model(&tables.WebhookSubscription{}).Where("user_id = ?", userID.String()).Count(&count)
+	err = tx.Error
+	return
+}
+
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context, userID model.UserID) (subscriptions []model.WebhookSubscription, err error) {
+	rows := []tables.WebhookSubscription{}
+
+	tx := r.db.DB.WithContext(ctx).Where("user_id = ?", userID.String()).Order("created_at").Find(&rows)
+	if tx.Error != nil {
+		r.l.With("user_id", userID.String()).Error("Failed to list the webhook subscriptions", tx.Error)
+		err = tx.Error
+		return
+	}
+
+	subscriptions = make([]model.WebhookSubscription, 0, len(rows))
+	for idx := range rows {
+		subscriptions = append(subscriptions, toWebhookSubscriptionModel(&rows[idx]))
+	}
+
+	return
+}
+
+// Returns the subscription along with its secret. Deleted subscriptions are not found.
+func (r *WebhookRepository) GetSubscription(ctx context.Context, id model.WebhookSubscriptionID) (subscription model.WebhookSubscription, err error) {
+	subscriptionID, err := uuid.Parse(id.String())
+	if err != nil {
+		err = fluxerrors.ErrWebhookSubscriptionNotFound
+		return
+	}
+
+	row := tables.WebhookSubscription{}
+
+	tx := r.db.DB.WithContext(ctx).Where("id = ?", subscriptionID).Limit(1).Find(&row)
+	if tx.Error != nil {
+		err = tx.Error
+		return
+	}
+
+	if tx.RowsAffected == 0 {
+		err = fluxerrors.ErrWebhookSubscriptionNotFound
+		return
+	}
+
+	subscription = toWebhookSubscriptionModel(&row)
+	subscription.Secret = row.Secret
+	return
+}
+
+// Deletes a subscription of the user. Pending deliveries of the subscription are dropped by the dispatcher.
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id model.WebhookSubscriptionID, userID model.UserID) (err error) {
+	subscriptionID, err := uuid.Parse(id.String())
+	if err != nil {
+		err = fluxerrors.ErrWebhookSubscriptionNotFound
+		return
+	}
+
+	tx := r.db.DB.WithContext(ctx).Where("id = ? AND user_id = ?", subscriptionID, userID.String()).Delete(&tables.WebhookSubscription{})
+	if tx.Error != nil {
+		r.l.With("subscription_id", id.String()).Error("Failed to delete the webhook subscription", tx.Error)
+		err = tx.Error
+		return
+	}
+
+	if tx.RowsAffected == 0 {
+		err = fluxerrors.ErrWebhookSubscriptionNotFound
+		return
+	}
+
+	return
+}
+
+// Creates the deliveries of the outbox events which were not relayed yet for the subscriptions of the
+// event owner. The events are claimed with SKIP LOCKED so several workers can relay at the same time.
+func (r *WebhookRepository) RelayEvents(ctx context.Context, limit int) (deliveries []model.WebhookDelivery, err error) {
+	err = r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		events := []tables.WebhookEvent{}
+
+		res := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("relayed_at IS NULL").
+			Order("created_at").
+			Limit(limit).
+			Find(&events)
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if len(events) == 0 {
+			return nil
+		}
+
+		eventIDs := make([]uuid.UUID, 0, len(events))
+
+		for _, event := range events {
+			eventIDs = append(eventIDs, event.ID)
+
+			eventType, _ := json.Marshal([]string{event.Type})
+			subscriptions := []tables.WebhookSubscription{}
+
+			res = tx.Where("user_id = ? AND event_types @> ?::jsonb", event.UserID, string(eventType)).Find(&subscriptions)
+			if res.Error != nil {
+				return res.Error
+			}
+
+			for _, subscription := range subscriptions {
+				row := tables.WebhookDelivery{
+					SubscriptionID: subscription.ID,
+					EventID:        event.ID,
+					EventType:      event.Type,
+					UserID:         event.UserID,
+					URL:            subscription.URL,
+					Status:         model.WebhookDeliveryPending.String(),
+				}
+
+				res = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row)
+				if res.Error != nil {
+					return res.Error
+				}
+
+				if res.RowsAffected > 0 {
+					deliveries = append(deliveries, toWebhookDeliveryModel(&row))
+				}
+			}
+		}
+
+		return tx.Model(&tables.WebhookEvent{}).Where("id IN ?", eventIDs).Update("relayed_at", time.Now()).Error
+	})
+
+	if err != nil {
+		r.l.Error("Failed to relay the webhook events", err)
+		deliveries = nil
+		return
+	}
+
+	return
+}
+
+// Returns the pending deliveries which were never attempted although they were created before the given time.
+// Their job was most likely not queued because the relaying process stopped right after creating them.
+func (r *WebhookRepository) ListStrandedDeliveries(ctx context.Context, createdBefore time.Time, limit int) (deliveries []model.WebhookDelivery, err error) {
+	rows := []tables.WebhookDelivery{}
+
+	tx := r.db.DB.WithContext(ctx).
+		Where("status = ? AND attempts = 0 AND created_at < ?", model.WebhookDeliveryPending.String(), createdBefore).
+		Order("created_at").
+		Limit(limit).
+		Find(&rows)
+
+	if tx.Error != nil {
+		err = tx.Error
+		return
+	}
+
+	deliveries = make([]model.WebhookDelivery, 0, len(rows))
+	for idx := range rows {
+		deliveries = append(deliveries, toWebhookDeliveryModel(&rows[idx]))
+	}
+
+	return
+}
+
+// Returns the delivery along with the payload of its event.
+func (r *WebhookRepository) GetDelivery(ctx context.Context, id model.WebhookDeliveryID) (delivery model.WebhookDelivery, err error) {
+	deliveryID, err := uuid.Parse(id.String())
+	if err != nil {
+		err = fluxerrors.ErrWebhookDeliveryNotFound
+		return
+	}
+
+	row := tables.WebhookDelivery{}
+
+	tx := r.db.DB.WithContext(ctx).Where("id = ?", deliveryID).Limit(1).Find(&row)
+	if tx.Error != nil {
+		err = tx.Error
+		return
+	}
+
+	if tx.RowsAffected == 0 {
+		err = fluxerrors.ErrWebhookDeliveryNotFound
+		return
+	}
+
+	event := tables.WebhookEvent{}
+
+	tx = r.db.DB.WithContext(ctx).Where("id = ?", row.EventID).Limit(1).Find(&event)
+	if tx.Error != nil {
+		err = tx.Error
+		return
+	}
+
+	if tx.RowsAffected == 0 {
+		err = fluxerrors.ErrWebhookDeliveryNotFound
+		return
+	}
+
+	delivery = toWebhookDeliveryModel(&row)
+	delivery.Payload = json.RawMessage(event.Payload)
+	return
+}
+
+// Stores the outcome of a delivery attempt.
+func (r *WebhookRepository) RecordDeliveryAttempt(ctx context.Context, delivery model.WebhookDelivery) (err error) {
+	deliveryID, err := uuid.Parse(delivery.ID.String())
+	if err != nil {
+		err = fluxerrors.ErrWebhookDeliveryNotFound
+		return
+	}
+
+	tx := r.db.DB.WithContext(ctx).Model(&tables.WebhookDelivery{}).Where("id = ?", deliveryID).Updates(map[string]interface{}{
+		"status":          delivery.Status.String(),
+		"attempts":        delivery.Attempts,
+		"response_status": delivery.ResponseStatus,
+		"last_error":      delivery.LastError,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"delivered_at":    delivery.DeliveredAt,
+	})
+
+	if tx.Error != nil {
+		r.l.With("delivery_id", delivery.ID.String()).Error("Failed to record the webhook delivery attempt", tx.Error)
+		err = tx.Error
+		return
+	}
+
+	if tx.RowsAffected == 0 {
+		err = fluxerrors.ErrWebhookDeliveryNotFound
+		return
+	}
+
+	return
+}
+
+// Returns the deliveries of a subscription of the user, newest first.
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, subscriptionID model.WebhookSubscriptionID, userID model.UserID, limit int, offset int) (deliveries []model.WebhookDelivery, err error) {
+	id, err := uuid.Parse(subscriptionID.String())
+	if err != nil {
+		err = fluxerrors.ErrWebhookSubscriptionNotFound
+		return
+	}
+
+	rows := []tables.WebhookDelivery{}
+
+	tx := r.db.DB.WithContext(ctx).
+		Where("subscription_id = ? AND user_id = ?", id, userID.String()).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&rows)
+
+	if tx.Error != nil {
+		r.l.With("subscription_id", subscriptionID.String()).Error("Failed to list the webhook deliveries", tx.Error)
+		err = tx.Error
+		return
+	}
+
+	deliveries = make([]model.WebhookDelivery, 0, len(rows))
+	for idx := range rows {
+		deliveries = append(deliveries, toWebhookDeliveryModel(&rows[idx]))
+	}
+
+	return
+}
+
+// Writes the lifecycle event of a video into the outbox when the status change is one the subscriptions are
+// notified about. Has to run in the transaction of the status change and with the video row as it was before.
+func recordVideoLifecycleEvent(tx *gorm.DB, before tables.Video, status model.VideoStatus, lastError string) (err error) {
+	if before.Status == status.String() {
+		return
+	}
+
+	var eventType model.WebhookEventType
+
+	switch status {
+	case model.VideoStatusCompleted:
+		eventType = model.WebhookEventVideoCompleted
+	case model.VideoStatusFailed:
+		eventType = model.WebhookEventVideoFailed
+	default:
+		return
+	}
+
+	event := tables.WebhookEvent{
+		ID:      uuid.New(),
+		UserID:  before.UserID,
+		VideoID: before.ID,
+		Type:    eventType.String(),
+	}
+
+	payload, err := json.Marshal(model.WebhookPayload{
+		ID:        model.WebhookEventID(event.ID.String()),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data: model.WebhookPayloadData{
+			Video: model.WebhookVideo{
+				ID:     model.VideoID(before.ID.String()),
+				Slug:   before.Slug,
+				Title:  before.Title,
+				Status: status,
+				Error:  lastError,
+			},
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	event.Payload = string(payload)
+
+	return tx.Create(&event).Error
+}
+
+func webhookEventTypesToStrings(eventTypes []model.WebhookEventType) (values []string) {
+	values = make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		values = append(values, eventType.String())
+	}
+	return
+}
+
+func toWebhookSubscriptionModel(row *tables.WebhookSubscription) (subscription model.WebhookSubscription) {
+	eventTypes := make([]model.WebhookEventType, 0, len(row.EventTypes))
+	for _, eventType := range row.EventTypes {
+		eventTypes = append(eventTypes, model.WebhookEventType(eventType))
+	}
+
+	return model.WebhookSubscription{
+		ID:         model.WebhookSubscriptionID(row.ID.String()),
+		UserID:     model.UserID(row.UserID.String()),
+		URL:        row.URL,
+		EventTypes: eventTypes,
+		CreatedAt:  &row.CreatedAt,
+		UpdatedAt:  &row.UpdatedAt,
+	}
+}
+
+func toWebhookDeliveryModel(row *tables.WebhookDelivery) (delivery model.WebhookDelivery) {
+	return model.WebhookDelivery{
+		ID:             model.WebhookDeliveryID(row.ID.String()),
+		SubscriptionID: model.WebhookSubscriptionID(row.SubscriptionID.String()),
+		EventID:        model.WebhookEventID(row.EventID.String()),
+		EventType:      model.WebhookEventType(row.EventType),
+		UserID:         model.UserID(row.UserID.String()),
+		URL:            row.URL,
+		Status:         model.WebhookDeliveryStatus(row.Status),
+		Attempts:       row.Attempts,
+		ResponseStatus: row.ResponseStatus,
+		LastError:      row.LastError,
+		NextAttemptAt:  row.NextAttemptAt,
+		DeliveredAt:    row.DeliveredAt,
+		CreatedAt:      &row.CreatedAt,
+		UpdatedAt:      &row.UpdatedAt,
+	}
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"fluxio-backend/pkg/common/schema"
+	"fluxio-backend/pkg/config"
+	"fluxio-backend/pkg/constants"
+	"fluxio-backend/pkg/eventsource"
+	"fluxio-backend/pkg/logger"
+	"fluxio-backend/pkg/model"
+	"fluxio-backend/pkg/pubsub"
+	"fluxio-backend/pkg/repository"
+	"fluxio-backend/pkg/repository/pgsql"
+	"fluxio-backend/pkg/service"
+	"fluxio-backend/pkg/storage"
+	"fluxio-backend/pkg/worker"
+	"os"
+	"sync"
+	"time"
+)
+
+// Dependencies shared by the API server and the standalone worker.
+type app struct {
+	cfg     *config.Config
+	logr    schema.Logger
+	repos   appRepositories
+	jobSvc  *service.JobService
+	vidSvc  *service.VideoService
+	hookSvc *service.WebhookService
+	store   storage.ObjectStore
+
+	storageEventHandler *eventsource.Handler
+	eventSource         eventsource.Source // Nil when the storage delivers the events to the webhook
+}
+
+type appRepositories struct {
+	user    *repository.UserRepository
+	job     *repository.JobRepository
+	video   *repository.VideoRepository
+	webhook *repository.WebhookRepository
+}
+
+// Loads the config, connects to the database and builds the repositories and services common to every process.
+// The process exits when any of them cannot be initialized.
+func bootstrap() *app {
+	logr := logger.NewDefaultLogger()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logr.Error("Failed to load the config.", err)
+		os.Exit(1)
+	}
+
+	db, err := pgsql.NewPgSQL(pgsql.PgSQLConfig{
+		URL: cfg.Database.GetDatabaseURL(),
+	})
+	if err != nil {
+		logr.Error("Error when initialization of database.", err)
+		os.Exit(1)
+	}
+
+	// Repositories
+	userRepo := repository.NewUserRepository(db, logr)
+	jobRepo := repository.NewJobRepository(db, logr)
+	webhookRepo := repository.NewWebhookRepository(db, logr)
+
+	store, err := storage.New(storage.Config{
+		Driver: cfg.Storage.Driver,
+		S3: storage.S3Config{
+			Region:    cfg.VideoCfg.S3Region,
+			AccessKey: cfg.VideoCfg.S3AccessKey,
+			SecretKey: cfg.VideoCfg.S3SecretKey,
+			Endpoint:  cfg.VideoCfg.S3Endpoint,
+		},
+		Local: storage.LocalConfig{
+			Root:          cfg.Storage.LocalRoot,
+			BaseURL:       cfg.Storage.LocalBaseURL,
+			SigningSecret: cfg.Storage.LocalSigningSecret,
+			PublicBuckets: []string{cfg.VideoCfg.S3PublicVideoBucketName, cfg.VideoCfg.S3ThumbnailBucketName},
+		},
+	})
+	if err != nil {
+		logr.Error("Failed to initialize the object storage.", err)
+		os.Exit(1)
+	}
+
+	videoRepo := repository.NewVideoRepository(db, store, repository.VideoRepositoryConfig{
+		S3RawVideoBucketName:    cfg.VideoCfg.S3RawVideoBucketName,
+		S3PublicVideoBucketName: cfg.VideoCfg.S3PublicVideoBucketName,
+		S3ThumbnailBucketName:   cfg.VideoCfg.S3ThumbnailBucketName,
+		S3StagingBucketName:     cfg.VideoCfg.S3StagingBucketName,
+		PublicBaseURL:           cfg.VideoCfg.PublicBaseURL,
+	},
+		logr)
+
+	// Services
+	jobService := service.NewJobService(jobRepo, logr)
+	videoEvents := pubsub.NewBroker[model.VideoEvent](constants.VideoEventBufferSize, logr)
+	thumbnailProfiles, err := service.ParseThumbnailProfiles(cfg.VideoCfg.ThumbnailWidths, cfg.VideoCfg.ThumbnailFormats)
+	if err != nil {
+		logr.Error("Invalid thumbnail profiles.", err)
+		os.Exit(1)
+	}
+
+	videoService := service.NewVideoService(videoRepo, jobService, videoEvents, service.VideoServiceConfig{
+		ThumbnailProfiles: thumbnailProfiles,
+	}, logr)
+	webhookService := service.NewWebhookService(webhookRepo, jobService, logr)
+
+	// Storage events of the raw bucket reach the backend through the webhook or a polled source.
+	storageEventHandler := eventsource.NewHandler(cfg.VideoCfg.S3RawVideoBucketName, videoService, logr)
+
+	eventSource, err := eventsource.New(eventsource.Config{
+		Source:      cfg.Storage.EventSource,
+		Store:       store,
+		Bucket:      cfg.VideoCfg.S3RawVideoBucketName,
+		Checkpoints: videoRepo,
+	})
+	if err != nil {
+		logr.Error("Failed to initialize the storage event source.", err)
+		os.Exit(1)
+	}
+
+	return &app{
+		cfg:  cfg,
+		logr: logr,
+		repos: appRepositories{
+			user:    userRepo,
+			job:     jobRepo,
+			video:   videoRepo,
+			webhook: webhookRepo,
+		},
+		jobSvc:  jobService,
+		vidSvc:  videoService,
+		hookSvc: webhookService,
+		store:   store,
+
+		storageEventHandler: storageEventHandler,
+		eventSource:         eventSource,
+	}
+}
+
+// Runs the job worker and the maintenance scheduler. Blocks until the context is cancelled and the
+// running jobs have been handed back.
+func (a *app) runBackgroundProcessing(ctx context.Context) {
+	processingWorker := worker.NewWorker(worker.WorkerConfig{
+		Concurrency:   a.cfg.Worker.Concurrency,
+		PollInterval:  constants.JobPollInterval,
+		LeaseDuration: constants.JobLeaseDuration,
+		RetryDelay:    constants.JobRetryDelay,
+	}, a.jobSvc, a.logr)
+
+	processingWorker.RegisterHandler(model.JobTypeVideoProcessing, a.vidSvc.HandleVideoProcessingJob)
+	processingWorker.RegisterHandler(model.JobTypeThumbnailRegeneration, a.vidSvc.HandleThumbnailRegenerationJob)
+	processingWorker.RegisterHandler(model.JobTypeVideoImport, a.vidSvc.HandleVideoImportJob)
+	processingWorker.RegisterHandler(model.JobTypeWebhookDelivery, a.hookSvc.HandleWebhookDeliveryJob)
+
+	scheduler := worker.NewScheduler(a.logr)
+
+	sweepCfg := service.StalledVideoSweepConfig{
+		ProcessingTimeout: time.Duration(a.cfg.Worker.ProcessingTimeoutMinutes) * time.Minute,
+	}
+	scheduler.Every("stalled_video_sweep", time.Duration(a.cfg.Worker.SweepIntervalSeconds)*time.Second, func(ctx context.Context) error {
+		return a.vidSvc.SweepStalledVideos(ctx, sweepCfg)
+	})
+	scheduler.Every("webhook_outbox_relay", constants.WebhookRelayInterval, a.hookSvc.RelayWebhookEvents)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scheduler.Start(ctx)
+	}()
+
+	if a.eventSource != nil {
+		consumer := eventsource.NewConsumer(a.eventSource, a.storageEventHandler, time.Duration(a.cfg.Storage.EventPollSeconds)*time.Second, a.logr)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			consumer.Start(ctx)
+		}()
+	}
+
+	processingWorker.Start(ctx)
+	wg.Wait()
+}
@@ -1,49 +1,23 @@
 package server
 
 import (
-	"fluxio-backend/pkg/config"
-	"fluxio-backend/pkg/logger"
-	"fluxio-backend/pkg/repository"
-	"fluxio-backend/pkg/repository/pgsql"
+	"context"
 	"fluxio-backend/pkg/service"
+	"fluxio-backend/pkg/storage"
 	"fluxio-backend/pkg/transport/http"
 	"fluxio-backend/pkg/transport/http/controller"
 	"fluxio-backend/pkg/transport/http/middleware"
 	"fluxio-backend/pkg/transport/http/routes"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 func NewServer() {
-	logr := logger.NewDefaultLogger()
-
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		logr.Error("Failed to load the config.", err)
-		os.Exit(1)
-	}
-
-	db, err := pgsql.NewPgSQL(pgsql.PgSQLConfig{
-		URL: cfg.Database.GetDatabaseURL(),
-	})
-	if err != nil {
-		logr.Error("Error when initialization of database.", err)
-		os.Exit(1)
-	}
-
-	// Repositories
-	userRepo := repository.NewUserRepository(db, logr)
-
-	videoRepo := repository.NewVideoRepository(db, repository.VideoRepositoryConfig{
-		S3RawVideoBucketName:    cfg.VideoCfg.S3RawVideoBucketName,
-		S3PublicVideoBucketName: cfg.VideoCfg.S3PublicVideoBucketName,
-		S3ThumbnailBucketName:   cfg.VideoCfg.S3ThumbnailBucketName,
-		S3Region:                cfg.VideoCfg.S3Region,
-		S3AccessKey:             cfg.VideoCfg.S3AccessKey,
-		S3SecretKey:             cfg.VideoCfg.S3SecretKey,
-		S3Endpoint:              cfg.VideoCfg.S3Endpoint,
-	},
-		logr)
+	a := bootstrap()
+	cfg := a.cfg
+	logr := a.logr
 
 	// Services
 	if cfg.JWT.Secret == "" {
@@ -52,41 +26,83 @@ func NewServer() {
 	}
 
 	jwtService := service.NewJWTService(cfg.JWT.Secret, logr)
-	userService := service.NewUserService(userRepo, jwtService, logr)
-	videoService := service.NewVideoService(videoRepo, logr)
+	userService := service.NewUserService(a.repos.user, jwtService, logr)
+	videoService := a.vidSvc
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The video events of the worker processes reach the event streams through the database.
+	go videoService.RelayVideoEvents(ctx)
+
+	// Run the video processing worker alongside the HTTP server unless dedicated workers are deployed.
+	var workerDone chan struct{}
+	if cfg.Worker.Embedded {
+		workerDone = make(chan struct{})
+		go func() {
+			defer close(workerDone)
+			a.runBackgroundProcessing(ctx)
+		}()
+	} else {
+		logr.Info("Video processing is left to the worker processes. Set FLUXIO_WORKER_EMBEDDED=true to run it in the server")
+	}
 
 	// Middleware
 	authMiddleware := middleware.NewAuthMiddleware(userService, jwtService, logr)
+	callbackAuthMiddleware := middleware.NewCallbackAuthMiddleware(cfg.VideoCfg.S3UploadCallbackSecret, logr)
 	middlewares := middleware.NewMiddleware(&middleware.MiddlewareList{
-		Auth: authMiddleware,
+		Auth:         authMiddleware,
+		CallbackAuth: callbackAuthMiddleware,
 	})
 
 	// Controllers
 	authController := controller.NewAuthController(userService, logr)
 	videoController := controller.NewVideoController(videoService, logr)
+	tusController := controller.NewTusController(videoService, logr)
+	webhookController := controller.NewWebhookController(a.hookSvc, logr)
 
-	// Pass the raw bucket name since that bucket's callback needs to be handled here
-	s3Controller := controller.NewS3CallbackController(cfg.VideoCfg.S3RawVideoBucketName, videoService, logr)
+	// The handler only accepts the events of the raw bucket
+	s3Controller := controller.NewS3CallbackController(a.storageEventHandler, logr)
 
 	// Route registrars
 	authRouter := routes.NewAuthRouter(authController, middlewares)
 	videoRouter := routes.NewVideoRouter(videoController, middlewares)
+	tusRouter := routes.NewTusRouter(tusController, middlewares)
+	webhookRouter := routes.NewWebhookRouter(webhookController, middlewares)
 	s3Router := routes.NewAWSCallbackRouter(s3Controller, middlewares)
 
+	registrars := []http.RouteRegistrar{
+		authRouter,  // Pass the auth router as a route registrar
+		videoRouter, // Pass the video router as a route registrar
+		s3Router,
+		tusRouter,
+		webhookRouter,
+	}
+
+	// The local storage driver serves its files through the API.
+	if localStore, ok := a.store.(*storage.LocalStore); ok {
+		storageController := controller.NewLocalStorageController(localStore, cfg.VideoCfg.S3RawVideoBucketName, videoService, logr)
+		registrars = append(registrars, routes.NewLocalStorageRouter(storageController, localStore.BucketURL("").Path))
+	}
+
 	// Create and start HTTP router
 	router := http.NewRouter(
 		http.RouterConfig{
 			Port:    cfg.Server.Port,
 			Address: cfg.Server.Address,
 		},
-		authRouter,  // Pass the auth router as a route registrar
-		videoRouter, // Pass the video router as a route registrar
-		s3Router,
+		registrars...,
 	)
 
 	// Start the server
-	if err := router.Start(); err != nil {
+	if err := router.Start(ctx); err != nil {
 		fmt.Println("Error starting server:", err)
 		os.Exit(1)
 	}
+
+	// Let the running jobs hand their state back to the queue before exiting.
+	stop()
+	if workerDone != nil {
+		<-workerDone
+	}
 }
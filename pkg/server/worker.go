@@ -0,0 +1,19 @@
+package server
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// StartWorker runs only the video processing pipeline and the maintenance tasks without the HTTP server.
+// It blocks until the process receives SIGINT or SIGTERM and the running jobs have been handed back.
+func StartWorker() {
+	a := bootstrap()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	a.runBackgroundProcessing(ctx)
+}
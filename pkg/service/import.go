@@ -0,0 +1,273 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fluxio-backend/pkg/utils"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Creates a video whose file is downloaded from a remote URL by a background job instead of being uploaded by the client.
+// The mime type is optional since the downloaded file is sniffed before it is stored.
+func (s *VideoService) ImportVideo(ctx context.Context, vidMeta model.Video, sourceURL string, mimeType string) (video model.Video, err error) {
+	logger := s.l.With("title", vidMeta.Title)
+
+	source, err := parseVideoImportURL(sourceURL)
+	if err != nil {
+		return
+	}
+
+	if strings.EqualFold(mimeType, "") {
+		mimeType = guessVideoImportMimeType(source)
+	}
+
+	video, _, err = s.AddVideo(ctx, vidMeta, mimeType)
+	if err != nil {
+		return
+	}
+
+	logger = logger.With("video_id", video.ID.String()).With("slug", video.Slug)
+
+	_, err = s.jobSvc.Enqueue(ctx, model.JobTypeVideoImport, model.VideoImportJobPayload{
+		Slug:      video.Slug,
+		SourceURL: source.String(),
+	}, videoImportJobKey(video.Slug), constants.MaxVideoImportJobAttempts)
+
+	if err != nil {
+		logger.Error("Failed to queue the video import", err)
+
+		// Nothing will ever upload the file so do not leave the video pending.
+		_, transitionErr := s.transitionStatus(ctx, video, model.VideoStatusUploadPending, model.VideoStatusFailed, "failed to queue the video import")
+		if transitionErr != nil {
+			logger.Error("Failed to mark the video as failed", transitionErr)
+		}
+
+		video = model.Video{}
+		err = fluxerrors.ErrVideoImportFailed
+		return
+	}
+
+	logger.Info("Video import queued")
+	return
+}
+
+// Runs a queued video import job. Errors the source cannot recover from fail the video right away while
+// network errors and server errors are retried until the job runs out of attempts.
+func (s *VideoService) HandleVideoImportJob(ctx context.Context, job model.Job) (err error) {
+	payload := model.VideoImportJobPayload{}
+
+	err = DecodeJobPayload(job, &payload)
+	if err != nil {
+		return
+	}
+
+	logger := s.l.With("slug", payload.Slug).With("job_id", job.ID.String())
+
+	video, err := s.videRepo.GetVideoBySlug(ctx, payload.Slug)
+	if err != nil {
+		if err == fluxerrors.ErrVideoNotFound {
+			logger.Info("Skipping import of a missing video")
+			err = nil
+		}
+		return
+	}
+
+	// The file was already stored by an earlier attempt which failed afterwards.
+	if video.Status != model.VideoStatusUploadPending {
+		logger.Info("Skipping video import", "status", video.Status.String())
+		return
+	}
+
+	logger = logger.With("video_id", video.ID.String())
+
+	storagePath, err := s.downloadVideoImport(ctx, video, payload.SourceURL, constants.MaxVideoImportSize)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if !isPermanentVideoImportError(err) && job.Attempts < job.MaxAttempts {
+			return
+		}
+
+		_, transitionErr := s.transitionStatus(ctx, video, model.VideoStatusUploadPending, model.VideoStatusFailed, err.Error())
+		if transitionErr != nil {
+			logger.Error("Failed to mark the video as failed", transitionErr)
+		}
+
+		logger.Error("Video import failed", err)
+		err = &model.JobPermanentError{Err: err}
+		return
+	}
+
+	err = s.UpdateUploadStatus(ctx, video.Slug, model.Video{
+		StoragePath: storagePath,
+	})
+	if err != nil {
+		return
+	}
+
+	err = s.QueuePostUploadProcessing(ctx, video.Slug)
+	if err != nil {
+		return
+	}
+
+	logger.Info("Video imported")
+	return
+}
+
+// Reports whether the import failed in a way which a retry cannot fix.
+func isPermanentVideoImportError(err error) bool {
+	return err == fluxerrors.ErrVideoImportTooLarge || err == fluxerrors.ErrVideoImportNotVideo ||
+		err == fluxerrors.ErrVideoImportSourceRejected || err == fluxerrors.ErrInvalidVideoImportURL
+}
+
+// Downloads the source file and streams it into the raw bucket while enforcing the size limit.
+func (s *VideoService) downloadVideoImport(ctx context.Context, video model.Video, sourceURL string, maxSize int64) (storagePath string, err error) {
+	logger := s.l.With("video_id", video.ID.String())
+
+	source, err := parseVideoImportURL(sourceURL)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, constants.VideoImportTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.String(), nil)
+	if err != nil {
+		err = fluxerrors.ErrInvalidVideoImportURL
+		return
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		logger.Error("Failed to reach the import source", err)
+		err = fluxerrors.ErrVideoImportFailed
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout {
+		err = fmt.Errorf("%w: source responded with %d", fluxerrors.ErrVideoImportFailed, resp.StatusCode)
+		return
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		logger.With("status_code", resp.StatusCode).Warn("Import source rejected the request")
+		err = fluxerrors.ErrVideoImportSourceRejected
+		return
+	}
+
+	if resp.ContentLength > maxSize {
+		err = fluxerrors.ErrVideoImportTooLarge
+		return
+	}
+
+	head := make([]byte, constants.VideoImportSniffLength)
+	n, err := io.ReadFull(resp.Body, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			err = fluxerrors.ErrVideoImportNotVideo
+			return
+		}
+
+		logger.Error("Failed to read the import source", err)
+		err = fluxerrors.ErrVideoImportFailed
+		return
+	}
+	head = head[:n]
+
+	mimeType, err := detectVideoImportMimeType(head, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return
+	}
+
+	body := &sizeLimitedReader{
+		r:     io.MultiReader(bytes.NewReader(head), resp.Body),
+		limit: maxSize,
+	}
+
+	storagePath, err = s.videRepo.UploadUnProcessedVideoFile(ctx, video.Slug, mimeType, body)
+	if body.exceeded {
+		err = fluxerrors.ErrVideoImportTooLarge
+		return
+	}
+
+	if err != nil {
+		return
+	}
+
+	logger.Info("Imported video file stored", "mime_type", mimeType, "size", body.read)
+	return
+}
+
+// Returns the type of the downloaded file. The sniffed type is trusted over the one reported by the source and
+// the reported one is only used when the content cannot be identified.
+func detectVideoImportMimeType(head []byte, reportedType string) (mimeType string, err error) {
+	mimeType = utils.SniffVideoMimeType(head)
+
+	if strings.EqualFold(mimeType, "") {
+		mimeType, _, _ = mime.ParseMediaType(reportedType)
+	}
+
+	if !utils.CheckVideoMimeTypeValidity(mimeType) {
+		mimeType = ""
+		err = fluxerrors.ErrVideoImportNotVideo
+		return
+	}
+
+	return
+}
+
+// Only absolute http and https URLs can be imported.
+func parseVideoImportURL(rawURL string) (source *url.URL, err error) {
+	source, err = url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (source.Scheme != "http" && source.Scheme != "https") || strings.EqualFold(source.Host, "") {
+		source = nil
+		err = fluxerrors.ErrInvalidVideoImportURL
+		return
+	}
+
+	return
+}
+
+// Guesses the type from the file extension of the URL. The guess only sets the initial format of the video.
+func guessVideoImportMimeType(source *url.URL) (mimeType string) {
+	mimeType, _, _ = mime.ParseMediaType(mime.TypeByExtension(path.Ext(source.Path)))
+	if !utils.CheckVideoMimeTypeValidity(mimeType) {
+		mimeType = constants.DefaultVideoImportMime
+	}
+
+	return
+}
+
+// Stops reading once more than the limit was read so that an oversized download is not stored.
+type sizeLimitedReader struct {
+	r        io.Reader
+	limit    int64
+	read     int64
+	exceeded bool
+}
+
+func (l *sizeLimitedReader) Read(p []byte) (n int, err error) {
+	n, err = l.r.Read(p)
+	l.read += int64(n)
+
+	if l.read > l.limit {
+		l.exceeded = true
+		err = errors.New("read limit exceeded")
+	}
+
+	return
+}
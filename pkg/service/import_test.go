@@ -0,0 +1,207 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/logger"
+	"fluxio-backend/pkg/model"
+	"fluxio-backend/pkg/repository"
+	"fluxio-backend/pkg/storage"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+const testRawBucket = "raw"
+
+// Returns a video service whose raw bucket is kept in a temporary directory.
+func newImportTestService(t *testing.T) (*VideoService, storage.ObjectStore) {
+	t.Helper()
+
+	store, err := storage.NewLocalStore(storage.LocalConfig{
+		Root:          t.TempDir(),
+		BaseURL:       "http://localhost/storage",
+		SigningSecret: "secret",
+	})
+	if err != nil {
+		t.Fatalf("failed to create the local store: %v", err)
+	}
+
+	logr := logger.NewDefaultLogger()
+	repo := repository.NewVideoRepository(nil, store, repository.VideoRepositoryConfig{
+		S3RawVideoBucketName:    testRawBucket,
+		S3PublicVideoBucketName: "public",
+		S3ThumbnailBucketName:   "thumbnails",
+	}, logr)
+
+	return NewVideoService(repo, nil, nil, VideoServiceConfig{}, logr), store
+}
+
+// Returns the start of an MP4 file which is enough for the type to be sniffed.
+func testMP4Body(size int) []byte {
+	body := make([]byte, size)
+	copy(body, []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom"))
+	return body
+}
+
+func TestDownloadVideoImport(t *testing.T) {
+	const maxSize = 4096
+
+	tests := []struct {
+		name          string
+		handler       http.HandlerFunc
+		wantErr       error
+		wantPermanent bool
+		wantStored    int
+	}{
+		{
+			name: "stores a video in the raw bucket",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/octet-stream")
+				w.Write(testMP4Body(2048))
+			},
+			wantStored: 2048,
+		},
+		{
+			name: "rejects a declared length over the limit",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Length", strconv.Itoa(maxSize+1))
+				w.Write(testMP4Body(maxSize + 1))
+			},
+			wantErr:       fluxerrors.ErrVideoImportTooLarge,
+			wantPermanent: true,
+		},
+		{
+			name: "rejects a body which grows past the limit",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				// Flushing before the body is written makes the response chunked so it has no length.
+				w.WriteHeader(http.StatusOK)
+				w.(http.Flusher).Flush()
+
+				body := testMP4Body(maxSize * 2)
+				for start := 0; start < len(body); start += 1024 {
+					w.Write(body[start : start+1024])
+					w.(http.Flusher).Flush()
+				}
+			},
+			wantErr:       fluxerrors.ErrVideoImportTooLarge,
+			wantPermanent: true,
+		},
+		{
+			name: "rejects a body which is not a video",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "video/mp4")
+				w.Write([]byte("<html><body>Not found</body></html>"))
+			},
+			wantErr:       fluxerrors.ErrVideoImportNotVideo,
+			wantPermanent: true,
+		},
+		{
+			name: "retries a server error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			wantErr: fluxerrors.ErrVideoImportFailed,
+		},
+		{
+			name: "retries a rate limited request",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			wantErr: fluxerrors.ErrVideoImportFailed,
+		},
+		{
+			name: "fails a rejected request permanently",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			wantErr:       fluxerrors.ErrVideoImportSourceRejected,
+			wantPermanent: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			svc, store := newImportTestService(t)
+			video := model.Video{ID: "9f0c7c61-3a55-4ad2-9a3c-1d5b4d7e8f10", Slug: "imported-video"}
+
+			storagePath, err := svc.downloadVideoImport(context.Background(), video, server.URL+"/video.mp4", maxSize)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				if permanent := isPermanentVideoImportError(err); permanent != tt.wantPermanent {
+					t.Errorf("got permanent %v, want %v", permanent, tt.wantPermanent)
+				}
+
+				if _, headErr := store.Head(context.Background(), testRawBucket, svc.videRepo.GetUnProcessedVideoFilePath(video.Slug)); headErr != fluxerrors.ErrObjectNotFound {
+					t.Errorf("got head error %v, want nothing stored", headErr)
+				}
+				return
+			}
+
+			body, info, err := store.Get(context.Background(), testRawBucket, storagePath)
+			if err != nil {
+				t.Fatalf("imported file not stored: %v", err)
+			}
+			defer body.Close()
+
+			data, _ := io.ReadAll(body)
+			if len(data) != tt.wantStored || !bytes.Equal(data, testMP4Body(tt.wantStored)) {
+				t.Errorf("got %d stored bytes, want %d", len(data), tt.wantStored)
+			}
+
+			if info.ContentType != "video/mp4" {
+				t.Errorf("got content type %q, want video/mp4", info.ContentType)
+			}
+		})
+	}
+}
+
+func TestDetectVideoImportMimeType(t *testing.T) {
+	tests := []struct {
+		name         string
+		head         []byte
+		reportedType string
+		wantMime     string
+		wantErr      error
+	}{
+		{"trusts the sniffed type over the reported one", testMP4Body(64), "text/plain", "video/mp4", nil},
+		{"falls back to the reported type", []byte{0x01, 0x02, 0x03}, "video/quicktime; charset=binary", "video/quicktime", nil},
+		{"rejects html reported as a video", []byte("<!DOCTYPE html><html></html>"), "video/mp4", "", fluxerrors.ErrVideoImportNotVideo},
+		{"rejects unknown content without a type", []byte{0x01, 0x02, 0x03}, "", "", fluxerrors.ErrVideoImportNotVideo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mimeType, err := detectVideoImportMimeType(tt.head, tt.reportedType)
+			if err != tt.wantErr || mimeType != tt.wantMime {
+				t.Errorf("got %q, %v, want %q, %v", mimeType, err, tt.wantMime, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSizeLimitedReader(t *testing.T) {
+	reader := &sizeLimitedReader{r: bytes.NewReader(make([]byte, 10)), limit: 8}
+
+	data, err := io.ReadAll(reader)
+	if err == nil || !reader.exceeded {
+		t.Fatalf("got %d bytes without exceeding the limit", len(data))
+	}
+
+	reader = &sizeLimitedReader{r: bytes.NewReader(make([]byte, 8)), limit: 8}
+
+	data, err = io.ReadAll(reader)
+	if err != nil || reader.exceeded || len(data) != 8 {
+		t.Fatalf("got %d bytes, %v, exceeded %v for a body at the limit", len(data), err, reader.exceeded)
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fluxio-backend/pkg/common/schema"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fluxio-backend/pkg/repository"
+	"fmt"
+	"time"
+)
+
+type JobService struct {
+	jobRepo *repository.JobRepository
+	l       schema.Logger
+}
+
+func NewJobService(jobRepo *repository.JobRepository, logger schema.Logger) *JobService {
+	return &JobService{
+		jobRepo: jobRepo,
+		l:       logger,
+	}
+}
+
+// Adds a job to the queue. An empty unique key allows any number of active jobs of the same kind.
+func (s *JobService) Enqueue(ctx context.Context, jobType model.JobType, payload any, uniqueKey string, maxAttempts uint32) (id model.JobID, err error) {
+	logger := s.l.With("job_type", jobType.String())
+
+	rawPayload, err := json.Marshal(payload)
+	if err != nil {
+		logger.Error("Failed to serialize the job payload", err)
+		err = fluxerrors.ErrInvalidJobPayload
+		return
+	}
+
+	id, err = s.jobRepo.Enqueue(ctx, model.Job{
+		Type:        jobType,
+		Payload:     rawPayload,
+		UniqueKey:   uniqueKey,
+		MaxAttempts: maxAttempts,
+	})
+
+	if err != nil {
+		if err == fluxerrors.ErrJobAlreadyQueued {
+			logger.Info("Job already queued", "unique_key", uniqueKey)
+			return
+		}
+
+		logger.Error("Failed to enqueue the job", err)
+		return
+	}
+
+	logger.Info("Job enqueued", "job_id", id.String())
+	return
+}
+
+// Checks whether a job with the unique key is still waiting or running.
+func (s *JobService) HasActiveJob(ctx context.Context, uniqueKey string) (exists bool, err error) {
+	return s.jobRepo.HasActiveJob(ctx, uniqueKey)
+}
+
+// Checks whether a job with the unique key was ever queued, whatever its state.
+func (s *JobService) HasJob(ctx context.Context, uniqueKey string) (exists bool, err error) {
+	return s.jobRepo.HasJob(ctx, uniqueKey)
+}
+
+// Claims the next runnable job for the worker.
+func (s *JobService) Claim(ctx context.Context, workerID string, jobTypes []model.JobType, lease time.Duration) (job model.Job, err error) {
+	return s.jobRepo.Claim(ctx, workerID, jobTypes, lease)
+}
+
+func (s *JobService) ExtendLease(ctx context.Context, job model.Job, lease time.Duration) (err error) {
+	return s.jobRepo.ExtendLease(ctx, job.ID, job.LockedBy, lease)
+}
+
+func (s *JobService) Complete(ctx context.Context, job model.Job) (err error) {
+	return s.jobRepo.Complete(ctx, job.ID, job.LockedBy)
+}
+
+func (s *JobService) Retry(ctx context.Context, job model.Job, runAt time.Time, jobErr error) (err error) {
+	return s.jobRepo.Retry(ctx, job.ID, job.LockedBy, runAt, errorMessage(jobErr))
+}
+
+func (s *JobService) Reschedule(ctx context.Context, job model.Job, runAt time.Time, jobErr error) (err error) {
+	return s.jobRepo.Reschedule(ctx, job.ID, job.LockedBy, runAt, errorMessage(jobErr))
+}
+
+func (s *JobService) Fail(ctx context.Context, job model.Job, jobErr error) (err error) {
+	return s.jobRepo.Fail(ctx, job.ID, job.LockedBy, errorMessage(jobErr))
+}
+
+// Decodes the payload of a job into the given value.
+func DecodeJobPayload(job model.Job, payload any) (err error) {
+	err = json.Unmarshal(job.Payload, payload)
+	if err != nil {
+		err = fluxerrors.ErrInvalidJobPayload
+	}
+	return
+}
+
+func videoProcessingJobKey(slug string) string {
+	return fmt.Sprintf("%s:%s", model.JobTypeVideoProcessing, slug)
+}
+
+func videoImportJobKey(slug string) string {
+	return fmt.Sprintf("%s:%s", model.JobTypeVideoImport, slug)
+}
+
+func thumbnailRegenerationJobKey(slug string) string {
+	return fmt.Sprintf("%s:%s", model.JobTypeThumbnailRegeneration, slug)
+}
+
+func webhookDeliveryJobKey(id model.WebhookDeliveryID) string {
+	return fmt.Sprintf("%s:%s", model.JobTypeWebhookDelivery, id)
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
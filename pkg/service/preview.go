@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"fluxio-backend/pkg/common/schema"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	ffmpeg_go "github.com/u2takey/ffmpeg-go"
+)
+
+// Name of the preview stage, also used as the stage of the reported ffmpeg progress.
+const previewStageName = "preview"
+
+// A part of the video which is cut into the preview clip.
+type previewSegment struct {
+	start    uint64
+	duration uint64
+}
+
+func (s *VideoService) runPreviewStage(ctx context.Context, logger schema.Logger, state *processingState) (err error) {
+	preview, err := s.generatePreview(ctx, logger, state.video, state.downloadURL)
+	if err != nil {
+		return
+	}
+
+	logger.Info("Preview clip generation completed", "segment_count", preview.SegmentCount, "duration", preview.Duration)
+	return
+}
+
+// Cuts a few short segments spread over the video, stitches them into a muted low resolution MP4 and converts
+// that into an animated WebP. Both the files are uploaded to the thumbnail bucket.
+func (s *VideoService) generatePreview(ctx context.Context, logger schema.Logger, video model.Video, downloadURL string) (preview model.ThumbnailPreview, err error) {
+	segments := previewSegments(video.Length)
+	if len(segments) == 0 || video.Width == 0 || video.Height == 0 {
+		err = fluxerrors.ErrThumbnailPreviewFailed
+		return
+	}
+
+	previewTempDir, err := os.MkdirTemp(os.TempDir(), "fluxio-preview-*")
+	if err != nil {
+		logger.Error("Failed to create temporary directory for the preview", err)
+		err = fluxerrors.ErrThumbnailPreviewFailed
+		return
+	}
+
+	defer os.RemoveAll(previewTempDir)
+
+	width, height := scaleToShortEdge(video.Width, video.Height, min(constants.PreviewShortEdge, video.Width, video.Height))
+
+	// Every segment is read with its own seeking input so only the needed ranges of the file are downloaded.
+	streams := make([]*ffmpeg_go.Stream, 0, len(segments))
+	var duration uint64
+
+	for _, segment := range segments {
+		streams = append(streams, ffmpeg_go.Input(downloadURL, ffmpeg_go.KwArgs{
+			"ss": segment.start,
+			"t":  segment.duration,
+		}).Video().
+			Filter("fps", ffmpeg_go.Args{fmt.Sprint(constants.PreviewFrameRate)}).
+			Filter("scale", ffmpeg_go.Args{fmt.Sprintf("%d:%d", width, height)}).
+			Filter("setsar", ffmpeg_go.Args{"1"}).
+			Filter("setpts", ffmpeg_go.Args{"PTS-STARTPTS"}))
+
+		duration += segment.duration
+	}
+
+	// Both the runs write the clip so their progress is measured against its length instead of the video's.
+	clip := video
+	clip.Length = duration
+
+	mp4Path := path.Join(previewTempDir, constants.PreviewMP4Name)
+	mp4Progress := s.newProgressWriter(ctx, logger, clip, previewStageName, "mp4")
+
+	err = ffmpeg_go.OutputContext(ctx, []*ffmpeg_go.Stream{ffmpeg_go.Concat(streams)}, mp4Path, ffmpeg_go.KwArgs{
+		"c:v":       "libx264",
+		"preset":    "veryfast",
+		"profile:v": "main",
+		"crf":       30,
+		"pix_fmt":   "yuv420p",
+		"movflags":  "+faststart", // Lets the browsers start playing before the file is fully loaded
+		"an":        "",
+	}).GlobalArgs(ffmpegProgressArgs...).OverWriteOutput().WithOutput(mp4Progress).Run()
+
+	if err != nil {
+		logger.Error("Failed to generate the preview clip", err)
+		err = fluxerrors.ErrThumbnailPreviewFailed
+		return
+	}
+
+	webpPath := path.Join(previewTempDir, constants.PreviewWebPName)
+	webpProgress := s.newProgressWriter(ctx, logger, clip, previewStageName, "webp")
+
+	err = ffmpeg_go.OutputContext(ctx, []*ffmpeg_go.Stream{ffmpeg_go.Input(mp4Path)}, webpPath, ffmpeg_go.KwArgs{
+		"c:v":      "libwebp",
+		"loop":     0, // Loop forever
+		"lossless": 0,
+		"q:v":      60,
+		"an":       "",
+	}).GlobalArgs(ffmpegProgressArgs...).OverWriteOutput().WithOutput(webpProgress).Run()
+
+	if err != nil {
+		logger.Error("Failed to convert the preview clip to WebP", err)
+		err = fluxerrors.ErrThumbnailPreviewFailed
+		return
+	}
+
+	preview = model.ThumbnailPreview{
+		VideoID:      video.ID,
+		Width:        uint16(width),
+		Height:       uint16(height),
+		Duration:     uint32(duration),
+		SegmentCount: uint8(len(segments)),
+		WebPPath:     s.videRepo.GetThumbnailPreviewFilePath(video.ID, constants.PreviewWebPName),
+		MP4Path:      s.videRepo.GetThumbnailPreviewFilePath(video.ID, constants.PreviewMP4Name),
+	}
+
+	preview.MP4Size, err = s.uploadPreviewFile(ctx, video.ID, mp4Path, constants.PreviewMP4Name, "video/mp4")
+	if err != nil {
+		return
+	}
+
+	preview.WebPSize, err = s.uploadPreviewFile(ctx, video.ID, webpPath, constants.PreviewWebPName, "image/webp")
+	if err != nil {
+		return
+	}
+
+	err = s.videRepo.SaveThumbnailPreview(ctx, preview)
+	return
+}
+
+// Uploads a local preview file and returns its size in KB.
+func (s *VideoService) uploadPreviewFile(ctx context.Context, id model.VideoID, filePath string, fileName string, contentType string) (size uint32, err error) {
+	fileStat, err := os.Stat(filePath)
+	if err != nil {
+		err = fluxerrors.ErrThumbnailPreviewFailed
+		return
+	}
+
+	err = uploadLocalFile(filePath, func(file io.Reader) error {
+		return s.videRepo.UploadThumbnailPreviewFile(ctx, id, fileName, contentType, file)
+	})
+	if err != nil {
+		return
+	}
+
+	size = uint32(fileStat.Size() / 1024)
+	return
+}
+
+// Spreads the preview segments evenly over the video with every segment centered in its part of the video.
+// A video too short for the segments is used as a single segment.
+func previewSegments(length uint64) (segments []previewSegment) {
+	if length == 0 {
+		return
+	}
+
+	total := uint64(constants.PreviewSegmentCount * constants.PreviewSegmentDuration)
+	if length <= total {
+		return []previewSegment{{start: 0, duration: length}}
+	}
+
+	for i := uint64(0); i < constants.PreviewSegmentCount; i++ {
+		center := length * (2*i + 1) / (2 * constants.PreviewSegmentCount)
+		start := center - min(center, constants.PreviewSegmentDuration/2)
+		start = min(start, length-constants.PreviewSegmentDuration)
+
+		segments = append(segments, previewSegment{start: start, duration: constants.PreviewSegmentDuration})
+	}
+
+	return
+}
@@ -0,0 +1,327 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fluxio-backend/pkg/common/schema"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	ffmpeg_go "github.com/u2takey/ffmpeg-go"
+)
+
+// Name of the transcoding stage, also used as the stage of the reported ffmpeg progress.
+const transcodeStageName = "transcode"
+
+// Data shared between the stages of a single processing run.
+type processingState struct {
+	video       model.Video
+	downloadURL string
+}
+
+// A single retryable step of the post upload processing. The outcome of every stage is recorded in the
+// internal status of the video so that a later run can resume after the last successful stage.
+type processingStage struct {
+	name         string
+	run          func(ctx context.Context, logger schema.Logger, state *processingState) error
+	doneStatus   model.VideoInternalStatus
+	failedStatus model.VideoInternalStatus
+	maxAttempts  uint8
+	optional     bool // Processing continues without the stage once it runs out of attempts
+}
+
+// Returns the processing stages in the order they are executed.
+func (s *VideoService) processingStages() []processingStage {
+	return []processingStage{
+		{
+			name:         "probe",
+			run:          s.runProbeStage,
+			doneStatus:   model.VidInternalStatusProbed,
+			failedStatus: model.VidInternalStatusMetaFailed,
+			maxAttempts:  constants.MaxVideoProcessingStageRetryCount,
+		},
+		{
+			name:         "persist_metadata",
+			run:          s.runPersistMetadataStage,
+			doneStatus:   model.VidInternalStatusMetaExtracted,
+			failedStatus: model.VidInternalStatusMetaFailed,
+			maxAttempts:  constants.MaxVideoProcessingStageRetryCount,
+		},
+		{
+			name:         "thumbnails",
+			run:          s.runThumbnailStage,
+			doneStatus:   model.VidInternalStatusThumbnailGenerated,
+			failedStatus: model.VidInternalStatusThumbnailFailed,
+			maxAttempts:  constants.MaxVideoThumbnailRegenerateRetryCount,
+			optional:     true,
+		},
+		{
+			name:         spriteStageName,
+			run:          s.runSpriteStage,
+			doneStatus:   model.VidInternalStatusSpritesGenerated,
+			failedStatus: model.VidInternalStatusSpritesFailed,
+			maxAttempts:  constants.MaxVideoThumbnailRegenerateRetryCount,
+			optional:     true,
+		},
+		{
+			name:         previewStageName,
+			run:          s.runPreviewStage,
+			doneStatus:   model.VidInternalStatusPreviewGenerated,
+			failedStatus: model.VidInternalStatusPreviewFailed,
+			maxAttempts:  constants.MaxVideoThumbnailRegenerateRetryCount,
+			optional:     true,
+		},
+		{
+			name:         transcodeStageName,
+			run:          s.runTranscodeStage,
+			doneStatus:   model.VidInternalStatusTranscoded,
+			failedStatus: model.VidInternalStatusTranscodeFailed,
+			maxAttempts:  constants.MaxVideoProcessingStageRetryCount,
+		},
+		{
+			name:        "finalize",
+			run:         s.runFinalizeStage,
+			doneStatus:  model.VidInternalStatusProcessingCompleted,
+			maxAttempts: constants.MaxVideoProcessingStageRetryCount,
+		},
+	}
+}
+
+// Reports whether the video recorded the stage as skipped. An optional stage which ran out of attempts keeps
+// its failed status with the attempts used up so that processing resumes after it.
+func (p processingStage) isSkipped(video model.Video) bool {
+	return p.optional && p.failedStatus == video.InternalStatus && video.StageAttempts >= p.maxAttempts
+}
+
+// Returns the index of the stage processing has to resume from. A failed stage is retried while a
+// successful or skipped stage is not run again.
+func resumeStageIndex(stages []processingStage, video model.Video) int {
+	switch video.InternalStatus {
+	case "", model.VidInternalStatusUploadPending:
+		return 0
+	case model.VidInternalStatusMetaFailed:
+		// The probe and the metadata stages share the failed status so use the stored probe output to tell them apart.
+		if strings.EqualFold(video.ProbeData, "") {
+			return 0
+		}
+		return 1
+	}
+
+	for idx, stage := range stages {
+		if stage.doneStatus == video.InternalStatus || stage.isSkipped(video) {
+			return idx + 1
+		}
+
+		if stage.failedStatus == video.InternalStatus {
+			return idx
+		}
+	}
+
+	return 0
+}
+
+// Records a failed stage attempt and decides how processing continues. The job is rescheduled with an
+// exponential backoff while the stage has attempts left. Once they are used up an optional stage is skipped
+// and any other stage fails the video permanently.
+func (s *VideoService) handleStageFailure(ctx context.Context, logger schema.Logger, state *processingState, stage processingStage, stageErr error) (skip bool, err error) {
+	attempts := state.video.StageAttempts + 1
+	permanent := stageErr == fluxerrors.ErrVideoStreamCountNotSupported // Retrying cannot fix an unsupported file
+
+	if attempts < stage.maxAttempts && !permanent {
+		runAt := time.Now().Add(processingRetryDelay(attempts))
+
+		err = s.videRepo.RecordProcessingFailure(ctx, state.video.ID, model.VideoStatusProcessingDelay, stage.failedStatus, attempts, stageErr.Error())
+		if err != nil {
+			logger.Error("Failed to record the stage failure", err)
+			return
+		}
+
+		s.publishStage(state.video, stage.failedStatus, stageErr.Error())
+
+		logger.Info("Processing stage scheduled for retry", "attempt", attempts, "run_at", runAt)
+		err = &model.JobRetryError{Err: stageErr, RunAt: runAt}
+		return
+	}
+
+	if stage.optional && !permanent {
+		err = s.videRepo.RecordProcessingFailure(ctx, state.video.ID, model.VideoStatusProcessing, stage.failedStatus, stage.maxAttempts, stageErr.Error())
+		if err != nil {
+			logger.Error("Failed to record the stage failure", err)
+			return
+		}
+
+		s.publishStage(state.video, stage.failedStatus, stageErr.Error())
+
+		logger.Warn("Optional processing stage ran out of attempts, continuing without it")
+		state.video.StageAttempts = 0
+		skip = true
+		return
+	}
+
+	err = s.videRepo.RecordProcessingFailure(ctx, state.video.ID, model.VideoStatusFailed, stage.failedStatus, attempts, stageErr.Error())
+	if err != nil {
+		logger.Error("Failed to record the stage failure", err)
+		return
+	}
+
+	s.publishStage(state.video, stage.failedStatus, stageErr.Error())
+
+	logger.Error("Video processing failed permanently", stageErr)
+	err = &model.JobPermanentError{Err: fluxerrors.ErrVideoProcessingFailed}
+	return
+}
+
+// Returns the percentage of the processing which is done once the given number of stages finished.
+func processingProgress(doneStages int, totalStages int) float64 {
+	return float64(doneStages) * 100 / float64(totalStages)
+}
+
+// Returns the delay before the given attempt of a stage is retried.
+func processingRetryDelay(attempt uint8) time.Duration {
+	delay := constants.VideoProcessingRetryBaseDelay
+	for i := uint8(1); i < attempt; i++ {
+		delay *= 2
+		if delay >= constants.VideoProcessingRetryMaxDelay {
+			return constants.VideoProcessingRetryMaxDelay
+		}
+	}
+
+	return delay
+}
+
+// Probes the uploaded file and stores the raw output so the probe does not have to be repeated.
+func (s *VideoService) runProbeStage(ctx context.Context, logger schema.Logger, state *processingState) (err error) {
+	rawProbe, err := ffmpeg_go.Probe(state.downloadURL)
+	if err != nil {
+		logger.Error("Failed to probe video using ffmpeg", err)
+		err = fluxerrors.ErrVideoPhysicalMetaExtractionFailed
+		return
+	}
+
+	err = s.videRepo.SaveProbeData(ctx, state.video.ID, rawProbe)
+	if err != nil {
+		return
+	}
+
+	state.video.ProbeData = rawProbe
+	return
+}
+
+// Extracts the physical metadata from the stored probe output and persists it on the video.
+func (s *VideoService) runPersistMetadataStage(ctx context.Context, logger schema.Logger, state *processingState) (err error) {
+	meta, err := parseProbeMetadata(state.video.ProbeData)
+	if err != nil {
+		logger.Error("Failed to parse the probe output", err)
+		return
+	}
+
+	meta.IsFeatured = state.video.IsFeatured
+
+	err = s.videRepo.UpdateMeta(ctx, state.video.ID, model.VideoStatusProcessing, meta)
+	if err != nil {
+		return
+	}
+
+	state.video.AudioCodec = meta.AudioCodec
+	state.video.AudioSampleRate = meta.AudioSampleRate
+	state.video.Width = meta.Width
+	state.video.Height = meta.Height
+	state.video.Format = meta.Format
+	state.video.Length = meta.Length
+	state.video.Size = meta.Size
+	return
+}
+
+func (s *VideoService) runThumbnailStage(ctx context.Context, logger schema.Logger, state *processingState) (err error) {
+	successCount, err := s.generateThumbnails(ctx, logger, state.video, state.downloadURL, nil)
+	if err != nil {
+		return
+	}
+
+	logger.Info("Thumbnail generation completed", "thumbnails_created", successCount)
+	return
+}
+
+// Transcodes the video into the adaptive bitrate ladder and stores the manifests of the packaged output.
+func (s *VideoService) runTranscodeStage(ctx context.Context, logger schema.Logger, state *processingState) (err error) {
+	renditions := s.buildRenditionLadder(state.video.Width, state.video.Height)
+	logger.Info("Starting video transcoding", "rendition_count", len(renditions))
+
+	manifests, err := s.transcodeAndPackage(ctx, logger, state.downloadURL, state.video, renditions)
+	if err != nil {
+		return
+	}
+
+	return s.videRepo.SaveVideoManifests(ctx, state.video.ID, manifests)
+}
+
+// Marks the video as playable.
+func (s *VideoService) runFinalizeStage(ctx context.Context, logger schema.Logger, state *processingState) (err error) {
+	return s.videRepo.UpdateMeta(ctx, state.video.ID, model.VideoStatusCompleted, model.Video{
+		IsFeatured: state.video.IsFeatured,
+	})
+}
+
+// Extracts the physical metadata of a video from the raw ffprobe output.
+func parseProbeMetadata(rawProbe string) (meta model.Video, err error) {
+	var probe model.FFProbeOutput
+	err = json.Unmarshal([]byte(rawProbe), &probe)
+	if err != nil {
+		err = fluxerrors.ErrVideoPhysicalMetaExtractionFailed
+		return
+	}
+
+	if probe.Format.NbStreams != 2 || len(probe.Streams) != 2 {
+		err = fluxerrors.ErrVideoStreamCountNotSupported
+		return
+	}
+
+	videoStream := model.FFProbeStream{}
+	audioStream := model.FFProbeStream{}
+
+	// Get the streams from the probe
+	if probe.Streams[0].CodecType == "video" {
+		videoStream = probe.Streams[0]
+		audioStream = probe.Streams[1]
+	} else {
+		audioStream = probe.Streams[0]
+		videoStream = probe.Streams[1]
+	}
+
+	meta.AudioCodec = audioStream.CodecName
+
+	sampleRate, err := strconv.Atoi(audioStream.SampleRate)
+	if err != nil {
+		err = fluxerrors.ErrVideoPhysicalMetaExtractionFailed
+		return
+	}
+
+	meta.AudioSampleRate = uint32(sampleRate)
+	meta.Width = uint32(videoStream.Width)
+	meta.Height = uint32(videoStream.Height)
+	meta.Format = videoStream.CodecName
+
+	duration, err := strconv.ParseFloat(probe.Format.Duration, 64)
+	if err != nil {
+		err = fluxerrors.ErrVideoPhysicalMetaExtractionFailed
+		return
+	}
+
+	meta.Length = uint64(math.Ceil(duration))
+
+	size, err := strconv.ParseFloat(probe.Format.Size, 64)
+	if err != nil {
+		err = fluxerrors.ErrVideoPhysicalMetaExtractionFailed
+		return
+	}
+
+	calcPrec := math.Pow(10, float64(constants.VidSizeDecimalPrecision)) // Stores the power precision to round the size.
+	meta.Size = float32(math.Round(size*calcPrec) / calcPrec)            // Round the size to decimal places.
+
+	return
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"fluxio-backend/pkg/constants"
+	"fluxio-backend/pkg/model"
+	"testing"
+)
+
+func TestResumeStageIndex(t *testing.T) {
+	stages := (&VideoService{}).processingStages()
+
+	stageIndex := func(name string) int {
+		for idx, stage := range stages {
+			if stage.name == name {
+				return idx
+			}
+		}
+
+		t.Fatalf("no stage named %s", name)
+		return -1
+	}
+
+	tests := []struct {
+		name  string
+		video model.Video
+		want  int
+	}{
+		{
+			name:  "starts a new upload from the beginning",
+			video: model.Video{InternalStatus: model.VidInternalStatusUploadPending},
+			want:  0,
+		},
+		{
+			name:  "retries the probe without a stored probe output",
+			video: model.Video{InternalStatus: model.VidInternalStatusMetaFailed, StageAttempts: 1},
+			want:  stageIndex("probe"),
+		},
+		{
+			name:  "retries the metadata with a stored probe output",
+			video: model.Video{InternalStatus: model.VidInternalStatusMetaFailed, ProbeData: "{}", StageAttempts: 1},
+			want:  stageIndex("persist_metadata"),
+		},
+		{
+			name:  "resumes after a successful stage",
+			video: model.Video{InternalStatus: model.VidInternalStatusThumbnailGenerated},
+			want:  stageIndex(spriteStageName),
+		},
+		{
+			name:  "retries an optional stage with attempts left",
+			video: model.Video{InternalStatus: model.VidInternalStatusThumbnailFailed, StageAttempts: 1},
+			want:  stageIndex("thumbnails"),
+		},
+		{
+			name:  "resumes after a skipped optional stage",
+			video: model.Video{InternalStatus: model.VidInternalStatusThumbnailFailed, StageAttempts: constants.MaxVideoThumbnailRegenerateRetryCount},
+			want:  stageIndex(spriteStageName),
+		},
+		{
+			name:  "resumes after the last skipped optional stage",
+			video: model.Video{InternalStatus: model.VidInternalStatusPreviewFailed, StageAttempts: constants.MaxVideoThumbnailRegenerateRetryCount},
+			want:  stageIndex(transcodeStageName),
+		},
+		{
+			name:  "retries a required stage",
+			video: model.Video{InternalStatus: model.VidInternalStatusTranscodeFailed, StageAttempts: constants.MaxVideoProcessingStageRetryCount},
+			want:  stageIndex(transcodeStageName),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resumeStageIndex(stages, tt.video); got != tt.want {
+				t.Errorf("got stage %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
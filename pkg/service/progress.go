@@ -0,0 +1,126 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fluxio-backend/pkg/common/schema"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Arguments which make ffmpeg write its progress as key=value lines to the standard output.
+var ffmpegProgressArgs = []string{"-progress", "pipe:1", "-nostats"}
+
+// Parses the progress which ffmpeg writes with the -progress option. Every block of the output ends with a
+// "progress" line at which the position reached so far is compared against the duration of the source.
+type ffmpegProgressWriter struct {
+	duration  time.Duration
+	startedAt time.Time
+	outTime   time.Duration
+	pending   []byte // Part of a line which was not terminated yet
+	report    func(percent float64, eta *uint64, done bool)
+}
+
+func newFFmpegProgressWriter(duration time.Duration, report func(percent float64, eta *uint64, done bool)) *ffmpegProgressWriter {
+	return &ffmpegProgressWriter{
+		duration:  duration,
+		startedAt: time.Now(),
+		report:    report,
+	}
+}
+
+// Never fails since an error would make ffmpeg stop writing to the pipe.
+func (w *ffmpegProgressWriter) Write(p []byte) (n int, err error) {
+	w.pending = append(w.pending, p...)
+
+	for {
+		idx := bytes.IndexByte(w.pending, '\n')
+		if idx < 0 {
+			break
+		}
+
+		w.parseLine(strings.TrimSpace(string(w.pending[:idx])))
+		w.pending = w.pending[idx+1:]
+	}
+
+	return len(p), nil
+}
+
+func (w *ffmpegProgressWriter) parseLine(line string) {
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return
+	}
+
+	switch key {
+	case "out_time_ms", "out_time_us":
+		// Despite the name out_time_ms is in microseconds as well. The value is N/A until the first frame is written.
+		outTime, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || outTime < 0 {
+			return
+		}
+
+		w.outTime = time.Duration(outTime) * time.Microsecond
+	case "progress":
+		w.emit(value == "end")
+	}
+}
+
+func (w *ffmpegProgressWriter) emit(done bool) {
+	if w.duration <= 0 {
+		return
+	}
+
+	if done {
+		eta := uint64(0)
+		w.report(100, &eta, true)
+		return
+	}
+
+	fraction := min(float64(w.outTime)/float64(w.duration), 1)
+
+	var eta *uint64
+	if fraction > 0 {
+		elapsed := time.Since(w.startedAt).Seconds()
+		remaining := uint64(elapsed * (1 - fraction) / fraction)
+		eta = &remaining
+	}
+
+	w.report(fraction*100, eta, false)
+}
+
+// Returns a writer for the -progress output of an ffmpeg run of the video. The progress is stored on the video
+// and pushed to its watchers at most once every report interval and once more when the run ends.
+func (s *VideoService) newProgressWriter(ctx context.Context, logger schema.Logger, video model.Video, stage string, step string) *ffmpegProgressWriter {
+	var lastReport time.Time
+
+	return newFFmpegProgressWriter(time.Duration(video.Length)*time.Second, func(percent float64, eta *uint64, done bool) {
+		now := time.Now()
+		if !done && now.Sub(lastReport) < constants.VideoProgressReportInterval {
+			return
+		}
+
+		lastReport = now
+
+		progress := model.VideoProgress{
+			Stage:      stage,
+			Step:       step,
+			Percent:    percent,
+			ETASeconds: eta,
+			UpdatedAt:  &now,
+		}
+
+		// Progress is informational so processing goes on without it. The video is not found when it is not
+		// processed, like while the thumbnails of a completed video are regenerated.
+		err := s.videRepo.UpdateProcessingProgress(ctx, video.ID, progress)
+		if err != nil && err != fluxerrors.ErrVideoNotFound {
+			logger.With("error", err.Error()).Warn("Failed to store the processing progress")
+		}
+
+		s.publishStageProgress(video, progress)
+	})
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+type progressReport struct {
+	percent float64
+	hasETA  bool
+	done    bool
+}
+
+func TestFFmpegProgressWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration time.Duration
+		writes   []string
+		want     []progressReport
+	}{
+		{
+			name:     "reports the position at every progress line",
+			duration: 10 * time.Second,
+			writes:   []string{"frame=10\nout_time_us=2500000\nprogress=continue\nout_time_us=5000000\nprogress=continue\n"},
+			want:     []progressReport{{25, true, false}, {50, true, false}},
+		},
+		{
+			name:     "joins lines split across writes",
+			duration: 10 * time.Second,
+			writes:   []string{"out_time", "_us=75", "00000\npro", "gress=cont", "inue\n"},
+			want:     []progressReport{{75, true, false}},
+		},
+		{
+			name:     "waits for the line to be terminated",
+			duration: 10 * time.Second,
+			writes:   []string{"out_time_us=5000000\nprogress=continue"},
+			want:     nil,
+		},
+		{
+			name:     "reads out_time_ms as microseconds",
+			duration: 10 * time.Second,
+			writes:   []string{"out_time_ms=2000000\nprogress=continue\n"},
+			want:     []progressReport{{20, true, false}},
+		},
+		{
+			name:     "ignores a position which is not available yet",
+			duration: 10 * time.Second,
+			writes:   []string{"out_time_us=N/A\nprogress=continue\n"},
+			want:     []progressReport{{0, false, false}},
+		},
+		{
+			name:     "keeps the last position when it becomes unavailable",
+			duration: 10 * time.Second,
+			writes:   []string{"out_time_us=4000000\nprogress=continue\nout_time_us=N/A\nprogress=continue\n"},
+			want:     []progressReport{{40, true, false}, {40, true, false}},
+		},
+		{
+			name:     "caps the position at the duration",
+			duration: 10 * time.Second,
+			writes:   []string{"out_time_us=12000000\nprogress=continue\n"},
+			want:     []progressReport{{100, true, false}},
+		},
+		{
+			name:     "completes at the end",
+			duration: 10 * time.Second,
+			writes:   []string{"out_time_us=9000000\r\nprogress=end\r\n"},
+			want:     []progressReport{{100, true, true}},
+		},
+		{
+			name:     "reports nothing without a duration",
+			duration: 0,
+			writes:   []string{"out_time_us=5000000\nprogress=continue\nprogress=end\n"},
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []progressReport
+			writer := newFFmpegProgressWriter(tt.duration, func(percent float64, eta *uint64, done bool) {
+				got = append(got, progressReport{percent, eta != nil, done})
+
+				if done && (eta == nil || *eta != 0) {
+					t.Errorf("got eta %v at the end, want 0", eta)
+				}
+			})
+
+			for _, write := range tt.writes {
+				n, err := writer.Write([]byte(write))
+				if err != nil || n != len(write) {
+					t.Fatalf("wrote %d of %d bytes, %v", n, len(write), err)
+				}
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got reports %v, want %v", got, tt.want)
+			}
+
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got report %d %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
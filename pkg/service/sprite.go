@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"fluxio-backend/pkg/common/schema"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	ffmpeg_go "github.com/u2takey/ffmpeg-go"
+)
+
+// Name of the sprite stage, also used as the stage of the reported ffmpeg progress.
+const spriteStageName = "sprites"
+
+// Content types of the supported sprite sheet formats.
+var spriteContentTypes = map[string]string{
+	"jpg":  "image/jpeg",
+	"webp": "image/webp",
+}
+
+func (s *VideoService) runSpriteStage(ctx context.Context, logger schema.Logger, state *processingState) (err error) {
+	sprite, err := s.generateSprites(ctx, logger, state.video, state.downloadURL)
+	if err != nil {
+		return
+	}
+
+	logger.Info("Sprite generation completed", "sheet_count", sprite.SheetCount, "frame_count", sprite.FrameCount)
+	return
+}
+
+// Samples a frame of the video every sprite interval, tiles the frames into sprite sheets and uploads them
+// to the thumbnail bucket along with a WebVTT index which maps every interval to its region on a sheet.
+func (s *VideoService) generateSprites(ctx context.Context, logger schema.Logger, video model.Video, downloadURL string) (sprite model.ThumbnailSprite, err error) {
+	contentType, ok := spriteContentTypes[constants.SpriteFormat]
+	if !ok || video.Length == 0 {
+		err = fluxerrors.ErrThumbnailSpriteFailed
+		return
+	}
+
+	spriteTempDir, err := os.MkdirTemp(os.TempDir(), "fluxio-sprites-*")
+	if err != nil {
+		logger.Error("Failed to create temporary directory for sprites", err)
+		err = fluxerrors.ErrThumbnailSpriteFailed
+		return
+	}
+
+	defer os.RemoveAll(spriteTempDir)
+
+	progress := s.newProgressWriter(ctx, logger, video, spriteStageName, "")
+
+	// Every frame is scaled into the same cell and padded so that the regions in the index stay on a grid.
+	err = ffmpeg_go.OutputContext(ctx, []*ffmpeg_go.Stream{ffmpeg_go.Input(downloadURL)}, path.Join(spriteTempDir, fmt.Sprintf("sprite-%%03d.%s", constants.SpriteFormat)), ffmpeg_go.KwArgs{
+		"vf": fmt.Sprintf("fps=1/%[1]d,scale=w=%[2]d:h=%[3]d:force_original_aspect_ratio=decrease,pad=%[2]d:%[3]d:(ow-iw)/2:(oh-ih)/2,tile=%[4]dx%[5]d",
+			constants.SpriteFrameInterval, constants.SpriteFrameWidth, constants.SpriteFrameHeight, constants.SpriteColumns, constants.SpriteRows),
+		"q:v": 5,
+		"an":  "",
+	}).GlobalArgs(ffmpegProgressArgs...).OverWriteOutput().WithOutput(progress).Run()
+
+	if err != nil {
+		logger.Error("Failed to generate the sprite sheets", err)
+		err = fluxerrors.ErrThumbnailSpriteFailed
+		return
+	}
+
+	sheets, err := filepath.Glob(path.Join(spriteTempDir, fmt.Sprintf("sprite-*.%s", constants.SpriteFormat)))
+	if err != nil || len(sheets) == 0 {
+		logger.Error("No sprite sheets were generated", err)
+		err = fluxerrors.ErrThumbnailSpriteFailed
+		return
+	}
+
+	sort.Strings(sheets)
+
+	sheetNames := make([]string, 0, len(sheets))
+	for _, sheet := range sheets {
+		sheetName := filepath.Base(sheet)
+
+		err = uploadLocalFile(sheet, func(file io.Reader) error {
+			return s.videRepo.UploadThumbnailSpriteFile(ctx, video.ID, sheetName, contentType, file)
+		})
+		if err != nil {
+			return
+		}
+
+		sheetNames = append(sheetNames, sheetName)
+	}
+
+	framesPerSheet := uint64(constants.SpriteColumns * constants.SpriteRows)
+	frameCount := min((video.Length+constants.SpriteFrameInterval-1)/constants.SpriteFrameInterval, uint64(len(sheetNames))*framesPerSheet)
+
+	sprite = model.ThumbnailSprite{
+		VideoID:     video.ID,
+		Format:      constants.SpriteFormat,
+		Interval:    constants.SpriteFrameInterval,
+		FrameWidth:  constants.SpriteFrameWidth,
+		FrameHeight: constants.SpriteFrameHeight,
+		Columns:     constants.SpriteColumns,
+		Rows:        constants.SpriteRows,
+		SheetCount:  uint32(len(sheetNames)),
+		FrameCount:  uint32(frameCount),
+		StoragePath: s.videRepo.GetThumbnailSpriteFilePath(video.ID, constants.SpriteIndexName),
+	}
+
+	index := buildSpriteIndex(sprite, sheetNames, video.Length)
+
+	err = s.videRepo.UploadThumbnailSpriteFile(ctx, video.ID, constants.SpriteIndexName, "text/vtt", strings.NewReader(index))
+	if err != nil {
+		return
+	}
+
+	err = s.videRepo.SaveThumbnailSprite(ctx, sprite)
+	return
+}
+
+// Builds the WebVTT index of the sprites. Each cue covers one interval and points at the region of its frame
+// on the sheet with a media fragment. The sheets are referenced relative to the index.
+func buildSpriteIndex(sprite model.ThumbnailSprite, sheetNames []string, length uint64) string {
+	var builder strings.Builder
+	builder.WriteString("WEBVTT\n")
+
+	framesPerSheet := uint32(sprite.Columns) * uint32(sprite.Rows)
+
+	for frame := uint32(0); frame < sprite.FrameCount; frame++ {
+		start := uint64(frame) * uint64(sprite.Interval)
+		end := min(start+uint64(sprite.Interval), length)
+
+		cell := frame % framesPerSheet
+		x := (cell % uint32(sprite.Columns)) * uint32(sprite.FrameWidth)
+		y := (cell / uint32(sprite.Columns)) * uint32(sprite.FrameHeight)
+
+		fmt.Fprintf(&builder, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			formatVTTTimestamp(start), formatVTTTimestamp(end), sheetNames[frame/framesPerSheet], x, y, sprite.FrameWidth, sprite.FrameHeight)
+	}
+
+	return builder.String()
+}
+
+// Formats the seconds as a WebVTT timestamp of HH:MM:SS.mmm.
+func formatVTTTimestamp(seconds uint64) string {
+	duration := time.Duration(seconds) * time.Second
+	return fmt.Sprintf("%02d:%02d:%02d.000", int(duration.Hours()), int(duration.Minutes())%60, int(duration.Seconds())%60)
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"time"
+)
+
+// Handles the storage event of an uploaded video file. Storage webhooks are delivered at least once so the
+// event is recorded in a ledger first and a redelivered event is acknowledged without being handled again.
+// An error is only returned when a redelivery can succeed, in which case the event is removed from the ledger.
+func (s *VideoService) HandleVideoUploadedEvent(ctx context.Context, slug string, event model.StorageEvent) (duplicate bool, err error) {
+	logger := s.l.With("slug", slug).With("bucket", event.Bucket).With("sequencer", event.Sequencer)
+
+	recorded, err := s.videRepo.RecordStorageEvent(ctx, event)
+	if err != nil {
+		return
+	}
+
+	if !recorded {
+		logger.Info("Skipping redelivered storage event")
+		duplicate = true
+		return
+	}
+
+	err = s.HandleVideoUpload(ctx, slug, event.Key)
+	if err == nil {
+		return
+	}
+
+	switch err {
+	case fluxerrors.ErrVideoNotFound, fluxerrors.ErrInvalidVideoStatus, fluxerrors.ErrInvalidVideoSlug, fluxerrors.ErrMalformedStoragePath:
+		// A redelivery fails the same way so keep the event recorded.
+		logger.Info("Storage event does not apply to the video", "reason", err.Error())
+		err = nil
+		return
+	}
+
+	deleteErr := s.videRepo.DeleteStorageEvent(ctx, event)
+	if deleteErr != nil {
+		logger.Error("Failed to release the storage event for redelivery", deleteErr)
+	}
+
+	return
+}
+
+// Moves the uploaded video to processing and queues its processing. Every path which learns about a finished
+// upload goes through here so that a call which failed to queue the processing can be repeated.
+func (s *VideoService) HandleVideoUpload(ctx context.Context, slug string, storagePath string) (err error) {
+	err = s.UpdateUploadStatus(ctx, slug, model.Video{
+		StoragePath: storagePath,
+	})
+
+	// The status was updated by an earlier delivery which failed to queue the processing.
+	if err == fluxerrors.ErrInvalidVideoStatus {
+		video, getErr := s.videRepo.GetVideoBySlug(ctx, slug)
+		if getErr != nil || video.Status != model.VideoStatusProcessing {
+			return
+		}
+		err = nil
+	}
+
+	if err != nil {
+		return
+	}
+
+	return s.QueuePostUploadProcessing(ctx, slug)
+}
+
+// Handles the storage event of a removed video file. The removal is recorded on the video and a video
+// which is still processing is failed since processing cannot finish without the file.
+func (s *VideoService) HandleVideoFileRemovedEvent(ctx context.Context, slug string, event model.StorageEvent) (duplicate bool, err error) {
+	logger := s.l.With("slug", slug).With("bucket", event.Bucket).With("sequencer", event.Sequencer)
+
+	recorded, err := s.videRepo.RecordStorageEvent(ctx, event)
+	if err != nil {
+		return
+	}
+
+	if !recorded {
+		logger.Info("Skipping redelivered storage event")
+		duplicate = true
+		return
+	}
+
+	err = s.handleVideoFileRemoval(ctx, slug)
+	if err == nil {
+		return
+	}
+
+	if err == fluxerrors.ErrVideoNotFound {
+		logger.Info("Storage event does not apply to any video")
+		err = nil
+		return
+	}
+
+	deleteErr := s.videRepo.DeleteStorageEvent(ctx, event)
+	if deleteErr != nil {
+		logger.Error("Failed to release the storage event for redelivery", deleteErr)
+	}
+
+	return
+}
+
+func (s *VideoService) handleVideoFileRemoval(ctx context.Context, slug string) (err error) {
+	video, err := s.videRepo.GetVideoBySlug(ctx, slug)
+	if err != nil {
+		return
+	}
+
+	logger := s.l.With("video_id", video.ID.String())
+
+	err = s.videRepo.MarkRawFileDeleted(ctx, video.ID, time.Now())
+	if err != nil {
+		return
+	}
+
+	for _, status := range []model.VideoStatus{model.VideoStatusProcessing, model.VideoStatusProcessingDelay} {
+		var transitioned bool
+		transitioned, err = s.transitionStatus(ctx, video, status, model.VideoStatusFailed, "raw video file was deleted")
+		if err != nil {
+			return
+		}
+
+		if transitioned {
+			logger.Info("Failed processing of the video since its raw file was deleted")
+			break
+		}
+	}
+
+	logger.Info("Raw video file removal recorded")
+	return
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"fluxio-backend/pkg/constants"
+	"fluxio-backend/pkg/model"
+	"strings"
+	"time"
+)
+
+type StalledVideoSweepConfig struct {
+	ProcessingTimeout time.Duration // Videos processing without an active job for longer are failed
+}
+
+// Finds the videos which stalled during upload or processing and moves them on. Uploads are abandoned once their
+// upload URL has expired, processing which was never queued is queued and processing fails once no job is left
+// to finish it.
+func (s *VideoService) SweepStalledVideos(ctx context.Context, cfg StalledVideoSweepConfig) (err error) {
+	err = s.abortExpiredUploads(ctx)
+	if err != nil {
+		return
+	}
+
+	err = s.abandonStalledUploads(ctx)
+	if err != nil {
+		return
+	}
+
+	err = s.queueUnqueuedProcessing(ctx)
+	if err != nil {
+		return
+	}
+
+	if cfg.ProcessingTimeout <= 0 {
+		return
+	}
+
+	return s.failStalledProcessing(ctx, cfg)
+}
+
+// Aborts the uploads which were not resumed before they expired. The video stays pending so that a new upload can be started.
+func (s *VideoService) abortExpiredUploads(ctx context.Context) (err error) {
+	uploads, err := s.videRepo.ListExpiredVideoUploads(ctx, time.Now(), constants.StalledVideoSweepBatchSize)
+	if err != nil {
+		return
+	}
+
+	for _, upload := range uploads {
+		logger := s.l.With("video_upload_id", upload.ID.String())
+
+		video, videoErr := s.videRepo.GetVideoByID(ctx, upload.VideoID)
+		if videoErr != nil {
+			logger.Error("Failed to get the video of the expired upload", videoErr)
+			continue
+		}
+
+		abortErr := s.abortVideoUpload(ctx, video, upload)
+		if abortErr != nil {
+			logger.Error("Failed to abort the expired upload", abortErr)
+			continue
+		}
+
+		logger.Info("Aborted expired upload")
+	}
+
+	return
+}
+
+func (s *VideoService) abandonStalledUploads(ctx context.Context) (err error) {
+	return s.sweepVideosUpdatedBefore(ctx, []model.VideoStatus{model.VideoStatusUploadPending}, time.Now().Add(-constants.PreSignedVidUploadURLExpireTime), func(video model.Video) {
+		logger := s.l.With("video_id", video.ID.String()).With("slug", video.Slug)
+
+		// A multipart upload keeps going as long as the client requests part URLs.
+		uploading, upload, uploadErr := s.hasRecentUploadActivity(ctx, video, constants.PreSignedVidUploadURLExpireTime)
+		if uploadErr != nil || uploading {
+			return
+		}
+
+		// An imported file is uploaded by the import job, which may still be waiting for a retry.
+		importing, importErr := s.jobSvc.HasActiveJob(ctx, videoImportJobKey(video.Slug))
+		if importErr != nil || importing {
+			return
+		}
+
+		// The upload may have finished without the storage event reaching us, in which case the file is
+		// handed to processing the way the event would have.
+		uploaded, headErr := s.videRepo.UnProcessedVideoFileExists(ctx, video.Slug)
+		if headErr != nil {
+			return
+		}
+
+		if uploaded {
+			recoverErr := s.HandleVideoUpload(ctx, video.Slug, s.videRepo.GetUnProcessedVideoFilePath(video.Slug))
+			if recoverErr != nil {
+				logger.Error("Failed to process the upload whose storage event was missed", recoverErr)
+				return
+			}
+
+			logger.Info("Recovered upload whose storage event was missed")
+			return
+		}
+
+		if !strings.EqualFold(upload.ID.String(), "") {
+			abortErr := s.abortVideoUpload(ctx, video, upload)
+			if abortErr != nil {
+				logger.Error("Failed to abort the stalled multipart upload", abortErr)
+				return
+			}
+		}
+
+		transitioned, transitionErr := s.transitionStatus(ctx, video, model.VideoStatusUploadPending, model.VideoStatusAbandoned, "upload url expired before the upload completed")
+		if transitionErr != nil || !transitioned {
+			return
+		}
+
+		logger.Info("Marked stalled upload as abandoned")
+	})
+}
+
+// Queues the processing of the videos which were moved to processing by an upload whose processing failed to be
+// queued. Such a video has never had a processing job, unlike a video whose job ran out of attempts.
+func (s *VideoService) queueUnqueuedProcessing(ctx context.Context) (err error) {
+	return s.sweepVideosUpdatedBefore(ctx, []model.VideoStatus{model.VideoStatusProcessing}, time.Now().Add(-constants.UnqueuedProcessingGracePeriod), func(video model.Video) {
+		logger := s.l.With("video_id", video.ID.String()).With("slug", video.Slug)
+
+		queued, jobErr := s.jobSvc.HasJob(ctx, videoProcessingJobKey(video.Slug))
+		if jobErr != nil || queued {
+			return
+		}
+
+		queueErr := s.QueuePostUploadProcessing(ctx, video.Slug)
+		if queueErr != nil {
+			logger.Error("Failed to queue the processing of the stalled video", queueErr)
+			return
+		}
+
+		logger.Warn("Queued the processing which was not queued after the upload")
+	})
+}
+
+func (s *VideoService) failStalledProcessing(ctx context.Context, cfg StalledVideoSweepConfig) (err error) {
+	statuses := []model.VideoStatus{model.VideoStatusProcessing, model.VideoStatusProcessingDelay}
+
+	return s.sweepVideosUpdatedBefore(ctx, statuses, time.Now().Add(-cfg.ProcessingTimeout), func(video model.Video) {
+		logger := s.l.With("video_id", video.ID.String()).With("slug", video.Slug)
+
+		// A long transcode does not touch the video so only give up once no job is left to finish it.
+		active, activeErr := s.jobSvc.HasActiveJob(ctx, videoProcessingJobKey(video.Slug))
+		if activeErr != nil || active {
+			return
+		}
+
+		transitioned, transitionErr := s.transitionStatus(ctx, video, video.Status, model.VideoStatusFailed, "processing stalled without an active job")
+		if transitionErr != nil || !transitioned {
+			return
+		}
+
+		logger.Warn("Marked stalled processing as failed")
+	})
+}
+
+// Hands every video in one of the statuses which was last updated before the given time to the sweep. The videos
+// are listed a batch at a time after the last one seen, so the ones the sweep leaves as they are do not hold back
+// the ones behind them.
+func (s *VideoService) sweepVideosUpdatedBefore(ctx context.Context, statuses []model.VideoStatus, updatedBefore time.Time, sweep func(video model.Video)) (err error) {
+	var after *model.Video
+
+	for {
+		videos, listErr := s.videRepo.ListVideosUpdatedBefore(ctx, statuses, updatedBefore, after, constants.StalledVideoSweepBatchSize)
+		if listErr != nil {
+			err = listErr
+			return
+		}
+
+		for _, video := range videos {
+			sweep(video)
+		}
+
+		if len(videos) < constants.StalledVideoSweepBatchSize {
+			return
+		}
+
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		after = &videos[len(videos)-1]
+	}
+}
@@ -1 +1,312 @@
 package service
+
+import (
+	"context"
+	"fluxio-backend/pkg/common/schema"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fmt"
+	"math/rand"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	ffmpeg_go "github.com/u2takey/ffmpeg-go"
+)
+
+// Generates the thumbnails of a video at the timestamps and stores them. The best scoring frames are picked
+// when no timestamps are given, falling back to random distinct timestamps when none of the frames is usable.
+// The previous thumbnails are soft deleted once a new one is stored so a failed attempt never leaves the
+// video without thumbnails.
+func (s *VideoService) generateThumbnails(ctx context.Context, logger schema.Logger, video model.Video, downloadURL string, timestamps []uint64) (successCount int, err error) {
+	thumbnailTempDir, err := os.MkdirTemp(os.TempDir(), "fluxio-thumbnails-*")
+	if err != nil {
+		logger.Error("Failed to create temporary directory for thumbnails", err)
+		err = fluxerrors.ErrThumbnailCreationFailed
+		return
+	}
+
+	defer os.RemoveAll(thumbnailTempDir)
+
+	// Every frame is extracted once at the largest profile size and scaled down into the variants from there.
+	thumbnailWidth, thumbnailHeight := largestThumbnailProfile(s.thumbnailProfiles)
+
+	frames := make([]thumbnailFrame, 0, len(timestamps))
+	for _, timestamp := range timestamps {
+		frames = append(frames, thumbnailFrame{at: time.Duration(timestamp) * time.Second})
+	}
+
+	if len(frames) == 0 {
+		var selectErr error
+
+		frames, selectErr = s.selectThumbnailFrames(ctx, logger, video, downloadURL)
+		if selectErr != nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+				return
+			}
+
+			logger.Warn("No usable thumbnail frame found, falling back to random timestamps")
+			for _, timestamp := range s.generateDistinctTimestamps(video.Length) {
+				frames = append(frames, thumbnailFrame{at: time.Duration(timestamp) * time.Second})
+			}
+		}
+	}
+
+	createdIDs := make([]model.ThumbnailID, 0, len(frames))
+	usedTimestamps := map[uint64]bool{}
+
+	for _, frame := range frames {
+		// The stored timestamp and the file name of a thumbnail are in whole seconds.
+		timestamp := uint64(frame.at / time.Second)
+		if usedTimestamps[timestamp] {
+			continue
+		}
+		usedTimestamps[timestamp] = true
+
+		// We need to convert the timestamp to ffmpeg format of HH:MM:SS.mmm
+		timeStr := fmt.Sprintf("%02d:%02d:%02d.%03d", int(frame.at.Hours()), int(frame.at.Minutes())%60, int(frame.at.Seconds())%60, frame.at.Milliseconds()%1000)
+
+		// The thumbnail filter replaces the frame with the most representative one around it. Scored frames
+		// were already picked so they are kept as is.
+		filter := fmt.Sprintf("scale=w=%[1]d:h=%[2]d:force_original_aspect_ratio=decrease,pad=%[1]d:%[2]d:(ow-iw)/2:(oh-ih)/2", thumbnailWidth, thumbnailHeight)
+		if !frame.exact {
+			filter = "thumbnail," + filter
+		}
+
+		opPath := path.Join(thumbnailTempDir, fmt.Sprintf("%s-%s.png", video.Slug, fmt.Sprint(timestamp)))
+
+		// We pass the URL so the ffmpeg will smartly use HTTP Range requests to get the exact frame.
+		ffmpegErr := ffmpeg_go.Input(downloadURL, ffmpeg_go.KwArgs{
+			"ss":      timeStr, // The Timestamp to extract the thumbnail from
+			"y":       "",      // Overwrite the output file if exists
+			"timeout": "40",    // Timeout for whole op execution
+		}).Output(opPath, ffmpeg_go.KwArgs{
+			"vframes": 1,      // How many frames to output
+			"vf":      filter, // Scale while maintaining the aspect ratio
+		}).OverWriteOutput().Run()
+
+		// Skip the timestamp if ffmpeg fails to generate the thumbnail.
+		if ffmpegErr != nil {
+			logger.With("timestamp", timestamp).Warn("Failed to extract the thumbnail frame")
+			continue
+		}
+
+		variants := s.createThumbnailVariants(ctx, logger.With("timestamp", timestamp), video.ID, timestamp, opPath, thumbnailTempDir)
+		if len(variants) == 0 {
+			continue
+		}
+
+		primary := primaryThumbnailVariant(variants)
+
+		// Placeholders are nice to have so the thumbnail is stored without them when they fail.
+		blurHash, lqip, placeholderErr := createThumbnailPlaceholders(opPath)
+		if placeholderErr != nil {
+			logger.With("timestamp", timestamp).Warn("Failed to create the thumbnail placeholders")
+		}
+
+		thumbnail := model.Thumbnail{
+			VideoID:     video.ID,
+			Width:       primary.Width,
+			Height:      primary.Height,
+			Size:        primary.Size,
+			Format:      primary.Format,
+			StoragePath: primary.StoragePath,
+			TimeStamp:   timestamp,
+			BlurHash:    blurHash,
+			LQIP:        lqip,
+			IsDefault:   successCount == 0, // Set the first stored thumbnail as default
+			Variants:    variants,
+		}
+
+		id, createErr := s.videRepo.CreateThumbnail(ctx, thumbnail)
+		if createErr != nil {
+			continue
+		}
+
+		createdIDs = append(createdIDs, id)
+		successCount++
+
+		thumbnail.ID = id
+		thumbnail.URL = s.videRepo.GetThumbnailFileURL(thumbnail.StoragePath)
+		for idx := range thumbnail.Variants {
+			thumbnail.Variants[idx].URL = s.videRepo.GetThumbnailFileURL(thumbnail.Variants[idx].StoragePath)
+		}
+		thumbnail.Sources = model.NewThumbnailSources(thumbnail.Variants)
+
+		s.publishThumbnail(video, thumbnail)
+	}
+
+	if successCount == 0 {
+		err = fluxerrors.ErrThumbnailCreationFailed
+		return
+	}
+
+	err = s.videRepo.DeleteVideoThumbnails(ctx, video.ID, createdIDs)
+	return
+}
+
+// Checks the timestamps requested for thumbnails against the video length.
+func validateThumbnailTimestamps(timestamps []uint64, videoLength uint64) (err error) {
+	if len(timestamps) > constants.TotalThumbnailCount {
+		return fluxerrors.ErrInvalidThumbnailTimestamp
+	}
+
+	seen := map[uint64]bool{}
+	for _, timestamp := range timestamps {
+		if timestamp > videoLength || seen[timestamp] {
+			return fluxerrors.ErrInvalidThumbnailTimestamp
+		}
+		seen[timestamp] = true
+	}
+
+	return
+}
+
+// Queues the regeneration of the thumbnails of a completed video. Only the owner can request it and the
+// number of regenerations is limited.
+func (s *VideoService) RequestThumbnailRegeneration(ctx context.Context, slug string, user model.User, timestamps []uint64) (err error) {
+	logger := s.l.With("slug", slug).With("user_id", user.ID.String())
+
+	if strings.EqualFold(slug, "") {
+		err = fluxerrors.ErrInvalidVideoSlug
+		return
+	}
+
+	video, err := s.videRepo.GetVideoBySlug(ctx, slug)
+	if err != nil {
+		if err == fluxerrors.ErrVideoNotFound {
+			return
+		}
+		logger.Error("Failed to get video by slug", err)
+		err = fluxerrors.ErrUnknown
+		return
+	}
+
+	if !strings.EqualFold(video.UserID.String(), user.ID.String()) {
+		err = fluxerrors.ErrVideoNotFound
+		return
+	}
+
+	logger = logger.With("video_id", video.ID.String())
+
+	if video.Status != model.VideoStatusCompleted {
+		err = fluxerrors.ErrInvalidVideoStatus
+		return
+	}
+
+	// Thumbnails are extracted from the uploaded file.
+	if video.RawFileDeletedAt != nil {
+		err = fluxerrors.ErrVideoRawFileMissing
+		return
+	}
+
+	if video.ThumbnailRetryCount >= constants.MaxVideoThumbnailRegenerateRetryCount {
+		err = fluxerrors.ErrThumbnailRetryExceeded
+		logger.Info("Thumbnail regeneration limit reached")
+		return
+	}
+
+	err = validateThumbnailTimestamps(timestamps, video.Length)
+	if err != nil {
+		return
+	}
+
+	_, err = s.jobSvc.Enqueue(ctx, model.JobTypeThumbnailRegeneration, model.ThumbnailRegenerationJobPayload{
+		Slug:       slug,
+		Timestamps: timestamps,
+	}, thumbnailRegenerationJobKey(slug), constants.MaxThumbnailRegenerationJobAttempts)
+
+	if err != nil {
+		if err == fluxerrors.ErrJobAlreadyQueued {
+			err = fluxerrors.ErrThumbnailAlreadyQueued
+			return
+		}
+
+		logger.Error("Failed to queue the thumbnail regeneration", err)
+		return
+	}
+
+	err = s.videRepo.IncrementThumbnailRetryCount(ctx, video.ID)
+	if err != nil {
+		logger.Error("Failed to increment the thumbnail retry count", err)
+		err = nil // The job is already queued so do not fail the request.
+	}
+
+	logger.Info("Thumbnail regeneration queued")
+	return
+}
+
+// Runs a queued thumbnail regeneration job.
+func (s *VideoService) HandleThumbnailRegenerationJob(ctx context.Context, job model.Job) (err error) {
+	payload := model.ThumbnailRegenerationJobPayload{}
+
+	err = DecodeJobPayload(job, &payload)
+	if err != nil {
+		return
+	}
+
+	logger := s.l.With("slug", payload.Slug).With("job_id", job.ID.String())
+
+	video, err := s.videRepo.GetVideoBySlug(ctx, payload.Slug)
+	if err != nil {
+		if err == fluxerrors.ErrVideoNotFound {
+			logger.Info("Skipping thumbnail regeneration of a missing video")
+			err = nil
+		}
+		return
+	}
+
+	if video.Status != model.VideoStatusCompleted {
+		logger.Info("Skipping thumbnail regeneration", "status", video.Status.String())
+		return
+	}
+
+	downloadURL, err := s.videRepo.GetUnProcessedVideoDownloadURL(ctx, video.Slug)
+	if err != nil {
+		return
+	}
+
+	successCount, err := s.generateThumbnails(ctx, logger, video, downloadURL.String(), payload.Timestamps)
+	if err != nil {
+		logger.Error("Thumbnail regeneration failed", err)
+		return
+	}
+
+	logger.Info("Thumbnails regenerated", "thumbnails_created", successCount)
+	return
+}
+
+func (v *VideoService) generateDistinctTimestamps(videoDuration uint64) []uint64 {
+	if videoDuration < 4 {
+		if videoDuration < 2 {
+			return []uint64{videoDuration}
+		}
+		step := videoDuration / 3
+		return []uint64{step, 2 * step, videoDuration}
+	}
+
+	// Divide video into 3 segments and pick a random time from each segment
+	segmentDuration := videoDuration / 4 // Use 4 segments to avoid the very beginning and end
+
+	timestamps := make([]uint64, 3)
+
+	// First thumbnail from first quarter (excluding first 5% of video)
+	minTime1 := uint64(float64(videoDuration) * 0.05)
+	maxTime1 := segmentDuration
+	timestamps[0] = minTime1 + uint64(rand.Int63n(int64(maxTime1-minTime1)))
+
+	// Second thumbnail from middle section
+	minTime2 := segmentDuration * 1
+	maxTime2 := segmentDuration * 2
+	timestamps[1] = minTime2 + uint64(rand.Int63n(int64(maxTime2-minTime2)))
+
+	// Third thumbnail from later section (avoiding last 5% of video)
+	minTime3 := segmentDuration * 2
+	maxTime3 := uint64(float64(videoDuration) * 0.95)
+	timestamps[2] = minTime3 + uint64(rand.Int63n(int64(maxTime3-minTime3)))
+
+	return timestamps
+}
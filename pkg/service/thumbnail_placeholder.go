@@ -0,0 +1,52 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fluxio-backend/pkg/constants"
+	"fluxio-backend/pkg/utils"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+)
+
+// Computes the placeholders of an extracted thumbnail frame: a BlurHash and a tiny JPEG as a data URI.
+func createThumbnailPlaceholders(framePath string) (blurHash string, lqip string, err error) {
+	file, err := os.Open(framePath)
+	if err != nil {
+		return
+	}
+
+	defer file.Close()
+
+	frame, err := png.Decode(file)
+	if err != nil {
+		return
+	}
+
+	blurHash, err = utils.EncodeBlurHash(downscaleToWidth(frame, constants.BlurHashSampleWidth), constants.BlurHashXComponents, constants.BlurHashYComponents)
+	if err != nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	err = jpeg.Encode(&buf, downscaleToWidth(frame, constants.ThumbnailLQIPWidth), &jpeg.Options{Quality: constants.ThumbnailLQIPJPEGQuality})
+	if err != nil {
+		return
+	}
+
+	lqip = "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+	return
+}
+
+// Scales the image down to the width keeping the aspect ratio.
+func downscaleToWidth(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() <= width {
+		return img
+	}
+
+	height := max(bounds.Dy()*width/bounds.Dx(), 1)
+	return utils.DownscaleImage(img, width, height)
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fluxio-backend/pkg/common/schema"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ffmpeg_go "github.com/u2takey/ffmpeg-go"
+)
+
+// A frame the thumbnails are extracted from. Exact frames are extracted as is while the others are
+// replaced with the most representative frame around the position.
+type thumbnailFrame struct {
+	at    time.Duration
+	exact bool
+}
+
+// Candidate frame found by the scene detection along with its quality metrics.
+type thumbnailCandidate struct {
+	at         time.Duration
+	brightness float64 // Mean luma between 0 and 255
+	contrast   float64 // Standard deviation of the luma
+	entropy    float64 // Shannon entropy of the luma histogram in bits
+	sharpness  float64 // Variance of the Laplacian of the luma
+	score      float64
+}
+
+// Reports whether the frame is black, blown out or nearly uniform and so not usable as a thumbnail.
+func (c thumbnailCandidate) isRejected() bool {
+	return c.brightness < constants.ThumbnailMinBrightness ||
+		c.brightness > constants.ThumbnailMaxBrightness ||
+		c.contrast < constants.ThumbnailMinContrast
+}
+
+// Picks the best frames of the video for the thumbnails. Candidate frames are extracted at the scene changes,
+// and at a regular interval for videos without them, scored on their exposure, detail and sharpness and the
+// best ones are returned with the highest score first.
+func (s *VideoService) selectThumbnailFrames(ctx context.Context, logger schema.Logger, video model.Video, downloadURL string) (frames []thumbnailFrame, err error) {
+	if video.Length == 0 {
+		err = fluxerrors.ErrThumbnailCreationFailed
+		return
+	}
+
+	candidateDir, err := os.MkdirTemp(os.TempDir(), "fluxio-thumbnail-candidates-*")
+	if err != nil {
+		logger.Error("Failed to create temporary directory for thumbnail candidates", err)
+		err = fluxerrors.ErrThumbnailCreationFailed
+		return
+	}
+
+	defer os.RemoveAll(candidateDir)
+
+	length := float64(video.Length)
+	minGap := length / constants.MaxThumbnailCandidateCount // Keeps the number of scene changes picked bounded
+	maxGap := length / constants.MinThumbnailCandidateCount // Fills videos without scene changes
+	metadataPath := path.Join(candidateDir, "candidates.txt")
+
+	// The metadata filter prints the position of every selected frame in the order the frames are written.
+	filter := fmt.Sprintf("select='isnan(prev_selected_t)+gt(scene,%.2f)*gte(t-prev_selected_t,%.3f)+gte(t-prev_selected_t,%.3f)',scale=%d:-2,metadata=mode=print:file=%s",
+		constants.ThumbnailSceneThreshold, minGap, maxGap, constants.ThumbnailAnalysisWidth, metadataPath)
+
+	progress := s.newProgressWriter(ctx, logger, video, "thumbnails", "scene_detect")
+
+	err = ffmpeg_go.OutputContext(ctx, []*ffmpeg_go.Stream{ffmpeg_go.Input(downloadURL)}, path.Join(candidateDir, "candidate-%04d.png"), ffmpeg_go.KwArgs{
+		"vf":    filter,
+		"vsync": "vfr", // Only write the selected frames
+		"an":    "",
+	}).GlobalArgs(ffmpegProgressArgs...).OverWriteOutput().WithOutput(progress).Run()
+
+	if err != nil {
+		logger.Error("Failed to extract the thumbnail candidates", err)
+		err = fluxerrors.ErrThumbnailCreationFailed
+		return
+	}
+
+	positions, err := readCandidatePositions(metadataPath)
+	if err != nil {
+		logger.Error("Failed to read the thumbnail candidate positions", err)
+		err = fluxerrors.ErrThumbnailCreationFailed
+		return
+	}
+
+	files, err := filepath.Glob(path.Join(candidateDir, "candidate-*.png"))
+	if err != nil {
+		err = fluxerrors.ErrThumbnailCreationFailed
+		return
+	}
+
+	sort.Strings(files)
+
+	candidates := make([]thumbnailCandidate, 0, len(files))
+	for idx, file := range files {
+		if idx >= len(positions) {
+			break
+		}
+
+		candidate, scoreErr := scoreCandidateFile(file)
+		if scoreErr != nil {
+			logger.With("file", filepath.Base(file)).Warn("Failed to score the thumbnail candidate")
+			continue
+		}
+
+		candidate.at = positions[idx]
+		if candidate.isRejected() {
+			continue
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	frames = pickThumbnailFrames(candidates, constants.TotalThumbnailCount, time.Duration(length/(constants.TotalThumbnailCount*2)*float64(time.Second)))
+	if len(frames) == 0 {
+		err = fluxerrors.ErrThumbnailCreationFailed
+		return
+	}
+
+	logger.Info("Thumbnail frames selected", "candidate_count", len(files), "usable_count", len(candidates))
+	return
+}
+
+// Reads the positions of the selected frames from the output of the metadata filter.
+func readCandidatePositions(metadataPath string) (positions []time.Duration, err error) {
+	file, err := os.Open(metadataPath)
+	if err != nil {
+		return
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "frame:") {
+			continue
+		}
+
+		for _, field := range strings.Fields(line) {
+			value, ok := strings.CutPrefix(field, "pts_time:")
+			if !ok {
+				continue
+			}
+
+			seconds, parseErr := strconv.ParseFloat(value, 64)
+			if parseErr != nil {
+				return nil, parseErr
+			}
+
+			positions = append(positions, time.Duration(seconds*float64(time.Second)))
+		}
+	}
+
+	err = scanner.Err()
+	return
+}
+
+func scoreCandidateFile(filePath string) (candidate thumbnailCandidate, err error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return
+	}
+
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		return
+	}
+
+	candidate = scoreFrame(img)
+	return
+}
+
+// Measures the exposure, detail and sharpness of a frame and combines them into a score between 0 and 1.
+func scoreFrame(img image.Image) (candidate thumbnailCandidate) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 3 || height < 3 {
+		return
+	}
+
+	luma := make([]float64, width*height)
+	histogram := [256]int{}
+	var sum float64
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+
+			// BT.601 luma of the 16 bit channels scaled down to 8 bits.
+			value := (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 257
+			luma[y*width+x] = value
+			histogram[min(int(value), 255)]++
+			sum += value
+		}
+	}
+
+	pixels := float64(width * height)
+	candidate.brightness = sum / pixels
+
+	var variance float64
+	for _, value := range luma {
+		variance += (value - candidate.brightness) * (value - candidate.brightness)
+	}
+	candidate.contrast = math.Sqrt(variance / pixels)
+
+	for _, count := range histogram {
+		if count == 0 {
+			continue
+		}
+
+		p := float64(count) / pixels
+		candidate.entropy -= p * math.Log2(p)
+	}
+
+	// Variance of the 4-neighbour Laplacian. Blurry frames have few edges and so a low variance.
+	var lapSum, lapSquares float64
+	for y := 1; y < height-1; y++ {
+		for x := 1; x < width-1; x++ {
+			idx := y*width + x
+			lap := luma[idx-width] + luma[idx+width] + luma[idx-1] + luma[idx+1] - 4*luma[idx]
+			lapSum += lap
+			lapSquares += lap * lap
+		}
+	}
+
+	inner := float64((width - 2) * (height - 2))
+	lapMean := lapSum / inner
+	candidate.sharpness = lapSquares/inner - lapMean*lapMean
+
+	exposure := 1 - math.Abs(candidate.brightness-128)/128
+
+	candidate.score = 0.4*math.Min(candidate.sharpness/constants.ThumbnailSharpnessNorm, 1) +
+		0.25*candidate.entropy/8 +
+		0.2*math.Min(candidate.contrast/64, 1) +
+		0.15*exposure
+
+	return
+}
+
+// Returns up to count frames with the highest score which are at least minGap apart from each other.
+func pickThumbnailFrames(candidates []thumbnailCandidate, count int, minGap time.Duration) (frames []thumbnailFrame) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	for _, candidate := range candidates {
+		if len(frames) >= count {
+			break
+		}
+
+		tooClose := false
+		for _, frame := range frames {
+			gap := candidate.at - frame.at
+			if gap < minGap && gap > -minGap {
+				tooClose = true
+				break
+			}
+		}
+
+		if tooClose {
+			continue
+		}
+
+		frames = append(frames, thumbnailFrame{at: candidate.at, exact: true})
+	}
+
+	return
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"fluxio-backend/pkg/constants"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+// Returns a frame whose luma at every position is given by the function.
+func testFrame(luma func(x, y int) uint8) image.Image {
+	img := image.NewGray(image.Rect(0, 0, 64, 36))
+	for y := 0; y < 36; y++ {
+		for x := 0; x < 64; x++ {
+			img.SetGray(x, y, color.Gray{Y: luma(x, y)})
+		}
+	}
+
+	return img
+}
+
+func solidFrame(value uint8) image.Image {
+	return testFrame(func(x, y int) uint8 { return value })
+}
+
+// Alternates between the dark and the light value at every pixel.
+func checkerboardFrame(dark uint8, light uint8) image.Image {
+	return testFrame(func(x, y int) uint8 {
+		if (x+y)%2 == 0 {
+			return dark
+		}
+		return light
+	})
+}
+
+// Has the same values as the checkerboard but a single edge between them.
+func splitFrame(dark uint8, light uint8) image.Image {
+	return testFrame(func(x, y int) uint8 {
+		if x < 32 {
+			return dark
+		}
+		return light
+	})
+}
+
+func TestScoreFrameRejectsUnusableFrames(t *testing.T) {
+	tests := []struct {
+		name         string
+		frame        image.Image
+		wantRejected bool
+	}{
+		{"black", solidFrame(5), true},
+		{"blown out", solidFrame(250), true},
+		{"uniform grey", solidFrame(128), true},
+		{"low contrast", checkerboardFrame(124, 132), true},
+		{"dark with detail", checkerboardFrame(10, 60), false},
+		{"detailed", checkerboardFrame(64, 192), false},
+		{"soft", splitFrame(64, 192), false},
+		{"too small to measure", image.NewGray(image.Rect(0, 0, 2, 2)), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidate := scoreFrame(tt.frame)
+			if rejected := candidate.isRejected(); rejected != tt.wantRejected {
+				t.Errorf("got rejected %v, want %v for brightness %.1f and contrast %.1f", rejected, tt.wantRejected, candidate.brightness, candidate.contrast)
+			}
+
+			if candidate.score < 0 || candidate.score > 1 {
+				t.Errorf("got score %f outside of 0 and 1", candidate.score)
+			}
+		})
+	}
+}
+
+func TestScoreFramePrefersSharpFrames(t *testing.T) {
+	sharp := scoreFrame(checkerboardFrame(96, 160))
+	blurry := scoreFrame(splitFrame(96, 160))
+
+	// Both frames have the same histogram so only the sharpness tells them apart.
+	if sharp.brightness != blurry.brightness || sharp.contrast != blurry.contrast || sharp.entropy != blurry.entropy {
+		t.Fatalf("got different histograms %+v and %+v", sharp, blurry)
+	}
+
+	if sharp.sharpness < constants.ThumbnailSharpnessNorm {
+		t.Errorf("got sharpness %f for the checkerboard, want at least %f", sharp.sharpness, constants.ThumbnailSharpnessNorm)
+	}
+
+	if blurry.sharpness >= constants.ThumbnailSharpnessNorm || blurry.score >= sharp.score {
+		t.Errorf("got blurry frame %+v scored at least as high as the sharp one %+v", blurry, sharp)
+	}
+
+	// A well exposed frame beats a dark one with the same detail.
+	dark := scoreFrame(checkerboardFrame(10, 60))
+	exposed := scoreFrame(checkerboardFrame(103, 153))
+	if dark.score >= exposed.score {
+		t.Errorf("got dark frame score %f, want it below %f", dark.score, exposed.score)
+	}
+}
+
+func TestPickThumbnailFrames(t *testing.T) {
+	candidate := func(seconds int, score float64) thumbnailCandidate {
+		return thumbnailCandidate{at: time.Duration(seconds) * time.Second, score: score}
+	}
+
+	tests := []struct {
+		name       string
+		candidates []thumbnailCandidate
+		count      int
+		minGap     time.Duration
+		want       []time.Duration
+	}{
+		{
+			name:       "orders the frames by score",
+			candidates: []thumbnailCandidate{candidate(10, 0.2), candidate(40, 0.9), candidate(70, 0.5)},
+			count:      3,
+			minGap:     10 * time.Second,
+			want:       []time.Duration{40 * time.Second, 70 * time.Second, 10 * time.Second},
+		},
+		{
+			name:       "stops at the count",
+			candidates: []thumbnailCandidate{candidate(10, 0.2), candidate(40, 0.9), candidate(70, 0.5), candidate(100, 0.7)},
+			count:      2,
+			minGap:     10 * time.Second,
+			want:       []time.Duration{40 * time.Second, 100 * time.Second},
+		},
+		{
+			name:       "skips frames close to a better one",
+			candidates: []thumbnailCandidate{candidate(40, 0.9), candidate(45, 0.8), candidate(35, 0.85), candidate(60, 0.3)},
+			count:      3,
+			minGap:     10 * time.Second,
+			want:       []time.Duration{40 * time.Second, 60 * time.Second},
+		},
+		{
+			name:       "keeps frames exactly the gap apart",
+			candidates: []thumbnailCandidate{candidate(40, 0.9), candidate(50, 0.8), candidate(30, 0.7)},
+			count:      3,
+			minGap:     10 * time.Second,
+			want:       []time.Duration{40 * time.Second, 50 * time.Second, 30 * time.Second},
+		},
+		{
+			name:       "keeps the earlier of equally scored frames",
+			candidates: []thumbnailCandidate{candidate(20, 0.5), candidate(25, 0.5)},
+			count:      3,
+			minGap:     10 * time.Second,
+			want:       []time.Duration{20 * time.Second},
+		},
+		{
+			name:       "returns nothing without candidates",
+			candidates: nil,
+			count:      3,
+			minGap:     10 * time.Second,
+			want:       nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames := pickThumbnailFrames(tt.candidates, tt.count, tt.minGap)
+			if len(frames) != len(tt.want) {
+				t.Fatalf("got frames %v, want at %v", frames, tt.want)
+			}
+
+			for i, frame := range frames {
+				if frame.at != tt.want[i] || !frame.exact {
+					t.Errorf("got frame %d %+v, want an exact frame at %v", i, frame, tt.want[i])
+				}
+			}
+		})
+	}
+}
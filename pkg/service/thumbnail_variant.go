@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"fluxio-backend/pkg/common/schema"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	ffmpeg_go "github.com/u2takey/ffmpeg-go"
+)
+
+// Encoder arguments of the supported thumbnail formats.
+var thumbnailEncoderArgs = map[string]ffmpeg_go.KwArgs{
+	"jpg": {
+		"q:v": 3,
+	},
+	"webp": {
+		"c:v":     "libwebp",
+		"quality": 80,
+	},
+	"avif": {
+		"c:v":           "libaom-av1",
+		"still-picture": 1,
+		"crf":           32,
+		"cpu-used":      6,
+		"pix_fmt":       "yuv420p",
+	},
+}
+
+// Builds the thumbnail profiles out of comma separated widths and formats. Every width is stored in every
+// format and the height keeps the 16:9 box the frames are padded into.
+func ParseThumbnailProfiles(widths string, formats string) (profiles []model.ThumbnailProfile, err error) {
+	parsedFormats := []string{}
+	for _, format := range strings.Split(formats, ",") {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if strings.EqualFold(format, "") {
+			continue
+		}
+
+		if !model.IsAcceptableThumbnailFormat(format) {
+			err = fmt.Errorf("%w: unsupported format %s", fluxerrors.ErrInvalidThumbnailProfile, format)
+			return
+		}
+
+		parsedFormats = append(parsedFormats, format)
+	}
+
+	seen := map[model.ThumbnailProfile]bool{}
+
+	for _, rawWidth := range strings.Split(widths, ",") {
+		rawWidth = strings.TrimSpace(rawWidth)
+		if strings.EqualFold(rawWidth, "") {
+			continue
+		}
+
+		width, parseErr := strconv.ParseUint(rawWidth, 10, 16)
+		if parseErr != nil || width < constants.MinThumbnailWidth || width > constants.MaxThumbnailWidth {
+			err = fmt.Errorf("%w: invalid width %s", fluxerrors.ErrInvalidThumbnailProfile, rawWidth)
+			return
+		}
+
+		// Both the dimensions are kept even as required by most encoders.
+		// The height is computed before the conversion since width*9 does not fit into an uint16.
+		width -= width % 2
+		height := width * 9 / 16
+		height -= height % 2
+
+		profileWidth, profileHeight := uint16(width), uint16(height)
+
+		for _, format := range parsedFormats {
+			profile := model.ThumbnailProfile{Width: profileWidth, Height: profileHeight, Format: format}
+			if seen[profile] {
+				continue
+			}
+
+			seen[profile] = true
+			profiles = append(profiles, profile)
+		}
+	}
+
+	if len(profiles) == 0 {
+		err = fluxerrors.ErrInvalidThumbnailProfile
+		return
+	}
+
+	sort.SliceStable(profiles, func(i, j int) bool {
+		return profiles[i].Width < profiles[j].Width
+	})
+
+	return
+}
+
+// Returns the dimensions of the largest profile.
+func largestThumbnailProfile(profiles []model.ThumbnailProfile) (width int, height int) {
+	for _, profile := range profiles {
+		if int(profile.Width) > width {
+			width = int(profile.Width)
+			height = int(profile.Height)
+		}
+	}
+
+	return
+}
+
+// Encodes the extracted frame into every thumbnail profile and uploads the variants. A variant which fails is
+// left out so that a missing encoder does not cost the whole thumbnail.
+func (s *VideoService) createThumbnailVariants(ctx context.Context, logger schema.Logger, videoID model.VideoID, timestamp uint64, framePath string, workDir string) (variants []model.ThumbnailVariant) {
+	for _, profile := range s.thumbnailProfiles {
+		variantLogger := logger.With("width", profile.Width).With("format", profile.Format)
+
+		outputArgs := ffmpeg_go.KwArgs{
+			"vframes": 1,
+			"vf":      fmt.Sprintf("scale=%d:%d", profile.Width, profile.Height),
+		}
+
+		for key, value := range thumbnailEncoderArgs[profile.Format] {
+			outputArgs[key] = value
+		}
+
+		opPath := path.Join(workDir, fmt.Sprintf("%d-%d.%s", timestamp, profile.Width, profile.Format))
+
+		err := ffmpeg_go.OutputContext(ctx, []*ffmpeg_go.Stream{ffmpeg_go.Input(framePath)}, opPath, outputArgs).OverWriteOutput().Run()
+		if err != nil {
+			variantLogger.Warn("Failed to encode the thumbnail variant")
+			continue
+		}
+
+		fileStat, err := os.Stat(opPath)
+		if err != nil {
+			continue
+		}
+
+		err = uploadLocalFile(opPath, func(file io.Reader) error {
+			return s.videRepo.UploadThumbnailVariantFile(ctx, videoID, timestamp, profile.Width, profile.Format, file)
+		})
+		if err != nil {
+			variantLogger.Warn("Failed to upload the thumbnail variant")
+			continue
+		}
+
+		variants = append(variants, model.ThumbnailVariant{
+			Width:       profile.Width,
+			Height:      profile.Height,
+			Format:      profile.Format,
+			Size:        uint32(fileStat.Size() / 1024), // Size in KB
+			StoragePath: s.videRepo.GetThumbnailVariantFilePath(videoID, timestamp, profile.Width, profile.Format),
+		})
+	}
+
+	return
+}
+
+// Returns the variant which describes the logical thumbnail. The largest JPEG is preferred since every
+// client can show it.
+func primaryThumbnailVariant(variants []model.ThumbnailVariant) (primary model.ThumbnailVariant) {
+	for _, variant := range variants {
+		isJPEG := variant.Format == "jpg"
+		primaryIsJPEG := primary.Format == "jpg"
+
+		if (isJPEG && !primaryIsJPEG) || (isJPEG == primaryIsJPEG && variant.Width > primary.Width) {
+			primary = variant
+		}
+	}
+
+	return
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"reflect"
+	"testing"
+)
+
+func TestParseThumbnailProfiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		widths  string
+		formats string
+		want    []model.ThumbnailProfile
+		wantErr error
+	}{
+		{
+			name:    "builds every width in every format",
+			widths:  "640, 320",
+			formats: "jpg,WEBP",
+			want: []model.ThumbnailProfile{
+				{Width: 320, Height: 180, Format: "jpg"},
+				{Width: 320, Height: 180, Format: "webp"},
+				{Width: 640, Height: 360, Format: "jpg"},
+				{Width: 640, Height: 360, Format: "webp"},
+			},
+		},
+		{
+			name:    "keeps the dimensions even",
+			widths:  "101",
+			formats: "jpg",
+			want:    []model.ThumbnailProfile{{Width: 100, Height: 56, Format: "jpg"}},
+		},
+		{
+			name:    "skips duplicated widths",
+			widths:  "320,321,,320",
+			formats: "jpg",
+			want:    []model.ThumbnailProfile{{Width: 320, Height: 180, Format: "jpg"}},
+		},
+		{
+			name:    "computes the height of the widest profile",
+			widths:  "3840",
+			formats: "jpg",
+			want:    []model.ThumbnailProfile{{Width: 3840, Height: 2160, Format: "jpg"}},
+		},
+		{
+			name:    "rejects a width above the maximum",
+			widths:  "8000",
+			formats: "jpg",
+			wantErr: fluxerrors.ErrInvalidThumbnailProfile,
+		},
+		{
+			name:    "rejects a width below the minimum",
+			widths:  "8",
+			formats: "jpg",
+			wantErr: fluxerrors.ErrInvalidThumbnailProfile,
+		},
+		{
+			name:    "rejects a width which is not a number",
+			widths:  "wide",
+			formats: "jpg",
+			wantErr: fluxerrors.ErrInvalidThumbnailProfile,
+		},
+		{
+			name:    "rejects an unsupported format",
+			widths:  "320",
+			formats: "gif",
+			wantErr: fluxerrors.ErrInvalidThumbnailProfile,
+		},
+		{
+			name:    "rejects an empty configuration",
+			widths:  " , ",
+			formats: "jpg",
+			wantErr: fluxerrors.ErrInvalidThumbnailProfile,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profiles, err := ParseThumbnailProfiles(tt.widths, tt.formats)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(profiles, tt.want) {
+				t.Errorf("got profiles %v, want %v", profiles, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"fluxio-backend/pkg/common/schema"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	ffmpeg_go "github.com/u2takey/ffmpeg-go"
+)
+
+// The complete adaptive bitrate ladder. Renditions larger than the source are dropped before transcoding.
+var renditionLadder = []model.VideoRendition{
+	{Name: "1080p", Width: 1920, Height: 1080, VideoBitrate: 5000, AudioBitrate: 192},
+	{Name: "720p", Width: 1280, Height: 720, VideoBitrate: 2800, AudioBitrate: 128},
+	{Name: "480p", Width: 854, Height: 480, VideoBitrate: 1400, AudioBitrate: 128},
+	{Name: "360p", Width: 640, Height: 360, VideoBitrate: 800, AudioBitrate: 96},
+}
+
+// Content types of the files produced by the packager.
+var packagedContentTypes = map[string]string{
+	".m3u8": "application/vnd.apple.mpegurl",
+	".mpd":  "application/dash+xml",
+	".m4s":  "video/iso.segment",
+	".mp4":  "video/mp4",
+}
+
+// Builds the renditions for a source video. The ladder is capped at the source dimensions and
+// the output dimensions keep the source aspect ratio.
+func (s *VideoService) buildRenditionLadder(srcWidth uint32, srcHeight uint32) (renditions []model.VideoRendition) {
+	if srcWidth == 0 || srcHeight == 0 {
+		return
+	}
+
+	// The ladder names refer to the short edge so portrait videos are treated the same as landscape ones.
+	shortEdge := min(srcWidth, srcHeight)
+
+	for _, rendition := range renditionLadder {
+		if rendition.Height > shortEdge {
+			continue
+		}
+
+		rendition.Width, rendition.Height = scaleToShortEdge(srcWidth, srcHeight, rendition.Height)
+		renditions = append(renditions, rendition)
+	}
+
+	// The source is smaller than the smallest rung so serve it at its own size.
+	if len(renditions) == 0 {
+		smallest := renditionLadder[len(renditionLadder)-1]
+		smallest.Name = fmt.Sprintf("%dp", shortEdge)
+		smallest.Width, smallest.Height = scaleToShortEdge(srcWidth, srcHeight, shortEdge)
+		renditions = append(renditions, smallest)
+	}
+
+	return
+}
+
+// Scales the dimensions so that the short edge matches the target while keeping the aspect ratio.
+// Both the dimensions are rounded to even values as required by most encoders.
+func scaleToShortEdge(width uint32, height uint32, target uint32) (scaledWidth uint32, scaledHeight uint32) {
+	if width >= height {
+		scaledHeight = target
+		scaledWidth = uint32(float64(width) * float64(target) / float64(height))
+	} else {
+		scaledWidth = target
+		scaledHeight = uint32(float64(height) * float64(target) / float64(width))
+	}
+
+	scaledWidth = max(scaledWidth-scaledWidth%2, 2)
+	scaledHeight = max(scaledHeight-scaledHeight%2, 2)
+	return
+}
+
+// Transcodes the source video into the rendition ladder, packages the renditions as CMAF and uploads
+// the HLS and DASH output to the public bucket.
+func (s *VideoService) transcodeAndPackage(ctx context.Context, logger schema.Logger, sourceURL string, video model.Video, renditions []model.VideoRendition) (manifests []model.VideoManifest, err error) {
+	if len(renditions) == 0 {
+		err = fluxerrors.ErrVideoTranscodingFailed
+		logger.Error("No renditions available to transcode", err)
+		return
+	}
+
+	workDir, err := os.MkdirTemp(os.TempDir(), "fluxio-transcode-*")
+	if err != nil {
+		logger.Error("Failed to create temporary directory for transcoding", err)
+		err = fluxerrors.ErrVideoTranscodingFailed
+		return
+	}
+
+	defer os.RemoveAll(workDir)
+
+	renditionFiles, err := s.transcodeRenditions(ctx, logger, sourceURL, video, renditions, workDir)
+	if err != nil {
+		return
+	}
+
+	packageDir := path.Join(workDir, constants.StreamDirectoryName)
+	err = s.packageRenditions(ctx, logger, video, renditionFiles, packageDir)
+	if err != nil {
+		return
+	}
+
+	logger.Info("Uploading packaged files")
+	err = s.uploadPackagedFiles(ctx, video.Slug, packageDir)
+	if err != nil {
+		logger.Error("Failed to upload packaged files", err)
+		err = fluxerrors.ErrVideoFileUploadFailed
+		return
+	}
+
+	manifests = []model.VideoManifest{
+		{
+			Format:      model.PackagingFormatHLS,
+			StoragePath: s.videRepo.GetPublicVideoFilePath(video.Slug, path.Join(constants.StreamDirectoryName, constants.HLSMasterPlaylistName)),
+		},
+		{
+			Format:      model.PackagingFormatDASH,
+			StoragePath: s.videRepo.GetPublicVideoFilePath(video.Slug, path.Join(constants.StreamDirectoryName, constants.DASHManifestName)),
+		},
+	}
+
+	return
+}
+
+// Encodes every rendition once into an intermediate MP4 file. The audio is only encoded with the first
+// rendition since all the renditions share the same audio track after packaging.
+func (s *VideoService) transcodeRenditions(ctx context.Context, logger schema.Logger, sourceURL string, video model.Video, renditions []model.VideoRendition, workDir string) (files []string, err error) {
+	for idx, rendition := range renditions {
+		renditionLogger := logger.With("rendition", rendition.Name)
+		renditionLogger.Info("Transcoding rendition")
+
+		opPath := path.Join(workDir, fmt.Sprintf("%s.mp4", rendition.Name))
+
+		outputArgs := ffmpeg_go.KwArgs{
+			"vf":               fmt.Sprintf("scale=%d:%d", rendition.Width, rendition.Height),
+			"c:v":              "libx264",
+			"preset":           "veryfast",
+			"profile:v":        "main",
+			"b:v":              fmt.Sprintf("%dk", rendition.VideoBitrate),
+			"maxrate":          fmt.Sprintf("%dk", rendition.VideoBitrate*107/100), // Allow small peaks over the target bitrate
+			"bufsize":          fmt.Sprintf("%dk", rendition.VideoBitrate*3/2),
+			"force_key_frames": fmt.Sprintf("expr:gte(t,n_forced*%d)", constants.StreamSegmentDuration), // Align key frames with segments across renditions
+		}
+
+		if idx == 0 {
+			outputArgs["c:a"] = "aac"
+			outputArgs["b:a"] = fmt.Sprintf("%dk", rendition.AudioBitrate)
+			outputArgs["ac"] = 2
+		} else {
+			outputArgs["an"] = ""
+		}
+
+		progress := s.newProgressWriter(ctx, renditionLogger, video, transcodeStageName, rendition.Name)
+
+		err = ffmpeg_go.OutputContext(ctx, []*ffmpeg_go.Stream{ffmpeg_go.Input(sourceURL)}, opPath, outputArgs).
+			GlobalArgs(ffmpegProgressArgs...).
+			OverWriteOutput().
+			WithOutput(progress).
+			Run()
+		if err != nil {
+			renditionLogger.Error("Failed to transcode rendition", err)
+			err = fluxerrors.ErrVideoTranscodingFailed
+			return
+		}
+
+		files = append(files, opPath)
+	}
+
+	return
+}
+
+// Packages the encoded renditions as fragmented MP4 segments which are referenced by both
+// the DASH manifest and the HLS playlists.
+func (s *VideoService) packageRenditions(ctx context.Context, logger schema.Logger, video model.Video, renditionFiles []string, packageDir string) (err error) {
+	logger.Info("Packaging renditions", "rendition_count", len(renditionFiles))
+
+	err = os.MkdirAll(packageDir, 0o755)
+	if err != nil {
+		logger.Error("Failed to create packaging directory", err)
+		err = fluxerrors.ErrVideoPackagingFailed
+		return
+	}
+
+	streams := []*ffmpeg_go.Stream{}
+	var audioStream *ffmpeg_go.Stream
+
+	for idx, file := range renditionFiles {
+		input := ffmpeg_go.Input(file)
+		streams = append(streams, input.Video())
+
+		if idx == 0 {
+			audioStream = input.Audio()
+		}
+	}
+
+	streams = append(streams, audioStream)
+
+	progress := s.newProgressWriter(ctx, logger, video, transcodeStageName, "package")
+
+	err = ffmpeg_go.OutputContext(ctx, streams, path.Join(packageDir, constants.DASHManifestName), ffmpeg_go.KwArgs{
+		"c":               "copy",
+		"f":               "dash",
+		"seg_duration":    constants.StreamSegmentDuration,
+		"use_template":    1,
+		"use_timeline":    1,
+		"hls_playlist":    1, // Also write the HLS master and media playlists for the same segments
+		"adaptation_sets": "id=0,streams=v id=1,streams=a",
+		"init_seg_name":   "init-$RepresentationID$.m4s",
+		"media_seg_name":  "chunk-$RepresentationID$-$Number%05d$.m4s",
+	}).GlobalArgs(ffmpegProgressArgs...).OverWriteOutput().WithOutput(progress).Run()
+
+	if err != nil {
+		logger.Error("Failed to package the renditions", err)
+		err = fluxerrors.ErrVideoPackagingFailed
+		return
+	}
+
+	return
+}
+
+// Uploads every packaged file to the public bucket keeping the directory layout.
+func (s *VideoService) uploadPackagedFiles(ctx context.Context, slug string, packageDir string) (err error) {
+	baseDir := path.Dir(packageDir)
+
+	err = filepath.WalkDir(packageDir, func(filePath string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil || entry.IsDir() {
+			return walkErr
+		}
+
+		relPath, err := filepath.Rel(baseDir, filePath)
+		if err != nil {
+			return err
+		}
+
+		contentType, ok := packagedContentTypes[path.Ext(filePath)]
+		if !ok {
+			contentType = "application/octet-stream"
+		}
+
+		return uploadLocalFile(filePath, func(file io.Reader) error {
+			return s.videRepo.UploadPublicVideoFile(ctx, slug, filepath.ToSlash(relPath), contentType, file)
+		})
+	})
+
+	return
+}
+
+// Opens a local file and hands it to the upload.
+func uploadLocalFile(filePath string, upload func(file io.Reader) error) (err error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return
+	}
+
+	defer file.Close()
+
+	return upload(file)
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fluxio-backend/pkg/repository"
+	"fluxio-backend/pkg/utils"
+	"io"
+	"strings"
+	"time"
+)
+
+// Starts a tus upload of the video file. The chunks sent by the client are streamed into a multipart
+// upload at the storage so a tus upload shares its record with the S3 multipart uploads.
+func (s *VideoService) CreateTusUpload(ctx context.Context, slug string, user model.User, mimeType string, length int64) (upload model.VideoUpload, err error) {
+	logger := s.l.With("slug", slug).With("user_id", user.ID.String())
+
+	if length <= 0 || length > constants.MaxTusUploadSize {
+		err = fluxerrors.ErrInvalidUploadLength
+		return
+	}
+
+	if !utils.CheckVideoMimeTypeValidity(mimeType) {
+		err = fluxerrors.ErrInvalidVideoExtension
+		return
+	}
+
+	video, err := s.getPendingUploadVideo(ctx, slug, user)
+	if err != nil {
+		return
+	}
+
+	logger = logger.With("video_id", video.ID.String())
+
+	uploadID, err := s.videRepo.CreateMultipartUpload(ctx, video.Slug, mimeType)
+	if err != nil {
+		return
+	}
+
+	expiresAt := time.Now().Add(constants.TusUploadExpireTime)
+
+	upload, err = s.videRepo.CreateVideoUpload(ctx, model.VideoUpload{
+		VideoID:     video.ID,
+		UploadID:    uploadID,
+		StoragePath: s.videRepo.GetUnProcessedVideoFilePath(video.Slug),
+		PartSize:    constants.TusUploadPartSize,
+		Protocol:    model.VideoUploadProtocolTus,
+		Length:      length,
+		ExpiresAt:   &expiresAt,
+	})
+
+	if err != nil {
+		// Free the upload at the storage since it can never be completed without the record.
+		abortErr := s.videRepo.AbortMultipartUpload(ctx, video.Slug, uploadID)
+		if abortErr != nil {
+			logger.Error("Failed to abort the untracked multipart upload", abortErr)
+		}
+		return
+	}
+
+	logger.Info("Tus upload started", "video_upload_id", upload.ID.String(), "length", length)
+	return
+}
+
+// Returns the tus upload of the user so that the client can read the offset to resume from.
+func (s *VideoService) GetTusUpload(ctx context.Context, id model.VideoUploadID, user model.User) (upload model.VideoUpload, err error) {
+	upload, _, err = s.getActiveTusUpload(ctx, id, user)
+	return
+}
+
+// Appends a chunk to the tus upload starting at the given offset. Once the declared length is reached the
+// upload is completed and the video is queued for processing.
+func (s *VideoService) WriteTusChunk(ctx context.Context, id model.VideoUploadID, user model.User, offset int64, body io.Reader) (upload model.VideoUpload, err error) {
+	upload, video, err := s.getActiveTusUpload(ctx, id, user)
+	if err != nil {
+		return
+	}
+
+	logger := s.l.With("video_id", video.ID.String()).With("video_upload_id", upload.ID.String())
+
+	upload, interrupted, err := writeTusChunk(ctx, &videoTusUploadStore{repo: s.videRepo, video: video}, upload, offset, body)
+	if err != nil {
+		return
+	}
+
+	if interrupted {
+		logger.With("offset", upload.Offset).Warn("Tus chunk was interrupted")
+	}
+
+	if upload.Offset < upload.Length {
+		return
+	}
+
+	err = s.completeTusUpload(ctx, video, upload)
+	return
+}
+
+// Terminates the tus upload and frees the parts stored so far.
+func (s *VideoService) TerminateTusUpload(ctx context.Context, id model.VideoUploadID, user model.User) (err error) {
+	upload, video, err := s.getActiveTusUpload(ctx, id, user)
+	if err != nil {
+		return
+	}
+
+	return s.abortVideoUpload(ctx, video, upload)
+}
+
+// Storage the chunks of a tus upload are written to.
+type tusUploadStore interface {
+	UploadPart(ctx context.Context, upload model.VideoUpload, partNumber int64, data []byte) (etag string, err error)
+	GetPendingData(ctx context.Context, upload model.VideoUpload) (data []byte, err error)
+	PutPendingData(ctx context.Context, upload model.VideoUpload, data []byte) (err error)
+	DeletePendingData(ctx context.Context, upload model.VideoUpload, offset int64) (err error)
+	SaveProgress(ctx context.Context, upload model.VideoUpload, expectedOffset int64, newParts []model.UploadedPart) (err error)
+}
+
+// Buffers the chunk into parts of a fixed size which are sent to the storage as soon as they are full, while
+// the remainder is kept aside until the next chunk arrives. The progress is stored after every part so that an
+// interrupted request only loses the data which has not been stored yet.
+func writeTusChunk(ctx context.Context, store tusUploadStore, upload model.VideoUpload, offset int64, body io.Reader) (updated model.VideoUpload, interrupted bool, err error) {
+	if offset != upload.Offset {
+		err = fluxerrors.ErrUploadOffsetMismatch
+		return
+	}
+
+	buffer := make([]byte, upload.PartSize)
+	filled := 0
+
+	pendingOffset := upload.Offset
+	pendingLength := upload.PendingLength()
+
+	if pendingLength > 0 {
+		var pending []byte
+		pending, err = store.GetPendingData(ctx, upload)
+		if err != nil {
+			return
+		}
+
+		if int64(len(pending)) != pendingLength || pendingLength > upload.PartSize {
+			err = fluxerrors.ErrUploadPendingDataMissing
+			return
+		}
+
+		filled = copy(buffer, pending)
+	}
+
+	reader := io.LimitReader(body, upload.Length-upload.Offset)
+	storedOffset := upload.Offset
+
+	var readErr error
+	for readErr == nil {
+		var n int
+		n, readErr = io.ReadFull(reader, buffer[filled:])
+		filled += n
+		upload.Offset += int64(n)
+
+		complete := upload.Offset == upload.Length
+		if filled < len(buffer) && !complete {
+			continue
+		}
+
+		if filled == 0 {
+			break
+		}
+
+		partNumber := int64(len(upload.Parts) + 1)
+
+		var etag string
+		etag, err = store.UploadPart(ctx, upload, partNumber, buffer[:filled])
+		if err != nil {
+			return
+		}
+
+		part := model.UploadedPart{
+			PartNumber: partNumber,
+			ETag:       etag,
+			Size:       int64(filled),
+		}
+
+		upload.Parts = append(upload.Parts, part)
+		filled = 0
+
+		err = saveTusProgress(ctx, store, &upload, storedOffset, []model.UploadedPart{part})
+		if err != nil {
+			return
+		}
+		storedOffset = upload.Offset
+
+		if complete {
+			break
+		}
+	}
+
+	// Keep the bytes which do not fill a part yet, including the ones received before the client disconnected.
+	if upload.Offset != storedOffset {
+		err = store.PutPendingData(ctx, upload, buffer[:filled])
+		if err != nil {
+			return
+		}
+
+		err = saveTusProgress(ctx, store, &upload, storedOffset, nil)
+		if err != nil {
+			return
+		}
+	}
+
+	// The bytes the chunk started with are in a part or in the new remainder by now.
+	if pendingLength > 0 && upload.Offset != pendingOffset {
+		_ = store.DeletePendingData(ctx, upload, pendingOffset)
+	}
+
+	interrupted = readErr != nil && readErr != io.EOF && !errors.Is(readErr, io.ErrUnexpectedEOF)
+	updated = upload
+	return
+}
+
+// Stores the offset of the upload and extends its expiry since the client is still active.
+func saveTusProgress(ctx context.Context, store tusUploadStore, upload *model.VideoUpload, expectedOffset int64, newParts []model.UploadedPart) (err error) {
+	expiresAt := time.Now().Add(constants.TusUploadExpireTime)
+	upload.ExpiresAt = &expiresAt
+
+	return store.SaveProgress(ctx, *upload, expectedOffset, newParts)
+}
+
+// Writes the chunks of a tus upload into the multipart upload of the video in the raw bucket.
+type videoTusUploadStore struct {
+	repo  *repository.VideoRepository
+	video model.Video
+}
+
+func (t *videoTusUploadStore) UploadPart(ctx context.Context, upload model.VideoUpload, partNumber int64, data []byte) (etag string, err error) {
+	return t.repo.UploadMultipartPart(ctx, t.video.Slug, upload.UploadID, partNumber, data)
+}
+
+func (t *videoTusUploadStore) GetPendingData(ctx context.Context, upload model.VideoUpload) (data []byte, err error) {
+	return t.repo.GetTusPendingData(ctx, upload.ID, upload.Offset)
+}
+
+func (t *videoTusUploadStore) PutPendingData(ctx context.Context, upload model.VideoUpload, data []byte) (err error) {
+	return t.repo.PutTusPendingData(ctx, upload.ID, upload.Offset, data)
+}
+
+func (t *videoTusUploadStore) DeletePendingData(ctx context.Context, upload model.VideoUpload, offset int64) (err error) {
+	return t.repo.DeleteTusPendingData(ctx, upload.ID, offset)
+}
+
+func (t *videoTusUploadStore) SaveProgress(ctx context.Context, upload model.VideoUpload, expectedOffset int64, newParts []model.UploadedPart) (err error) {
+	return t.repo.AdvanceVideoUploadOffset(ctx, upload.ID, expectedOffset, upload, newParts)
+}
+
+// Assembles the stored parts and queues the processing the same way as a storage notification does.
+func (s *VideoService) completeTusUpload(ctx context.Context, video model.Video, upload model.VideoUpload) (err error) {
+	logger := s.l.With("video_id", video.ID.String()).With("video_upload_id", upload.ID.String())
+
+	err = s.videRepo.CompleteMultipartUpload(ctx, video.Slug, upload.UploadID, upload.Parts)
+	if err != nil {
+		return
+	}
+
+	err = s.videRepo.UpdateVideoUploadState(ctx, upload.ID, model.VideoUploadStateCompleted)
+	if err != nil {
+		logger.Error("Failed to mark the tus upload as completed", err)
+		return
+	}
+
+	err = s.HandleVideoUpload(ctx, video.Slug, upload.StoragePath)
+	if err != nil {
+		return
+	}
+
+	logger.Info("Tus upload completed", "part_count", len(upload.Parts), "length", upload.Length)
+	return
+}
+
+// Returns the active tus upload along with its video if both belong to the user.
+func (s *VideoService) getActiveTusUpload(ctx context.Context, id model.VideoUploadID, user model.User) (upload model.VideoUpload, video model.Video, err error) {
+	upload, err = s.videRepo.GetVideoUploadByID(ctx, id)
+	if err != nil {
+		return
+	}
+
+	if upload.Protocol != model.VideoUploadProtocolTus {
+		upload = model.VideoUpload{}
+		err = fluxerrors.ErrVideoUploadNotFound
+		return
+	}
+
+	video, err = s.videRepo.GetVideoByID(ctx, upload.VideoID)
+	if err != nil {
+		if err == fluxerrors.ErrVideoNotFound {
+			err = fluxerrors.ErrVideoUploadNotFound
+		}
+		upload = model.VideoUpload{}
+		return
+	}
+
+	if !strings.EqualFold(video.UserID.String(), user.ID.String()) {
+		upload, video = model.VideoUpload{}, model.Video{}
+		err = fluxerrors.ErrVideoUploadNotFound
+		return
+	}
+
+	if upload.State != model.VideoUploadStateActive {
+		err = fluxerrors.ErrVideoUploadNotFound
+		return
+	}
+
+	if upload.ExpiresAt != nil && time.Now().After(*upload.ExpiresAt) {
+		err = fluxerrors.ErrVideoUploadExpired
+		return
+	}
+
+	return
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"errors"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// Keeps a tus upload in memory the way the database and the raw bucket keep it.
+type fakeTusUploadStore struct {
+	offset   int64
+	parts    []model.UploadedPart
+	partData map[int64]string
+	pending  map[int64]string // Pending data by the offset it ends at
+}
+
+func newFakeTusUploadStore() *fakeTusUploadStore {
+	return &fakeTusUploadStore{
+		partData: map[int64]string{},
+		pending:  map[int64]string{},
+	}
+}
+
+// Returns the upload as it would be read back from the database.
+func (f *fakeTusUploadStore) load(length int64, partSize int64) model.VideoUpload {
+	return model.VideoUpload{
+		ID:       "upload",
+		Protocol: model.VideoUploadProtocolTus,
+		State:    model.VideoUploadStateActive,
+		PartSize: partSize,
+		Length:   length,
+		Offset:   f.offset,
+		Parts:    append([]model.UploadedPart{}, f.parts...),
+	}
+}
+
+func (f *fakeTusUploadStore) UploadPart(ctx context.Context, upload model.VideoUpload, partNumber int64, data []byte) (etag string, err error) {
+	f.partData[partNumber] = string(data)
+	return fmt.Sprintf("etag-%d", partNumber), nil
+}
+
+func (f *fakeTusUploadStore) GetPendingData(ctx context.Context, upload model.VideoUpload) (data []byte, err error) {
+	pending, ok := f.pending[upload.Offset]
+	if !ok {
+		return nil, fluxerrors.ErrUploadPendingDataMissing
+	}
+
+	return []byte(pending), nil
+}
+
+func (f *fakeTusUploadStore) PutPendingData(ctx context.Context, upload model.VideoUpload, data []byte) (err error) {
+	f.pending[upload.Offset] = string(data)
+	return
+}
+
+func (f *fakeTusUploadStore) DeletePendingData(ctx context.Context, upload model.VideoUpload, offset int64) (err error) {
+	delete(f.pending, offset)
+	return
+}
+
+func (f *fakeTusUploadStore) SaveProgress(ctx context.Context, upload model.VideoUpload, expectedOffset int64, newParts []model.UploadedPart) (err error) {
+	if expectedOffset != f.offset {
+		return fluxerrors.ErrUploadOffsetMismatch
+	}
+
+	f.offset = upload.Offset
+	f.parts = append(f.parts, newParts...)
+	return
+}
+
+func (f *fakeTusUploadStore) storedParts() (parts []string) {
+	for _, part := range f.parts {
+		parts = append(parts, f.partData[part.PartNumber])
+	}
+
+	return
+}
+
+type tusChunk struct {
+	offset int64
+	body   io.Reader
+}
+
+func TestWriteTusChunk(t *testing.T) {
+	tests := []struct {
+		name            string
+		length          int64
+		chunks          []tusChunk
+		wantErr         error
+		wantInterrupted bool
+		wantOffset      int64
+		wantParts       []string
+		wantPending     string
+	}{
+		{
+			name:        "buffers a chunk smaller than a part",
+			length:      10,
+			chunks:      []tusChunk{{0, strings.NewReader("abc")}},
+			wantOffset:  3,
+			wantPending: "abc",
+		},
+		{
+			name:        "flushes every full part",
+			length:      10,
+			chunks:      []tusChunk{{0, strings.NewReader("abcdefghi")}},
+			wantOffset:  9,
+			wantParts:   []string{"abcd", "efgh"},
+			wantPending: "i",
+		},
+		{
+			name:   "carries the remainder over to the next chunk",
+			length: 10,
+			chunks: []tusChunk{
+				{0, strings.NewReader("abc")},
+				{3, strings.NewReader("de")},
+			},
+			wantOffset:  5,
+			wantParts:   []string{"abcd"},
+			wantPending: "e",
+		},
+		{
+			name:   "carries the remainder over without filling a part",
+			length: 10,
+			chunks: []tusChunk{
+				{0, strings.NewReader("a")},
+				{1, strings.NewReader("b")},
+			},
+			wantOffset:  2,
+			wantPending: "ab",
+		},
+		{
+			name:       "completes at the length",
+			length:     6,
+			chunks:     []tusChunk{{0, strings.NewReader("abcdef")}},
+			wantOffset: 6,
+			wantParts:  []string{"abcd", "ef"},
+		},
+		{
+			name:   "completes with the carried over remainder",
+			length: 6,
+			chunks: []tusChunk{
+				{0, strings.NewReader("abc")},
+				{3, strings.NewReader("def")},
+			},
+			wantOffset: 6,
+			wantParts:  []string{"abcd", "ef"},
+		},
+		{
+			name:       "ignores the bytes past the length",
+			length:     6,
+			chunks:     []tusChunk{{0, strings.NewReader("abcdefXYZ")}},
+			wantOffset: 6,
+			wantParts:  []string{"abcd", "ef"},
+		},
+		{
+			name:   "rejects a chunk at another offset",
+			length: 10,
+			chunks: []tusChunk{
+				{0, strings.NewReader("abc")},
+				{2, strings.NewReader("d")},
+			},
+			wantErr:     fluxerrors.ErrUploadOffsetMismatch,
+			wantOffset:  3,
+			wantPending: "abc",
+		},
+		{
+			name:            "keeps the bytes received before an interruption",
+			length:          10,
+			chunks:          []tusChunk{{0, io.MultiReader(strings.NewReader("abcde"), iotest.ErrReader(errors.New("connection reset")))}},
+			wantInterrupted: true,
+			wantOffset:      5,
+			wantParts:       []string{"abcd"},
+			wantPending:     "e",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeTusUploadStore()
+
+			var (
+				upload      model.VideoUpload
+				interrupted bool
+				err         error
+			)
+
+			for _, chunk := range tt.chunks {
+				upload, interrupted, err = writeTusChunk(context.Background(), store, store.load(tt.length, 4), chunk.offset, chunk.body)
+				if err != nil {
+					break
+				}
+
+				if upload.Offset != store.offset {
+					t.Fatalf("returned offset %d, stored offset %d", upload.Offset, store.offset)
+				}
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if interrupted != tt.wantInterrupted {
+				t.Errorf("got interrupted %v, want %v", interrupted, tt.wantInterrupted)
+			}
+
+			if store.offset != tt.wantOffset {
+				t.Errorf("got offset %d, want %d", store.offset, tt.wantOffset)
+			}
+
+			if got := store.storedParts(); strings.Join(got, ",") != strings.Join(tt.wantParts, ",") {
+				t.Errorf("got parts %q, want %q", got, tt.wantParts)
+			}
+
+			if got := store.load(tt.length, 4).PendingLength(); got != int64(len(tt.wantPending)) {
+				t.Errorf("got pending length %d, want %d", got, len(tt.wantPending))
+			}
+
+			// Only the remainder at the stored offset is kept.
+			wantPendingCount := 0
+			if tt.wantPending != "" {
+				wantPendingCount = 1
+			}
+
+			if len(store.pending) != wantPendingCount || store.pending[store.offset] != tt.wantPending {
+				t.Errorf("got pending data %q, want %q at offset %d", store.pending, tt.wantPending, store.offset)
+			}
+		})
+	}
+}
+
+func TestWriteTusChunkMissingPendingData(t *testing.T) {
+	store := newFakeTusUploadStore()
+
+	_, _, err := writeTusChunk(context.Background(), store, store.load(10, 4), 0, strings.NewReader("abc"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	delete(store.pending, store.offset)
+
+	_, _, err = writeTusChunk(context.Background(), store, store.load(10, 4), 3, strings.NewReader("d"))
+	if !errors.Is(err, fluxerrors.ErrUploadPendingDataMissing) {
+		t.Fatalf("got error %v, want %v", err, fluxerrors.ErrUploadPendingDataMissing)
+	}
+
+	if store.offset != 3 {
+		t.Errorf("got offset %d, want 3", store.offset)
+	}
+}
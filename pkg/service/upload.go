@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fluxio-backend/pkg/utils"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Starts a multipart upload of the video file. An upload which is already in progress is returned instead
+// so that the client can resume it.
+func (s *VideoService) InitiateMultipartUpload(ctx context.Context, slug string, user model.User, mimeType string) (upload model.VideoUpload, err error) {
+	logger := s.l.With("slug", slug).With("user_id", user.ID.String())
+
+	if !utils.CheckVideoMimeTypeValidity(mimeType) {
+		err = fluxerrors.ErrInvalidVideoExtension
+		return
+	}
+
+	video, err := s.getPendingUploadVideo(ctx, slug, user)
+	if err != nil {
+		return
+	}
+
+	logger = logger.With("video_id", video.ID.String())
+
+	upload, err = s.getSyncedActiveUpload(ctx, video)
+	if err != fluxerrors.ErrVideoUploadNotFound {
+		if err == nil && upload.Protocol == model.VideoUploadProtocolTus {
+			upload = model.VideoUpload{}
+			err = fluxerrors.ErrVideoUploadAlreadyActive
+		}
+		return
+	}
+
+	uploadID, err := s.videRepo.CreateMultipartUpload(ctx, video.Slug, mimeType)
+	if err != nil {
+		return
+	}
+
+	upload, err = s.videRepo.CreateVideoUpload(ctx, model.VideoUpload{
+		VideoID:     video.ID,
+		UploadID:    uploadID,
+		StoragePath: s.videRepo.GetUnProcessedVideoFilePath(video.Slug),
+		PartSize:    constants.MultipartUploadPartSize,
+	})
+
+	if err != nil {
+		// Free the upload at the storage since it can never be completed without the record.
+		abortErr := s.videRepo.AbortMultipartUpload(ctx, video.Slug, uploadID)
+		if abortErr != nil {
+			logger.Error("Failed to abort the untracked multipart upload", abortErr)
+		}
+
+		// Another request started an upload at the same time.
+		if err == fluxerrors.ErrVideoUploadAlreadyActive {
+			upload, err = s.getSyncedActiveUpload(ctx, video)
+		}
+		return
+	}
+
+	logger.Info("Multipart upload started", "video_upload_id", upload.ID.String())
+	return
+}
+
+// Returns the multipart upload in progress along with the parts which already reached the storage.
+func (s *VideoService) GetMultipartUpload(ctx context.Context, slug string, user model.User) (upload model.VideoUpload, err error) {
+	video, err := s.getPendingUploadVideo(ctx, slug, user)
+	if err != nil {
+		return
+	}
+
+	return s.getSyncedActiveUpload(ctx, video)
+}
+
+// Generates a presigned URL to upload a single part of the multipart upload in progress.
+func (s *VideoService) GenerateMultipartPartURL(ctx context.Context, slug string, user model.User, partNumber int64) (url url.URL, err error) {
+	video, err := s.getPendingUploadVideo(ctx, slug, user)
+	if err != nil {
+		return
+	}
+
+	upload, err := s.videRepo.GetActiveVideoUpload(ctx, video.ID)
+	if err != nil {
+		return
+	}
+
+	// The parts of a tus upload are written by the server only.
+	if upload.Protocol == model.VideoUploadProtocolTus {
+		err = fluxerrors.ErrVideoUploadAlreadyActive
+		return
+	}
+
+	ptrURL, err := s.videRepo.GenerateMultipartPartUploadURL(ctx, video.Slug, upload.UploadID, partNumber)
+	if err != nil {
+		return
+	}
+
+	// Keep the upload marked as active so that the sweeper does not abandon a long running upload.
+	err = s.videRepo.SaveVideoUploadParts(ctx, upload.ID, nil)
+	if err != nil {
+		return
+	}
+
+	url = *ptrURL
+	return
+}
+
+// Assembles the uploaded parts into the video file and queues the post upload processing.
+func (s *VideoService) CompleteMultipartUpload(ctx context.Context, slug string, user model.User) (err error) {
+	logger := s.l.With("slug", slug).With("user_id", user.ID.String())
+
+	video, err := s.getPendingUploadVideo(ctx, slug, user)
+	if err != nil {
+		return
+	}
+
+	logger = logger.With("video_id", video.ID.String())
+
+	upload, err := s.getSyncedActiveUpload(ctx, video)
+	if err != nil {
+		return
+	}
+
+	if upload.Protocol == model.VideoUploadProtocolTus {
+		err = fluxerrors.ErrVideoUploadAlreadyActive
+		return
+	}
+
+	if len(upload.Parts) == 0 {
+		err = fluxerrors.ErrVideoUploadPartsIncomplete
+		return
+	}
+
+	err = s.videRepo.CompleteMultipartUpload(ctx, video.Slug, upload.UploadID, upload.Parts)
+	if err != nil {
+		return
+	}
+
+	err = s.videRepo.UpdateVideoUploadState(ctx, upload.ID, model.VideoUploadStateCompleted)
+	if err != nil {
+		logger.Error("Failed to mark the multipart upload as completed", err)
+		return
+	}
+
+	// Do not wait for the storage notification since the upload is known to be complete.
+	err = s.HandleVideoUpload(ctx, video.Slug, upload.StoragePath)
+	if err != nil {
+		return
+	}
+
+	logger.Info("Multipart upload completed", "part_count", len(upload.Parts))
+	return
+}
+
+// Aborts the multipart upload in progress. A new upload can be started afterwards.
+func (s *VideoService) AbortMultipartUpload(ctx context.Context, slug string, user model.User) (err error) {
+	video, err := s.getPendingUploadVideo(ctx, slug, user)
+	if err != nil {
+		return
+	}
+
+	upload, err := s.videRepo.GetActiveVideoUpload(ctx, video.ID)
+	if err != nil {
+		return
+	}
+
+	return s.abortVideoUpload(ctx, video, upload)
+}
+
+func (s *VideoService) abortVideoUpload(ctx context.Context, video model.Video, upload model.VideoUpload) (err error) {
+	err = s.videRepo.AbortMultipartUpload(ctx, video.Slug, upload.UploadID)
+	if err != nil {
+		return
+	}
+
+	err = s.videRepo.UpdateVideoUploadState(ctx, upload.ID, model.VideoUploadStateAborted)
+	if err != nil {
+		return
+	}
+
+	// The buffered bytes of a tus upload are not part of the multipart upload. Failing to delete them only leaves garbage behind.
+	if upload.PendingLength() > 0 {
+		_ = s.videRepo.DeleteTusPendingData(ctx, upload.ID, upload.Offset)
+	}
+
+	s.l.With("video_id", video.ID.String()).Info("Multipart upload aborted", "video_upload_id", upload.ID.String())
+	return
+}
+
+// Checks whether a video has a multipart upload which made progress within the window.
+func (s *VideoService) hasRecentUploadActivity(ctx context.Context, video model.Video, window time.Duration) (active bool, upload model.VideoUpload, err error) {
+	upload, err = s.videRepo.GetActiveVideoUpload(ctx, video.ID)
+	if err != nil {
+		if err == fluxerrors.ErrVideoUploadNotFound {
+			err = nil
+		}
+		return
+	}
+
+	// A tus upload can be resumed until it expires.
+	if upload.Protocol == model.VideoUploadProtocolTus && upload.ExpiresAt != nil {
+		active = time.Now().Before(*upload.ExpiresAt)
+		return
+	}
+
+	active = upload.UpdatedAt != nil && time.Since(*upload.UpdatedAt) < window
+	return
+}
+
+// Returns the active upload with the parts recorded from the storage, which is the source of truth.
+func (s *VideoService) getSyncedActiveUpload(ctx context.Context, video model.Video) (upload model.VideoUpload, err error) {
+	upload, err = s.videRepo.GetActiveVideoUpload(ctx, video.ID)
+	if err != nil {
+		return
+	}
+
+	parts, err := s.videRepo.ListMultipartUploadParts(ctx, video.Slug, upload.UploadID)
+	if err != nil {
+		return
+	}
+
+	err = s.videRepo.SaveVideoUploadParts(ctx, upload.ID, parts)
+	if err != nil {
+		return
+	}
+
+	upload.Parts = parts
+	if upload.Parts == nil {
+		upload.Parts = []model.UploadedPart{}
+	}
+
+	return
+}
+
+// Returns the video of the user if it still waits for its file.
+func (s *VideoService) getPendingUploadVideo(ctx context.Context, slug string, user model.User) (video model.Video, err error) {
+	if strings.EqualFold(slug, "") {
+		err = fluxerrors.ErrInvalidVideoSlug
+		return
+	}
+
+	video, err = s.videRepo.GetVideoBySlug(ctx, slug)
+	if err != nil {
+		if err == fluxerrors.ErrVideoNotFound {
+			return
+		}
+		s.l.With("slug", slug).Error("Failed to get video by slug", err)
+		err = fluxerrors.ErrUnknown
+		return
+	}
+
+	if !strings.EqualFold(video.UserID.String(), user.ID.String()) {
+		video = model.Video{}
+		err = fluxerrors.ErrVideoNotFound
+		return
+	}
+
+	if video.Status != model.VideoStatusUploadPending {
+		video = model.Video{}
+		err = fluxerrors.ErrVideoUploadNotAllowed
+		return
+	}
+
+	return
+}
@@ -2,35 +2,36 @@ package service
 
 import (
 	"context"
-	"encoding/json"
 	"fluxio-backend/pkg/common/schema"
 	"fluxio-backend/pkg/constants"
 	fluxerrors "fluxio-backend/pkg/errors"
 	"fluxio-backend/pkg/model"
+	"fluxio-backend/pkg/pubsub"
 	"fluxio-backend/pkg/repository"
 	"fluxio-backend/pkg/utils"
-	"fmt"
-	"math"
-	"math/rand"
-	"net/http"
 	"net/url"
-	"os"
-	"path"
-	"strconv"
 	"strings"
-
-	ffmpeg_go "github.com/u2takey/ffmpeg-go"
 )
 
 type VideoService struct {
-	videRepo *repository.VideoRepository
-	l        schema.Logger
+	videRepo          *repository.VideoRepository
+	jobSvc            *JobService
+	events            *pubsub.Broker[model.VideoEvent]
+	thumbnailProfiles []model.ThumbnailProfile
+	l                 schema.Logger
+}
+
+type VideoServiceConfig struct {
+	ThumbnailProfiles []model.ThumbnailProfile // Sizes and formats every thumbnail is stored in
 }
 
-func NewVideoService(videRepo *repository.VideoRepository, logger schema.Logger) *VideoService {
+func NewVideoService(videRepo *repository.VideoRepository, jobSvc *JobService, events *pubsub.Broker[model.VideoEvent], cfg VideoServiceConfig, logger schema.Logger) *VideoService {
 	return &VideoService{
-		videRepo: videRepo,
-		l:        logger,
+		videRepo:          videRepo,
+		jobSvc:            jobSvc,
+		events:            events,
+		thumbnailProfiles: cfg.ThumbnailProfiles,
+		l:                 logger,
 	}
 }
 
@@ -80,71 +81,102 @@ func (s *VideoService) AddVideo(ctx context.Context, vidMeta model.Video, mimeTy
 	return
 }
 
-// Handles the meta update after the video file is uploaded.
-func (s *VideoService) UpdateUploadStatus(ctx context.Context, slug string, params model.Video) (err error) {
-	logger := s.l.With("slug", slug)
+// Issues a fresh upload URL for a video whose upload never completed. Only the owner can request it and
+// every request counts against the upload retry limit.
+func (s *VideoService) RegenerateUploadURL(ctx context.Context, slug string, user model.User, mimeType string) (video model.Video, url url.URL, err error) {
+	logger := s.l.With("slug", slug).With("user_id", user.ID.String())
 
-	if strings.EqualFold(params.StoragePath, "") {
-		err = fluxerrors.ErrMalformedStoragePath
-		logger.Error("Invalid storage path", err)
+	if strings.EqualFold(slug, "") {
+		err = fluxerrors.ErrInvalidVideoSlug
 		return
 	}
 
-	if strings.EqualFold(slug, "") {
-		err = fluxerrors.ErrInvalidVideoSlug
-		logger.Error("Invalid video slug", err)
+	if !utils.CheckVideoMimeTypeValidity(mimeType) {
+		err = fluxerrors.ErrInvalidVideoExtension
 		return
 	}
 
-	existData, err := s.videRepo.GetProcessingDetailsBySlug(ctx, slug)
+	video, err = s.videRepo.GetVideoBySlug(ctx, slug)
 	if err != nil {
 		if err == fluxerrors.ErrVideoNotFound {
-			logger.Error("Video not found", err)
 			return
 		}
-
-		logger.Error("Failed to get video processing details", err)
+		logger.Error("Failed to get video by slug", err)
 		err = fluxerrors.ErrUnknown
 		return
 	}
 
-	logger = logger.With("video_id", existData.ID.String())
+	// Hide the video from everyone except the owner.
+	if !strings.EqualFold(video.UserID.String(), user.ID.String()) {
+		video = model.Video{}
+		err = fluxerrors.ErrVideoNotFound
+		return
+	}
 
-	// If video status is not pending or retry limit is over end it.
-	if existData.Status != model.VideoStatusUploadPending || existData.RetryCount > constants.MaxVideoURLRegenerateRetryCount {
-		err = fluxerrors.ErrInvalidVideoStatus
-		logger.Error("Invalid video status for update", err)
+	logger = logger.With("video_id", video.ID.String())
+
+	if video.Status != model.VideoStatusUploadPending && video.Status != model.VideoStatusAbandoned {
+		video = model.Video{}
+		err = fluxerrors.ErrVideoUploadNotAllowed
 		return
 	}
 
-	err = s.videRepo.UpdateMeta(ctx, existData.ID, model.VideoStatusProcessing, model.Video{
-		StoragePath: params.StoragePath,
-	})
+	if video.RetryCount >= constants.MaxVideoURLRegenerateRetryCount {
+		video = model.Video{}
+		err = fluxerrors.ErrVideoUploadRetryExceeded
+		logger.Info("Upload URL retry limit reached")
+		return
+	}
 
+	err = s.videRepo.IncrementVideoRetryCount(ctx, video.ID)
 	if err != nil {
-		if err == fluxerrors.ErrInvalidVideoID || err == fluxerrors.ErrMalformedStoragePath {
-			logger.Error("Failed to update video metadata", err)
+		logger.Error("Failed to increment the upload retry count", err)
+		video = model.Video{}
+		err = fluxerrors.ErrVideoURLGenerationFailed
+		return
+	}
+
+	video.RetryCount++
+
+	// Give an abandoned upload another chance.
+	if video.Status == model.VideoStatusAbandoned {
+		transitioned, transitionErr := s.transitionStatus(ctx, video, model.VideoStatusAbandoned, model.VideoStatusUploadPending, "")
+		if transitionErr != nil || !transitioned {
+			logger.Error("Failed to move the abandoned video back to upload pending", transitionErr)
+			video = model.Video{}
+			err = fluxerrors.ErrVideoUploadNotAllowed
 			return
 		}
 
-		logger.Error("Video metadata update failed", err)
-		err = fluxerrors.ErrVideoMetaUpdateFailed
+		video.Status = model.VideoStatusUploadPending
+	}
+
+	ptrURL, err := s.videRepo.GenerateUnProcessedVideoUploadURL(ctx, video.ID, video.Slug, mimeType)
+	if err != nil {
+		logger.Error("Failed to generate video upload URL", err)
+		video = model.Video{}
+		err = fluxerrors.ErrVideoURLGenerationFailed
 		return
 	}
 
-	logger.Info("Video upload status updated to processing")
+	url = *ptrURL
+	logger.Info("Video upload URL regenerated", "retry_count", video.RetryCount)
 	return
 }
 
-// Performs all the post upload processing for the video.
-func (s *VideoService) PerformPostUploadProcessing(ctx context.Context, slug string) (err error) {
-	logger := s.l.With("slug", slug)
-	logger.Info("Starting post-upload processing")
+// Returns the video details along with the manifests of the available packaging formats.
+// Videos which are private or not yet ready are only visible to their owner.
+func (s *VideoService) GetVideoDetails(ctx context.Context, slug string, user model.User) (video model.Video, err error) {
+	logger := s.l.With("slug", slug).With("user_id", user.ID.String())
 
-	videoMeta, err := s.videRepo.GetVideoBySlug(ctx, slug)
+	if strings.EqualFold(slug, "") {
+		err = fluxerrors.ErrInvalidVideoSlug
+		return
+	}
+
+	video, err = s.videRepo.GetVideoBySlug(ctx, slug)
 	if err != nil {
 		if err == fluxerrors.ErrVideoNotFound {
-			logger.Error("Video not found for processing", err)
 			return
 		}
 		logger.Error("Failed to get video by slug", err)
@@ -152,253 +184,257 @@ func (s *VideoService) PerformPostUploadProcessing(ctx context.Context, slug str
 		return
 	}
 
-	logger = logger.With("video_id", videoMeta.ID.String())
+	isOwner := strings.EqualFold(video.UserID.String(), user.ID.String())
+	if !isOwner && (video.Visibility != model.VideoVisibilityPublic || video.Status != model.VideoStatusCompleted) {
+		video = model.Video{}
+		err = fluxerrors.ErrVideoNotFound
+		return
+	}
 
-	if videoMeta.Status != model.VideoStatusProcessing {
-		err = fluxerrors.ErrInvalidVideoStatus
-		logger.Error("Invalid video status for processing", err)
+	if video.Status != model.VideoStatusCompleted {
 		return
 	}
 
-	downloadURL, err := s.videRepo.GetUnProcessedVideoDownloadURL(ctx, videoMeta.Slug)
+	video.Manifests, err = s.videRepo.GetVideoManifests(ctx, video.ID)
 	if err != nil {
-		if err == fluxerrors.ErrVideoURLGenerationFailed {
-			logger.Error("Failed to generate video download URL", err)
-			return
-		}
-		logger.Error("Unknown error getting video download URL", err)
+		logger.Error("Failed to get the video manifests", err)
+		video = model.Video{}
 		err = fluxerrors.ErrUnknown
 		return
 	}
 
-	// Extract the whole video meta data like size, type, width, height,etc
-	logger.Info("Extracting video metadata")
-	rawProbe, err := ffmpeg_go.Probe(downloadURL.String())
+	video.Thumbnails, err = s.videRepo.GetVideoThumbnails(ctx, video.ID)
 	if err != nil {
-		logger.Error("Failed to probe video using ffmpeg", err)
-		err = fluxerrors.ErrVideoPhysicalMetaExtractionFailed
+		logger.Error("Failed to get the video thumbnails", err)
+		video = model.Video{}
+		err = fluxerrors.ErrUnknown
 		return
 	}
 
-	var probe model.FFProbeOutput
-	err = json.Unmarshal([]byte(rawProbe), &probe)
-	if err != nil {
-		logger.Error("Failed to parse ffprobe output", err)
-		err = fluxerrors.ErrVideoPhysicalMetaExtractionFailed
+	// The sprites and the preview are optional so a video without them is still returned.
+	sprite, err := s.videRepo.GetThumbnailSprite(ctx, video.ID)
+	if err == nil {
+		video.Sprite = &sprite
+	} else if err != fluxerrors.ErrThumbnailSpriteNotFound {
+		logger.Error("Failed to get the thumbnail sprite", err)
+		video = model.Video{}
+		err = fluxerrors.ErrUnknown
 		return
 	}
 
-	if probe.Format.NbStreams != 2 {
-		logger.Error("Unsupported video stream count", nil)
-		err = fluxerrors.ErrVideoStreamCountNotSupported
+	preview, err := s.videRepo.GetThumbnailPreview(ctx, video.ID)
+	if err == nil {
+		video.Preview = &preview
+	} else if err != fluxerrors.ErrThumbnailPreviewNotFound {
+		logger.Error("Failed to get the preview clip", err)
+		video = model.Video{}
+		err = fluxerrors.ErrUnknown
 		return
 	}
 
-	videoStream := model.FFProbeStream{}
-	audioStream := model.FFProbeStream{}
-
-	// Get the streams from the probe
-	if probe.Streams[0].CodecType == "video" {
-		videoStream = probe.Streams[0]
-		audioStream = probe.Streams[1]
-	} else {
-		audioStream = probe.Streams[0]
-		videoStream = probe.Streams[1]
-	}
+	err = nil
+	return
+}
 
-	updateData := model.Video{}
-	updateData.AudioCodec = audioStream.CodecName
+// Handles the meta update after the video file is uploaded.
+func (s *VideoService) UpdateUploadStatus(ctx context.Context, slug string, params model.Video) (err error) {
+	logger := s.l.With("slug", slug)
 
-	sampleRate, err := strconv.Atoi(audioStream.SampleRate)
-	if err != nil {
-		logger.Error("Failed to parse audio sample rate", err)
-		err = fluxerrors.ErrVideoPhysicalMetaExtractionFailed
+	if strings.EqualFold(params.StoragePath, "") {
+		err = fluxerrors.ErrMalformedStoragePath
+		logger.Error("Invalid storage path", err)
 		return
 	}
 
-	updateData.AudioSampleRate = uint32(sampleRate)
-	updateData.Width = uint32(videoStream.Width)
-	updateData.Height = uint32(videoStream.Height)
-	updateData.Format = videoStream.CodecName
+	if strings.EqualFold(slug, "") {
+		err = fluxerrors.ErrInvalidVideoSlug
+		logger.Error("Invalid video slug", err)
+		return
+	}
 
-	duration, err := strconv.ParseFloat(probe.Format.Duration, 64)
+	existData, err := s.videRepo.GetProcessingDetailsBySlug(ctx, slug)
 	if err != nil {
-		logger.Error("Failed to parse video duration", err)
-		err = fluxerrors.ErrVideoPhysicalMetaExtractionFailed
+		if err == fluxerrors.ErrVideoNotFound {
+			logger.Error("Video not found", err)
+			return
+		}
+
+		logger.Error("Failed to get video processing details", err)
+		err = fluxerrors.ErrUnknown
 		return
 	}
 
-	updateData.Length = uint64(math.Ceil(duration))
+	logger = logger.With("video_id", existData.ID.String())
 
-	size, err := strconv.ParseFloat(probe.Format.Size, 64)
-	if err != nil {
-		logger.Error("Failed to parse video size", err)
-		err = fluxerrors.ErrVideoPhysicalMetaExtractionFailed
+	// If video status is not pending or retry limit is over end it.
+	if existData.Status != model.VideoStatusUploadPending || existData.RetryCount > constants.MaxVideoURLRegenerateRetryCount {
+		err = fluxerrors.ErrInvalidVideoStatus
+		logger.Error("Invalid video status for update", err)
 		return
 	}
 
-	calcPrec := math.Pow(10, float64(constants.VidSizeDecimalPrecision)) // Stores the power precision to round the size.
-	updateData.Size = float32(math.Round(size*calcPrec) / calcPrec)      // Round the size to decimal places.
+	err = s.videRepo.UpdateMeta(ctx, existData.ID, model.VideoStatusProcessing, model.Video{
+		StoragePath: params.StoragePath,
+	})
 
-	err = s.videRepo.UpdateInternalStatus(ctx, videoMeta.ID, model.VidInternalStatusMetaExtracted)
 	if err != nil {
-		if err == fluxerrors.ErrVideoNotFound {
-			logger.Error("Video not found when updating status", err)
+		if err == fluxerrors.ErrInvalidVideoID || err == fluxerrors.ErrMalformedStoragePath {
+			logger.Error("Failed to update video metadata", err)
 			return
 		}
-		logger.Error("Failed to update video internal status", err)
+
+		logger.Error("Video metadata update failed", err)
 		err = fluxerrors.ErrVideoMetaUpdateFailed
 		return
 	}
 
-	// Create thumbnails for the video and store them in the db
-	logger.Info("Starting thumbnail generation")
-	thumbnailTempDir, err := os.MkdirTemp(os.TempDir(), "fluxio-thumbnails-*")
-	if err != nil {
-		logger.Error("Failed to create temporary directory for thumbnails", err)
-		err = fluxerrors.ErrThumbnailCreationFailed
+	logger.Info("Video upload status updated to processing")
+	return
+}
+
+// Queues the post upload processing of a video. Queueing is idempotent while a job for the video is still active.
+func (s *VideoService) QueuePostUploadProcessing(ctx context.Context, slug string) (err error) {
+	logger := s.l.With("slug", slug)
+
+	if strings.EqualFold(slug, "") {
+		err = fluxerrors.ErrInvalidVideoSlug
 		return
 	}
 
-	defer os.RemoveAll(thumbnailTempDir)
-
-	thumbnailWidth := 1280
-	thumbnailHeight := 720
-	thumbnailFormat := "jpg"
+	_, err = s.jobSvc.Enqueue(ctx, model.JobTypeVideoProcessing, model.VideoProcessingJobPayload{
+		Slug: slug,
+	}, videoProcessingJobKey(slug), constants.MaxVideoProcessingJobAttempts)
 
-	timestamps := s.generateDistinctTimestamps(updateData.Length)
+	if err != nil {
+		if err == fluxerrors.ErrJobAlreadyQueued {
+			err = nil
+			return
+		}
 
-	successThumbnailCount := 0
-	client := &http.Client{}
+		logger.Error("Failed to queue post-upload processing", err)
+		return
+	}
 
-	// Generate three thumbnails
-	for idx, timestamp := range timestamps {
-		// We need to convert the timestamp to ffmpeg format of HH:MM:SS
-		timestampSeconds := timestamp
-		hours := timestampSeconds / 3600
-		minutes := (timestampSeconds % 3600) / 60
-		seconds := timestampSeconds % 60
-		timeStr := fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds)
+	logger.Info("Post-upload processing queued")
+	return
+}
 
-		opPath := path.Join(thumbnailTempDir, fmt.Sprintf("%s-%s.%s", videoMeta.Slug, fmt.Sprint(timestamp), thumbnailFormat))
+// Runs a queued video processing job.
+func (s *VideoService) HandleVideoProcessingJob(ctx context.Context, job model.Job) (err error) {
+	payload := model.VideoProcessingJobPayload{}
 
-		// We pass the URL so the ffmpeg will smartly use HTTP Range requests to get the exact frame.
-		err = ffmpeg_go.Input(downloadURL.String(), ffmpeg_go.KwArgs{
-			"ss":      timeStr, // The Timestamp to extract the thumbnail from
-			"y":       "",      // Overwrite the output file if exists
-			"timeout": "40",    // Timeout for whole op execution
-		}).Output(opPath, ffmpeg_go.KwArgs{
-			"vframes": 1,                                                                                                                                                                                // How many frames to output
-			"s":       fmt.Sprintf("%dx%d", thumbnailWidth, thumbnailHeight),                                                                                                                            // Pass the thumbnail dimensions here
-			"q:v":     3,                                                                                                                                                                                // Quality of the thumbnail
-			"vf":      fmt.Sprintf("thumbnail,scale=w=%[1]s:h=%[2]s:force_original_aspect_ratio=decrease,pad=%[1]s:%[2]s:(ow-iw)/2:(oh-ih)/2", fmt.Sprint(thumbnailWidth), fmt.Sprint(thumbnailHeight)), // Apply thumbnail filter, scale, maintain aspect ratio
-		}).OverWriteOutput().Run()
+	err = DecodeJobPayload(job, &payload)
+	if err != nil {
+		return
+	}
 
-		// Perform cleanup of the temporary file
-		defer os.Remove(opPath)
+	err = s.PerformPostUploadProcessing(ctx, payload.Slug)
 
-		if err != nil {
-			// Silence the error if ffmpeg fails to generate the thumbnail.
-			err = nil
-			continue
-		}
+	// Nothing to retry when the video is gone or has already left the processing state.
+	if err == fluxerrors.ErrVideoNotFound || err == fluxerrors.ErrInvalidVideoStatus {
+		s.l.With("slug", payload.Slug).With("job_id", job.ID.String()).Info("Skipping video processing job", "reason", err.Error())
+		err = nil
+	}
 
-		fileStat, err := os.Stat(opPath)
-		if err != nil {
-			// If the file does not exist, we can skip this thumbnail.
-			err = nil
-			continue
-		}
+	return
+}
 
-		thumbnail := model.Thumbnail{
-			VideoID:   videoMeta.ID,
-			Width:     uint16(thumbnailWidth),
-			Height:    uint16(thumbnailHeight),
-			Size:      uint32(fileStat.Size() / 1024), // Size in KB
-			Format:    thumbnailFormat,
-			TimeStamp: timestamp,
-			IsDefault: idx == 0, // Set the first thumbnail as default
-		}
+// Performs the post upload processing of the video stage by stage. Processing resumes after the last
+// stage recorded as successful in the internal status of the video.
+func (s *VideoService) PerformPostUploadProcessing(ctx context.Context, slug string) (err error) {
+	logger := s.l.With("slug", slug)
 
-		url, err := s.videRepo.GenerateThumbnailUploadURL(ctx, thumbnail.VideoID, thumbnail.TimeStamp, thumbnailFormat)
-		if err != nil {
-			err = nil
-			continue
+	videoMeta, err := s.videRepo.GetVideoBySlug(ctx, slug)
+	if err != nil {
+		if err == fluxerrors.ErrVideoNotFound {
+			logger.Error("Video not found for processing", err)
+			return
 		}
+		logger.Error("Failed to get video by slug", err)
+		err = fluxerrors.ErrUnknown
+		return
+	}
 
-		thumbFile, err := os.Open(opPath)
-		if err != nil {
-			err = nil
-			continue
-		}
+	logger = logger.With("video_id", videoMeta.ID.String())
 
-		defer thumbFile.Close()
+	if videoMeta.Status != model.VideoStatusProcessing && videoMeta.Status != model.VideoStatusProcessingDelay {
+		err = fluxerrors.ErrInvalidVideoStatus
+		logger.Error("Invalid video status for processing", err)
+		return
+	}
 
-		uploadReq, err := http.NewRequest(http.MethodPut, url.String(), thumbFile)
+	// The video was waiting for a stage retry.
+	if videoMeta.Status == model.VideoStatusProcessingDelay {
+		err = s.videRepo.UpdateStatus(ctx, videoMeta.ID, model.VideoStatusProcessing)
 		if err != nil {
-			err = nil
-			continue
+			logger.Error("Failed to move the video back to processing", err)
+			return
 		}
+	}
 
-		uploadReq.Header.Set("Content-Type", fmt.Sprintf("image/%s", thumbnailFormat))
-		uploadReq.ContentLength = fileStat.Size()
+	stages := s.processingStages()
+	startIdx := resumeStageIndex(stages, videoMeta)
 
-		resp, err := client.Do(uploadReq)
-		if err != nil {
-			err = nil
-			continue
-		}
+	// The attempts recorded for a skipped stage do not count against the stage after it.
+	if startIdx > 0 && stages[startIdx-1].isSkipped(videoMeta) {
+		videoMeta.StageAttempts = 0
+	}
 
-		if !(resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNoContent) {
-			resp.Body.Close()
-			continue
+	logger.Info("Starting post-upload processing", "internal_status", videoMeta.InternalStatus.String(), "resume_stage", startIdx)
+
+	downloadURL, err := s.videRepo.GetUnProcessedVideoDownloadURL(ctx, videoMeta.Slug)
+	if err != nil {
+		if err == fluxerrors.ErrVideoURLGenerationFailed {
+			logger.Error("Failed to generate video download URL", err)
+			return
 		}
+		logger.Error("Unknown error getting video download URL", err)
+		err = fluxerrors.ErrUnknown
+		return
+	}
 
-		resp.Body.Close()
+	state := &processingState{
+		video:       videoMeta,
+		downloadURL: downloadURL.String(),
+	}
 
-		thumbnail.StoragePath = fmt.Sprintf("%s.%s", utils.CreateURLSafeThumbnailFileName(thumbnail.VideoID.String(), fmt.Sprint(thumbnail.TimeStamp)), thumbnailFormat)
+	for i, stage := range stages[startIdx:] {
+		stageLogger := logger.With("stage", stage.name)
+		stageLogger.Info("Running processing stage")
 
-		_, err = s.videRepo.CreateThumbnail(ctx, thumbnail)
+		err = stage.run(ctx, stageLogger, state)
 		if err != nil {
-			err = nil // Ignore the error if thumbnail creation fails.
-			continue
-		}
+			stageLogger.Error("Processing stage failed", err)
 
-		successThumbnailCount++
-	}
+			// The worker is shutting down so the attempt is not held against the stage.
+			if ctx.Err() != nil {
+				return
+			}
 
-	logger.Info("Video processing completed successfully", "thumbnails_created", successThumbnailCount)
-	return
-}
+			skip, failureErr := s.handleStageFailure(ctx, stageLogger, state, stage, err)
+			if !skip {
+				err = failureErr
+				return
+			}
 
-func (v *VideoService) generateDistinctTimestamps(videoDuration uint64) []uint64 {
-	if videoDuration < 4 {
-		if videoDuration < 2 {
-			return []uint64{videoDuration}
+			s.publishProgress(state.video, stage.failedStatus, processingProgress(startIdx+i+1, len(stages)))
+			err = nil
+			continue
 		}
-		step := videoDuration / 3
-		return []uint64{step, 2 * step, videoDuration}
-	}
-
-	// Divide video into 3 segments and pick a random time from each segment
-	segmentDuration := videoDuration / 4 // Use 4 segments to avoid the very beginning and end
 
-	timestamps := make([]uint64, 3)
-
-	// First thumbnail from first quarter (excluding first 5% of video)
-	minTime1 := uint64(float64(videoDuration) * 0.05)
-	maxTime1 := segmentDuration
-	timestamps[0] = minTime1 + uint64(rand.Int63n(int64(maxTime1-minTime1)))
+		err = s.videRepo.AdvanceProcessingStage(ctx, videoMeta.ID, stage.doneStatus)
+		if err != nil {
+			stageLogger.Error("Failed to record the stage outcome", err)
+			return
+		}
 
-	// Second thumbnail from middle section
-	minTime2 := segmentDuration * 1
-	maxTime2 := segmentDuration * 2
-	timestamps[1] = minTime2 + uint64(rand.Int63n(int64(maxTime2-minTime2)))
+		state.video.InternalStatus = stage.doneStatus
+		state.video.StageAttempts = 0
 
-	// Third thumbnail from later section (avoiding last 5% of video)
-	minTime3 := segmentDuration * 2
-	maxTime3 := uint64(float64(videoDuration) * 0.95)
-	timestamps[2] = minTime3 + uint64(rand.Int63n(int64(maxTime3-minTime3)))
+		s.publishStage(state.video, stage.doneStatus, "")
+		s.publishProgress(state.video, stage.doneStatus, processingProgress(startIdx+i+1, len(stages)))
+	}
 
-	return timestamps
+	logger.Info("Video processing completed successfully")
+	return
 }
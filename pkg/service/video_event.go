@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fluxio-backend/pkg/pubsub"
+	"strings"
+	"time"
+)
+
+// Subscribes the owner of the video to its events and returns the current state of the video. The events of
+// every process reach the subscription while RelayVideoEvents runs. The subscription has to be closed by the caller.
+func (s *VideoService) SubscribeVideoEvents(ctx context.Context, slug string, user model.User) (video model.Video, sub *pubsub.Subscription[model.VideoEvent], err error) {
+	logger := s.l.With("slug", slug).With("user_id", user.ID.String())
+
+	if strings.EqualFold(slug, "") {
+		err = fluxerrors.ErrInvalidVideoSlug
+		return
+	}
+
+	video, err = s.videRepo.GetVideoBySlug(ctx, slug)
+	if err != nil {
+		if err == fluxerrors.ErrVideoNotFound {
+			return
+		}
+		logger.Error("Failed to get video by slug", err)
+		err = fluxerrors.ErrUnknown
+		return
+	}
+
+	if !strings.EqualFold(video.UserID.String(), user.ID.String()) {
+		video = model.Video{}
+		err = fluxerrors.ErrVideoNotFound
+		return
+	}
+
+	sub = s.events.Subscribe(video.ID.String())
+
+	// Read the video again so that a change made before the subscription is part of the returned video.
+	video, err = s.videRepo.GetVideoBySlug(ctx, slug)
+	if err != nil {
+		sub.Close()
+		sub = nil
+		video = model.Video{}
+		return
+	}
+
+	return
+}
+
+// Returns the stored state of a video as a status event so that a watcher can catch up on missed events.
+func (s *VideoService) GetVideoStatusEvent(ctx context.Context, slug string) (event model.VideoEvent, err error) {
+	video, err := s.videRepo.GetVideoBySlug(ctx, slug)
+	if err != nil {
+		return
+	}
+
+	event = model.NewVideoStatusEvent(video)
+	return
+}
+
+// Passes the video events published by every process to the local subscribers until the context is cancelled.
+// The events sent while the listening connection is down are missed.
+func (s *VideoService) RelayVideoEvents(ctx context.Context) {
+	for {
+		err := s.videRepo.ListenVideoEvents(ctx, func(event model.VideoEvent) {
+			s.events.Publish(event.VideoID.String(), event)
+		})
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.l.Error("Listening to the video events failed, listening again", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(constants.VideoEventListenRetryDelay):
+		}
+	}
+}
+
+// Moves the video to the new status when it is still in the old one. The watchers learn about the change
+// from the notification sent along with the update.
+func (s *VideoService) transitionStatus(ctx context.Context, video model.Video, from model.VideoStatus, to model.VideoStatus, reason string) (transitioned bool, err error) {
+	return s.videRepo.TransitionStatus(ctx, video.ID, from, to, reason)
+}
+
+func (s *VideoService) publishStage(video model.Video, stage model.VideoInternalStatus, message string) {
+	s.publishVideoEvent(model.VideoEvent{
+		Type:    model.VideoEventStage,
+		VideoID: video.ID,
+		Slug:    video.Slug,
+		Stage:   stage,
+		Message: message,
+	})
+}
+
+func (s *VideoService) publishProgress(video model.Video, stage model.VideoInternalStatus, progress float64) {
+	s.publishVideoEvent(model.VideoEvent{
+		Type:     model.VideoEventProgress,
+		VideoID:  video.ID,
+		Slug:     video.Slug,
+		Stage:    stage,
+		Progress: &progress,
+	})
+}
+
+func (s *VideoService) publishStageProgress(video model.Video, progress model.VideoProgress) {
+	s.publishVideoEvent(model.VideoEvent{
+		Type:          model.VideoEventStageProgress,
+		VideoID:       video.ID,
+		Slug:          video.Slug,
+		StageProgress: &progress,
+		Message:       progress.String(),
+	})
+}
+
+func (s *VideoService) publishThumbnail(video model.Video, thumbnail model.Thumbnail) {
+	s.publishVideoEvent(model.VideoEvent{
+		Type:      model.VideoEventThumbnail,
+		VideoID:   video.ID,
+		Slug:      video.Slug,
+		Thumbnail: &thumbnail,
+	})
+}
+
+// Sends the event through the database so that the watchers connected to any process receive it. An event
+// which cannot be sent is dropped since the watchers catch up from the stored state of the video.
+func (s *VideoService) publishVideoEvent(event model.VideoEvent) {
+	event.CreatedAt = time.Now()
+
+	err := s.videRepo.PublishVideoEvent(context.Background(), event)
+	if err != nil {
+		s.l.With("video_id", event.VideoID.String()).With("error", err.Error()).Warn("Failed to publish the video event")
+	}
+}
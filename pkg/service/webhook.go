@@ -0,0 +1,430 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fluxio-backend/pkg/common/schema"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fluxio-backend/pkg/repository"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+type WebhookService struct {
+	webhookRepo *repository.WebhookRepository
+	jobSvc      *JobService
+	client      *http.Client
+	l           schema.Logger
+}
+
+func NewWebhookService(webhookRepo *repository.WebhookRepository, jobSvc *JobService, logger schema.Logger) *WebhookService {
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+		jobSvc:      jobSvc,
+		client:      newWebhookClient(),
+		l:           logger,
+	}
+}
+
+// Registers a webhook endpoint of the user. A secret is generated when none is given and is only
+// returned here since every later read leaves it out.
+func (s *WebhookService) CreateSubscription(ctx context.Context, user model.User, subscription model.WebhookSubscription) (created model.WebhookSubscription, err error) {
+	logger := s.l.With("user_id", user.ID.String())
+
+	endpoint, err := validateWebhookURL(subscription.URL)
+	if err != nil {
+		return
+	}
+
+	if len(subscription.EventTypes) == 0 {
+		err = fluxerrors.ErrInvalidWebhookEventType
+		return
+	}
+
+	seen := map[model.WebhookEventType]bool{}
+	eventTypes := make([]model.WebhookEventType, 0, len(subscription.EventTypes))
+	for _, eventType := range subscription.EventTypes {
+		if !eventType.IsAcceptable() {
+			err = fluxerrors.ErrInvalidWebhookEventType
+			return
+		}
+
+		if !seen[eventType] {
+			seen[eventType] = true
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+
+	if strings.EqualFold(subscription.Secret, "") {
+		subscription.Secret, err = generateWebhookSecret()
+		if err != nil {
+			logger.Error("Failed to generate the webhook secret", err)
+			return
+		}
+	}
+
+	if len(subscription.Secret) < constants.MinWebhookSecretLength {
+		err = fluxerrors.ErrWebhookSecretTooShort
+		return
+	}
+
+	count, err := s.webhookRepo.CountSubscriptions(ctx, user.ID)
+	if err != nil {
+		return
+	}
+
+	if count >= constants.MaxWebhookSubscriptionsPerUser {
+		err = fluxerrors.ErrWebhookLimitReached
+		return
+	}
+
+	created, err = s.webhookRepo.CreateSubscription(ctx, model.WebhookSubscription{
+		UserID:     user.ID,
+		URL:        endpoint.String(),
+		Secret:     subscription.Secret,
+		EventTypes: eventTypes,
+	})
+	if err != nil {
+		return
+	}
+
+	logger.Info("Webhook subscription created", "subscription_id", created.ID.String())
+	return
+}
+
+func (s *WebhookService) ListSubscriptions(ctx context.Context, user model.User) (subscriptions []model.WebhookSubscription, err error) {
+	return s.webhookRepo.ListSubscriptions(ctx, user.ID)
+}
+
+func (s *WebhookService) DeleteSubscription(ctx context.Context, user model.User, id model.WebhookSubscriptionID) (err error) {
+	err = s.webhookRepo.DeleteSubscription(ctx, id, user.ID)
+	if err != nil {
+		return
+	}
+
+	s.l.With("user_id", user.ID.String()).Info("Webhook subscription deleted", "subscription_id", id.String())
+	return
+}
+
+// Returns the delivery log of a subscription of the user.
+func (s *WebhookService) ListDeliveries(ctx context.Context, user model.User, id model.WebhookSubscriptionID, limit int, offset int) (deliveries []model.WebhookDelivery, err error) {
+	if limit <= 0 {
+		limit = constants.DefaultWebhookDeliveryPageSize
+	}
+
+	if limit > constants.MaxWebhookDeliveryPageSize {
+		limit = constants.MaxWebhookDeliveryPageSize
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	// Tell a missing subscription apart from one without deliveries.
+	subscription, err := s.webhookRepo.GetSubscription(ctx, id)
+	if err != nil {
+		return
+	}
+
+	if !strings.EqualFold(subscription.UserID.String(), user.ID.String()) {
+		err = fluxerrors.ErrWebhookSubscriptionNotFound
+		return
+	}
+
+	return s.webhookRepo.ListDeliveries(ctx, id, user.ID, limit, offset)
+}
+
+// Turns the events in the outbox into deliveries and queues a job for each of them. Deliveries whose job
+// was never queued are picked up again once they are old enough.
+func (s *WebhookService) RelayWebhookEvents(ctx context.Context) (err error) {
+	deliveries, err := s.webhookRepo.RelayEvents(ctx, constants.WebhookRelayBatchSize)
+	if err != nil {
+		return
+	}
+
+	stranded, err := s.webhookRepo.ListStrandedDeliveries(ctx, time.Now().Add(-constants.WebhookStrandedDeliveryAge), constants.WebhookRelayBatchSize)
+	if err != nil {
+		return
+	}
+
+	for _, delivery := range append(deliveries, stranded...) {
+		_, enqueueErr := s.jobSvc.Enqueue(ctx, model.JobTypeWebhookDelivery, model.WebhookDeliveryJobPayload{
+			DeliveryID: delivery.ID.String(),
+		}, webhookDeliveryJobKey(delivery.ID), constants.MaxWebhookDeliveryJobAttempts)
+
+		if enqueueErr != nil && enqueueErr != fluxerrors.ErrJobAlreadyQueued {
+			s.l.With("delivery_id", delivery.ID.String()).Error("Failed to queue the webhook delivery", enqueueErr)
+		}
+	}
+
+	return
+}
+
+// Runs a queued webhook delivery. Failed attempts are retried with an exponential backoff until the
+// delivery runs out of attempts.
+func (s *WebhookService) HandleWebhookDeliveryJob(ctx context.Context, job model.Job) (err error) {
+	payload := model.WebhookDeliveryJobPayload{}
+
+	err = DecodeJobPayload(job, &payload)
+	if err != nil {
+		return
+	}
+
+	logger := s.l.With("delivery_id", payload.DeliveryID).With("job_id", job.ID.String())
+
+	delivery, err := s.webhookRepo.GetDelivery(ctx, model.WebhookDeliveryID(payload.DeliveryID))
+	if err != nil {
+		if err == fluxerrors.ErrWebhookDeliveryNotFound {
+			logger.Info("Skipping a missing webhook delivery")
+			err = nil
+		}
+		return
+	}
+
+	if delivery.Status != model.WebhookDeliveryPending {
+		logger.Info("Skipping webhook delivery", "status", delivery.Status.String())
+		return
+	}
+
+	subscription, err := s.webhookRepo.GetSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		if err != fluxerrors.ErrWebhookSubscriptionNotFound {
+			return
+		}
+
+		// The subscription was deleted after the event was relayed.
+		delivery.Status = model.WebhookDeliveryFailed
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = nil
+		err = s.webhookRepo.RecordDeliveryAttempt(ctx, delivery)
+		return
+	}
+
+	delivery.Attempts++
+	delivery.ResponseStatus, err = s.deliverWebhook(ctx, subscription, delivery)
+
+	if err == nil {
+		deliveredAt := time.Now()
+		delivery.Status = model.WebhookDeliverySucceeded
+		delivery.LastError = ""
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &deliveredAt
+
+		err = s.webhookRepo.RecordDeliveryAttempt(ctx, delivery)
+		if err != nil {
+			return
+		}
+
+		logger.Info("Webhook delivered", "attempt", delivery.Attempts)
+		return
+	}
+
+	// The worker is shutting down so the attempt is not held against the delivery.
+	if ctx.Err() != nil {
+		return
+	}
+
+	deliveryErr := err
+	delivery.LastError = deliveryErr.Error()
+
+	if delivery.Attempts >= constants.MaxWebhookDeliveryAttempts {
+		delivery.Status = model.WebhookDeliveryFailed
+		delivery.NextAttemptAt = nil
+
+		err = s.webhookRepo.RecordDeliveryAttempt(ctx, delivery)
+		if err != nil {
+			return
+		}
+
+		logger.Warn("Webhook delivery ran out of attempts")
+		err = &model.JobPermanentError{Err: deliveryErr}
+		return
+	}
+
+	runAt := time.Now().Add(webhookRetryDelay(delivery.Attempts))
+	delivery.NextAttemptAt = &runAt
+
+	err = s.webhookRepo.RecordDeliveryAttempt(ctx, delivery)
+	if err != nil {
+		return
+	}
+
+	logger.Info("Webhook delivery scheduled for retry", "attempt", delivery.Attempts, "run_at", runAt)
+	err = &model.JobRetryError{Err: deliveryErr, RunAt: runAt}
+	return
+}
+
+// Posts the signed payload to the subscription. Any response outside of 2xx counts as a failure.
+func (s *WebhookService) deliverWebhook(ctx context.Context, subscription model.WebhookSubscription, delivery model.WebhookDelivery) (responseStatus int, err error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		err = fluxerrors.ErrInvalidWebhookURL
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(constants.WebhookEventHeader, delivery.EventType.String())
+	req.Header.Set(constants.WebhookDeliveryHeader, delivery.ID.String())
+	req.Header.Set(constants.CallbackTimestampHeader, timestamp)
+	req.Header.Set(constants.CallbackSignatureHeader, signWebhookPayload(subscription.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", fluxerrors.ErrWebhookDeliveryFailed, err.Error())
+		return
+	}
+	defer resp.Body.Close()
+
+	// Drain a little of the body so that the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	responseStatus = resp.StatusCode
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err = fmt.Errorf("%w: endpoint responded with %d", fluxerrors.ErrWebhookDeliveryFailed, resp.StatusCode)
+		return
+	}
+
+	return
+}
+
+// Returns a client which only connects to public addresses. The check runs on the resolved address right
+// before every connection so that a host name which resolves to an internal address, even after the URL was
+// validated, cannot be reached.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: constants.WebhookDeliveryTimeout,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return fluxerrors.ErrWebhookURLNotAllowed
+			}
+
+			addr, err := netip.ParseAddr(host)
+			if err != nil || !isPublicWebhookAddr(addr) {
+				return fluxerrors.ErrWebhookURLNotAllowed
+			}
+
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // A proxy would connect to the endpoint without the check
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   constants.WebhookDeliveryTimeout,
+		Transport: transport,
+		// Redirects are not followed so that the payload only reaches the registered URL.
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Parses the URL of a webhook endpoint. Host names are checked again at every delivery since they can
+// resolve to another address later, literal addresses are rejected right away.
+func validateWebhookURL(rawURL string) (endpoint *url.URL, err error) {
+	endpoint, err = url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || strings.EqualFold(endpoint.Hostname(), "") {
+		endpoint = nil
+		err = fluxerrors.ErrInvalidWebhookURL
+		return
+	}
+
+	hostname := strings.TrimSuffix(strings.ToLower(endpoint.Hostname()), ".")
+	if hostname == "localhost" || strings.HasSuffix(hostname, ".localhost") {
+		endpoint = nil
+		err = fluxerrors.ErrWebhookURLNotAllowed
+		return
+	}
+
+	addr, parseErr := netip.ParseAddr(hostname)
+	if parseErr == nil && !isPublicWebhookAddr(addr) {
+		endpoint = nil
+		err = fluxerrors.ErrWebhookURLNotAllowed
+		return
+	}
+
+	return
+}
+
+// Ranges which are neither public nor covered by the checks of netip.Addr.
+var reservedWebhookPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // This network
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // Reserved, includes the broadcast address
+}
+
+// Reports whether a webhook may be delivered to the address. Loopback, private, link-local (which includes
+// the cloud metadata endpoints), unspecified, multicast and reserved addresses are refused.
+func isPublicWebhookAddr(addr netip.Addr) bool {
+	addr = addr.Unmap() // An IPv4-mapped IPv6 address reaches the IPv4 address
+
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return false
+	}
+
+	for _, prefix := range reservedWebhookPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Signs the payload the same way the storage callbacks are signed, an HMAC-SHA256 over "<timestamp>.<body>".
+func signWebhookPayload(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func generateWebhookSecret() (secret string, err error) {
+	raw := make([]byte, constants.WebhookSecretLength)
+
+	_, err = rand.Read(raw)
+	if err != nil {
+		return
+	}
+
+	secret = "whsec_" + hex.EncodeToString(raw)
+	return
+}
+
+// Returns the delay before the given attempt of a delivery is retried.
+func webhookRetryDelay(attempt uint32) time.Duration {
+	delay := constants.WebhookRetryBaseDelay
+	for i := uint32(1); i < attempt; i++ {
+		delay *= 2
+		if delay >= constants.WebhookRetryMaxDelay {
+			return constants.WebhookRetryMaxDelay
+		}
+	}
+
+	return delay
+}
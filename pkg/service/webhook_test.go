@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestValidateWebhookURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantURL string
+		wantErr error
+	}{
+		{name: "accepts an https url", url: "https://hooks.example.com/fluxio", wantURL: "https://hooks.example.com/fluxio"},
+		{name: "accepts an http url with a port", url: "http://hooks.example.com:8080/fluxio", wantURL: "http://hooks.example.com:8080/fluxio"},
+		{name: "trims the surrounding spaces", url: "  https://hooks.example.com  ", wantURL: "https://hooks.example.com"},
+		{name: "accepts a public address", url: "https://93.184.216.34/fluxio", wantURL: "https://93.184.216.34/fluxio"},
+		{name: "rejects another scheme", url: "ftp://hooks.example.com", wantErr: fluxerrors.ErrInvalidWebhookURL},
+		{name: "rejects a relative url", url: "/fluxio", wantErr: fluxerrors.ErrInvalidWebhookURL},
+		{name: "rejects a url without a host", url: "https://:8080/fluxio", wantErr: fluxerrors.ErrInvalidWebhookURL},
+		{name: "rejects an unparsable url", url: "https://hooks.example.com/%zz", wantErr: fluxerrors.ErrInvalidWebhookURL},
+		{name: "rejects localhost", url: "http://localhost:8080", wantErr: fluxerrors.ErrWebhookURLNotAllowed},
+		{name: "rejects a localhost subdomain", url: "http://api.localhost.", wantErr: fluxerrors.ErrWebhookURLNotAllowed},
+		{name: "rejects a loopback address", url: "http://127.0.0.1", wantErr: fluxerrors.ErrWebhookURLNotAllowed},
+		{name: "rejects an IPv6 loopback address", url: "http://[::1]:8080", wantErr: fluxerrors.ErrWebhookURLNotAllowed},
+		{name: "rejects a private address", url: "http://10.0.0.5", wantErr: fluxerrors.ErrWebhookURLNotAllowed},
+		{name: "rejects another private address", url: "http://192.168.1.1", wantErr: fluxerrors.ErrWebhookURLNotAllowed},
+		{name: "rejects an IPv6 unique local address", url: "http://[fd00::1]", wantErr: fluxerrors.ErrWebhookURLNotAllowed},
+		{name: "rejects the metadata endpoint", url: "http://169.254.169.254/latest/meta-data", wantErr: fluxerrors.ErrWebhookURLNotAllowed},
+		{name: "rejects an IPv4-mapped private address", url: "http://[::ffff:10.0.0.5]", wantErr: fluxerrors.ErrWebhookURLNotAllowed},
+		{name: "rejects the unspecified address", url: "http://0.0.0.0", wantErr: fluxerrors.ErrWebhookURLNotAllowed},
+		{name: "rejects a carrier-grade NAT address", url: "http://100.64.0.1", wantErr: fluxerrors.ErrWebhookURLNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint, err := validateWebhookURL(tt.url)
+			if err != tt.wantErr {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && endpoint.String() != tt.wantURL {
+				t.Errorf("got url %s, want %s", endpoint.String(), tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	body := []byte(`{"event":"video.completed"}`)
+	want := "sha256=9ba41e4459648fc02948eff0d180c94d6fb26e92295b880b39cf3dd6d16ce014"
+
+	if got := signWebhookPayload("whsec_test", "1700000000", body); got != want {
+		t.Fatalf("got signature %s, want %s", got, want)
+	}
+
+	if signWebhookPayload("whsec_test", "1700000001", body) == want {
+		t.Errorf("signature does not cover the timestamp")
+	}
+
+	if signWebhookPayload("whsec_other", "1700000000", body) == want {
+		t.Errorf("signature does not depend on the secret")
+	}
+}
+
+func TestDeliverWebhookRefusesInternalAddresses(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	svc := &WebhookService{client: newWebhookClient()}
+
+	// The subscription URL skips the validation the same way a host name which resolves to a loopback address would.
+	_, err := svc.deliverWebhook(context.Background(), model.WebhookSubscription{URL: server.URL, Secret: "whsec_test"}, model.WebhookDelivery{
+		EventType: model.WebhookEventVideoCompleted,
+		Payload:   []byte(`{}`),
+	})
+
+	if !errors.Is(err, fluxerrors.ErrWebhookDeliveryFailed) {
+		t.Fatalf("got error %v, want %v", err, fluxerrors.ErrWebhookDeliveryFailed)
+	}
+
+	if requests.Load() != 0 {
+		t.Errorf("the internal endpoint received %d requests", requests.Load())
+	}
+}
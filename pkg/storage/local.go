@@ -0,0 +1,599 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query parameters of the URLs signed by the local store.
+const (
+	LocalExpiresParam    = "X-Fluxio-Expires"
+	LocalSignatureParam  = "X-Fluxio-Signature"
+	LocalUploadIDParam   = "uploadId"
+	LocalPartNumberParam = "partNumber"
+)
+
+// Directories inside the root which never hold bucket contents.
+const (
+	localMetaDir    = ".meta"
+	localUploadsDir = ".uploads"
+	localTempDir    = ".tmp"
+)
+
+type LocalConfig struct {
+	Root          string   // Directory every bucket is stored under
+	BaseURL       string   // URL the storage handler of the API is reachable at
+	SigningSecret string   // Secret the URLs handed out to clients are signed with
+	PublicBuckets []string // Buckets which can be read without a signed URL
+}
+
+// LocalStore keeps the objects on the local disk and serves them through the storage handler of the API
+// so that a development setup does not need an S3 compatible storage.
+type LocalStore struct {
+	root          string
+	baseURL       *url.URL
+	secret        []byte
+	publicBuckets []string
+}
+
+type localObjectMeta struct {
+	ContentType string `json:"content_type"`
+	ETag        string `json:"etag"`
+}
+
+type localUploadMeta struct {
+	Bucket      string `json:"bucket"`
+	Key         string `json:"key"`
+	ContentType string `json:"content_type"`
+}
+
+func NewLocalStore(cfg LocalConfig) (store *LocalStore, err error) {
+	if strings.EqualFold(cfg.SigningSecret, "") {
+		err = fluxerrors.ErrStorageSigningKeyRequired
+		return
+	}
+
+	baseURL, err := url.Parse(strings.TrimRight(cfg.BaseURL, "/"))
+	if err != nil {
+		return
+	}
+
+	root, err := filepath.Abs(cfg.Root)
+	if err != nil {
+		return
+	}
+
+	err = os.MkdirAll(filepath.Join(root, localTempDir), 0o755)
+	if err != nil {
+		return
+	}
+
+	store = &LocalStore{
+		root:          root,
+		baseURL:       baseURL,
+		secret:        []byte(cfg.SigningSecret),
+		publicBuckets: cfg.PublicBuckets,
+	}
+	return
+}
+
+func (s *LocalStore) Put(ctx context.Context, bucket string, key string, body io.Reader, contentType string) (err error) {
+	objectPath, err := s.objectPath(bucket, key)
+	if err != nil {
+		return
+	}
+
+	tempPath, etag, err := s.writeTempFile(body)
+	if err != nil {
+		return
+	}
+
+	return s.commitObject(tempPath, objectPath, bucket, key, localObjectMeta{
+		ContentType: contentType,
+		ETag:        etag,
+	})
+}
+
+func (s *LocalStore) Get(ctx context.Context, bucket string, key string) (body io.ReadCloser, info ObjectInfo, err error) {
+	return s.Open(bucket, key)
+}
+
+// Opens an object for reading. The file can be seeked so that range requests can be served from it.
+func (s *LocalStore) Open(bucket string, key string) (file *os.File, info ObjectInfo, err error) {
+	info, err = s.Head(context.Background(), bucket, key)
+	if err != nil {
+		return
+	}
+
+	objectPath, err := s.objectPath(bucket, key)
+	if err != nil {
+		return
+	}
+
+	file, err = os.Open(objectPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		err = fluxerrors.ErrObjectNotFound
+	}
+	return
+}
+
+func (s *LocalStore) Head(ctx context.Context, bucket string, key string) (info ObjectInfo, err error) {
+	objectPath, err := s.objectPath(bucket, key)
+	if err != nil {
+		return
+	}
+
+	stat, err := os.Stat(objectPath)
+	if err != nil || stat.IsDir() {
+		err = fluxerrors.ErrObjectNotFound
+		return
+	}
+
+	meta := localObjectMeta{}
+	rawMeta, metaErr := os.ReadFile(s.metaPath(bucket, key))
+	if metaErr == nil {
+		_ = json.Unmarshal(rawMeta, &meta)
+	}
+
+	info = ObjectInfo{
+		Key:          cleanObjectKey(key),
+		Size:         stat.Size(),
+		ContentType:  meta.ContentType,
+		ETag:         meta.ETag,
+		LastModified: stat.ModTime(),
+	}
+	return
+}
+
+// Deleting a missing object succeeds like it does on S3.
+func (s *LocalStore) Delete(ctx context.Context, bucket string, key string) (err error) {
+	objectPath, err := s.objectPath(bucket, key)
+	if err != nil {
+		return
+	}
+
+	err = os.Remove(objectPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+
+	err = os.Remove(s.metaPath(bucket, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+	}
+	return
+}
+
+func (s *LocalStore) List(ctx context.Context, bucket string, prefix string, startAfter string, limit int) (objects []ObjectInfo, err error) {
+	bucketPath, err := s.bucketPath(bucket)
+	if err != nil {
+		return
+	}
+
+	// The walk is in the order of the path elements which differs from the order of the keys, so every
+	// key is collected before the page is cut out.
+	keys := []string{}
+
+	err = filepath.WalkDir(bucketPath, func(filePath string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			if errors.Is(walkErr, fs.ErrNotExist) {
+				return nil
+			}
+			return walkErr
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(bucketPath, filePath)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(relPath)
+		if !strings.HasPrefix(key, prefix) || key <= startAfter {
+			return nil
+		}
+
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if len(objects) >= limit {
+			break
+		}
+
+		info, headErr := s.Head(ctx, bucket, key)
+		if headErr != nil {
+			err = headErr
+			return
+		}
+
+		objects = append(objects, info)
+	}
+
+	return
+}
+
+func (s *LocalStore) PresignPut(ctx context.Context, bucket string, key string, contentType string, expiry time.Duration) (url *url.URL, err error) {
+	return s.sign("PUT", bucket, key, "", 0, expiry)
+}
+
+func (s *LocalStore) PresignGet(ctx context.Context, bucket string, key string, expiry time.Duration) (url *url.URL, err error) {
+	return s.sign("GET", bucket, key, "", 0, expiry)
+}
+
+func (s *LocalStore) BucketURL(bucket string) (url *url.URL) {
+	return s.baseURL.JoinPath(bucket)
+}
+
+// Checks whether the objects of the bucket can be read without a signed URL.
+func (s *LocalStore) IsPublicBucket(bucket string) bool {
+	return slices.Contains(s.publicBuckets, bucket)
+}
+
+// Verifies a request made with a URL signed by the store. The method is part of the signature so that a
+// download URL cannot be used to upload.
+func (s *LocalStore) VerifySignedRequest(method string, bucket string, key string, query url.Values) (err error) {
+	expires, err := strconv.ParseInt(query.Get(LocalExpiresParam), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		err = fluxerrors.ErrInvalidStorageSignature
+		return
+	}
+
+	// A HEAD request is allowed with the URL of a download.
+	if method == "HEAD" {
+		method = "GET"
+	}
+
+	var partNumber int64
+	if query.Has(LocalPartNumberParam) {
+		partNumber, err = strconv.ParseInt(query.Get(LocalPartNumberParam), 10, 64)
+		if err != nil {
+			err = fluxerrors.ErrInvalidStorageSignature
+			return
+		}
+	}
+
+	expected := s.signature(method, bucket, cleanObjectKey(key), query.Get(LocalUploadIDParam), partNumber, expires)
+	signature, err := hex.DecodeString(query.Get(LocalSignatureParam))
+	if err != nil || !hmac.Equal(signature, expected) {
+		err = fluxerrors.ErrInvalidStorageSignature
+		return
+	}
+
+	return
+}
+
+func (s *LocalStore) CreateMultipartUpload(ctx context.Context, bucket string, key string, contentType string) (uploadID string, err error) {
+	_, err = s.objectPath(bucket, key)
+	if err != nil {
+		return
+	}
+
+	rawID := make([]byte, 16)
+	_, err = rand.Read(rawID)
+	if err != nil {
+		return
+	}
+
+	uploadID = hex.EncodeToString(rawID)
+
+	err = os.MkdirAll(s.uploadPath(uploadID), 0o755)
+	if err != nil {
+		uploadID = ""
+		return
+	}
+
+	rawMeta, _ := json.Marshal(localUploadMeta{
+		Bucket:      bucket,
+		Key:         cleanObjectKey(key),
+		ContentType: contentType,
+	})
+
+	err = os.WriteFile(filepath.Join(s.uploadPath(uploadID), "upload.json"), rawMeta, 0o644)
+	if err != nil {
+		uploadID = ""
+	}
+	return
+}
+
+func (s *LocalStore) PresignUploadPart(ctx context.Context, bucket string, key string, uploadID string, partNumber int64, expiry time.Duration) (url *url.URL, err error) {
+	return s.sign("PUT", bucket, key, uploadID, partNumber, expiry)
+}
+
+func (s *LocalStore) UploadPart(ctx context.Context, bucket string, key string, uploadID string, partNumber int64, body io.ReadSeeker) (etag string, err error) {
+	return s.WritePart(bucket, key, uploadID, partNumber, body)
+}
+
+// Stores a part of a multipart upload. Uploading a part again replaces it.
+func (s *LocalStore) WritePart(bucket string, key string, uploadID string, partNumber int64, body io.Reader) (etag string, err error) {
+	_, err = s.getUpload(bucket, key, uploadID)
+	if err != nil {
+		return
+	}
+
+	tempPath, etag, err := s.writeTempFile(body)
+	if err != nil {
+		return
+	}
+
+	partPath := filepath.Join(s.uploadPath(uploadID), fmt.Sprintf("%d.part", partNumber))
+
+	err = os.WriteFile(partPath+".etag", []byte(etag), 0o644)
+	if err != nil {
+		os.Remove(tempPath)
+		etag = ""
+		return
+	}
+
+	err = os.Rename(tempPath, partPath)
+	if err != nil {
+		os.Remove(tempPath)
+		etag = ""
+	}
+	return
+}
+
+func (s *LocalStore) ListParts(ctx context.Context, bucket string, key string, uploadID string) (parts []Part, err error) {
+	_, err = s.getUpload(bucket, key, uploadID)
+	if err != nil {
+		return
+	}
+
+	entries, err := os.ReadDir(s.uploadPath(uploadID))
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		rawNumber, isPart := strings.CutSuffix(entry.Name(), ".part")
+		if !isPart {
+			continue
+		}
+
+		number, parseErr := strconv.ParseInt(rawNumber, 10, 64)
+		if parseErr != nil {
+			continue
+		}
+
+		partPath := filepath.Join(s.uploadPath(uploadID), entry.Name())
+
+		stat, statErr := os.Stat(partPath)
+		etag, etagErr := os.ReadFile(partPath + ".etag")
+		if statErr != nil || etagErr != nil {
+			continue
+		}
+
+		parts = append(parts, Part{
+			Number: number,
+			ETag:   string(etag),
+			Size:   stat.Size(),
+		})
+	}
+
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].Number < parts[j].Number
+	})
+	return
+}
+
+func (s *LocalStore) CompleteMultipartUpload(ctx context.Context, bucket string, key string, uploadID string, parts []Part) (err error) {
+	upload, err := s.getUpload(bucket, key, uploadID)
+	if err != nil {
+		return
+	}
+
+	objectPath, err := s.objectPath(bucket, key)
+	if err != nil {
+		return
+	}
+
+	readers := make([]io.Reader, 0, len(parts))
+	for _, part := range parts {
+		partPath := filepath.Join(s.uploadPath(uploadID), fmt.Sprintf("%d.part", part.Number))
+
+		etag, readErr := os.ReadFile(partPath + ".etag")
+		if readErr != nil || string(etag) != part.ETag {
+			err = fmt.Errorf("part %d does not match the uploaded part", part.Number)
+			return
+		}
+
+		var file *os.File
+		file, err = os.Open(partPath)
+		if err != nil {
+			return
+		}
+		defer file.Close()
+
+		readers = append(readers, file)
+	}
+
+	tempPath, etag, err := s.writeTempFile(io.MultiReader(readers...))
+	if err != nil {
+		return
+	}
+
+	err = s.commitObject(tempPath, objectPath, bucket, key, localObjectMeta{
+		ContentType: upload.ContentType,
+		ETag:        fmt.Sprintf("%s-%d", strings.Trim(etag, `"`), len(parts)),
+	})
+	if err != nil {
+		return
+	}
+
+	return os.RemoveAll(s.uploadPath(uploadID))
+}
+
+func (s *LocalStore) AbortMultipartUpload(ctx context.Context, bucket string, key string, uploadID string) (err error) {
+	_, err = s.getUpload(bucket, key, uploadID)
+	if err != nil {
+		return
+	}
+
+	return os.RemoveAll(s.uploadPath(uploadID))
+}
+
+func (s *LocalStore) getUpload(bucket string, key string, uploadID string) (upload localUploadMeta, err error) {
+	if strings.EqualFold(uploadID, "") || strings.ContainsAny(uploadID, `/\.`) {
+		err = fluxerrors.ErrMultipartUploadNotFound
+		return
+	}
+
+	rawMeta, err := os.ReadFile(filepath.Join(s.uploadPath(uploadID), "upload.json"))
+	if err != nil {
+		err = fluxerrors.ErrMultipartUploadNotFound
+		return
+	}
+
+	err = json.Unmarshal(rawMeta, &upload)
+	if err != nil || upload.Bucket != bucket || upload.Key != cleanObjectKey(key) {
+		upload = localUploadMeta{}
+		err = fluxerrors.ErrMultipartUploadNotFound
+		return
+	}
+
+	return
+}
+
+// Writes the body to a temporary file inside the root so that it can be renamed into place.
+func (s *LocalStore) writeTempFile(body io.Reader) (tempPath string, etag string, err error) {
+	file, err := os.CreateTemp(filepath.Join(s.root, localTempDir), "object-*")
+	if err != nil {
+		return
+	}
+
+	hash := md5.New()
+	_, err = io.Copy(io.MultiWriter(file, hash), body)
+	closeErr := file.Close()
+
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(file.Name())
+		return
+	}
+
+	tempPath = file.Name()
+	etag = fmt.Sprintf(`"%s"`, hex.EncodeToString(hash.Sum(nil)))
+	return
+}
+
+func (s *LocalStore) commitObject(tempPath string, objectPath string, bucket string, key string, meta localObjectMeta) (err error) {
+	defer os.Remove(tempPath)
+
+	err = os.MkdirAll(filepath.Dir(objectPath), 0o755)
+	if err != nil {
+		return
+	}
+
+	metaPath := s.metaPath(bucket, key)
+	err = os.MkdirAll(filepath.Dir(metaPath), 0o755)
+	if err != nil {
+		return
+	}
+
+	rawMeta, _ := json.Marshal(meta)
+	err = os.WriteFile(metaPath, rawMeta, 0o644)
+	if err != nil {
+		return
+	}
+
+	return os.Rename(tempPath, objectPath)
+}
+
+func (s *LocalStore) sign(method string, bucket string, key string, uploadID string, partNumber int64, expiry time.Duration) (signedURL *url.URL, err error) {
+	_, err = s.objectPath(bucket, key)
+	if err != nil {
+		return
+	}
+
+	key = cleanObjectKey(key)
+	expires := time.Now().Add(expiry).Unix()
+
+	query := url.Values{}
+	query.Set(LocalExpiresParam, strconv.FormatInt(expires, 10))
+	query.Set(LocalSignatureParam, hex.EncodeToString(s.signature(method, bucket, key, uploadID, partNumber, expires)))
+
+	if !strings.EqualFold(uploadID, "") {
+		query.Set(LocalUploadIDParam, uploadID)
+		query.Set(LocalPartNumberParam, strconv.FormatInt(partNumber, 10))
+	}
+
+	signedURL = s.baseURL.JoinPath(bucket, key)
+	signedURL.RawQuery = query.Encode()
+	return
+}
+
+func (s *LocalStore) signature(method string, bucket string, key string, uploadID string, partNumber int64, expires int64) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%d\n%d", method, bucket, key, uploadID, partNumber, expires)
+	return mac.Sum(nil)
+}
+
+func (s *LocalStore) bucketPath(bucket string) (bucketPath string, err error) {
+	if strings.EqualFold(bucket, "") || strings.HasPrefix(bucket, ".") || strings.ContainsAny(bucket, `/\`) {
+		err = fluxerrors.ErrInvalidObjectKey
+		return
+	}
+
+	bucketPath = filepath.Join(s.root, bucket)
+	return
+}
+
+func (s *LocalStore) objectPath(bucket string, key string) (objectPath string, err error) {
+	bucketPath, err := s.bucketPath(bucket)
+	if err != nil {
+		return
+	}
+
+	key = cleanObjectKey(key)
+	if strings.EqualFold(key, "") {
+		err = fluxerrors.ErrInvalidObjectKey
+		return
+	}
+
+	objectPath = filepath.Join(bucketPath, filepath.FromSlash(key))
+	return
+}
+
+func (s *LocalStore) metaPath(bucket string, key string) string {
+	return filepath.Join(s.root, localMetaDir, bucket, filepath.FromSlash(cleanObjectKey(key))+".json")
+}
+
+func (s *LocalStore) uploadPath(uploadID string) string {
+	return filepath.Join(s.root, localUploadsDir, uploadID)
+}
+
+// Cleans the key so that it cannot point outside of its bucket.
+func cleanObjectKey(key string) string {
+	return strings.TrimPrefix(path.Clean("/"+key), "/")
+}
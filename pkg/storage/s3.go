@@ -0,0 +1,313 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fmt"
+	"io"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+type S3Config struct {
+	Region    string
+	AccessKey string
+	SecretKey string
+	Endpoint  string // Custom endpoint of an S3 compatible storage like MinIO
+}
+
+// S3Store keeps the objects in AWS S3 or any S3 compatible storage.
+type S3Store struct {
+	client   *s3.S3
+	region   string
+	endpoint *neturl.URL
+}
+
+func NewS3Store(cfg S3Config) (store *S3Store, err error) {
+	endpointURL, err := neturl.Parse(cfg.Endpoint)
+	if err != nil {
+		return
+	}
+
+	awsConfig := &aws.Config{
+		Region:      aws.String(cfg.Region),
+		Credentials: credentials.NewStaticCredentials(cfg.AccessKey, cfg.SecretKey, ""),
+	}
+
+	if !strings.EqualFold(endpointURL.Host, "") {
+
+		awsConfig.Endpoint = aws.String(endpointURL.String())
+		awsConfig.DisableSSL = aws.Bool(strings.EqualFold(endpointURL.Scheme, "http"))
+		awsConfig.S3ForcePathStyle = aws.Bool(true)
+	}
+
+	awsSession, err := session.NewSession(awsConfig)
+	if err != nil {
+		return
+	}
+
+	store = &S3Store{
+		client:   s3.New(awsSession),
+		region:   cfg.Region,
+		endpoint: endpointURL,
+	}
+	return
+}
+
+func (s *S3Store) Put(ctx context.Context, bucket string, key string, body io.Reader, contentType string) (err error) {
+	uploader := s3manager.NewUploaderWithClient(s.client, func(u *s3manager.Uploader) {
+		u.PartSize = constants.MultipartUploadPartSize
+	})
+
+	_, err = uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+		Body:        body,
+	})
+	return
+}
+
+func (s *S3Store) Get(ctx context.Context, bucket string, key string) (body io.ReadCloser, info ObjectInfo, err error) {
+	output, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+
+	if err != nil {
+		err = mapS3Error(err)
+		return
+	}
+
+	body = output.Body
+	info = ObjectInfo{
+		Key:          key,
+		Size:         aws.Int64Value(output.ContentLength),
+		ContentType:  aws.StringValue(output.ContentType),
+		ETag:         aws.StringValue(output.ETag),
+		LastModified: aws.TimeValue(output.LastModified),
+	}
+	return
+}
+
+func (s *S3Store) Head(ctx context.Context, bucket string, key string) (info ObjectInfo, err error) {
+	output, err := s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+
+	if err != nil {
+		err = mapS3Error(err)
+		return
+	}
+
+	info = ObjectInfo{
+		Key:          key,
+		Size:         aws.Int64Value(output.ContentLength),
+		ContentType:  aws.StringValue(output.ContentType),
+		ETag:         aws.StringValue(output.ETag),
+		LastModified: aws.TimeValue(output.LastModified),
+	}
+	return
+}
+
+func (s *S3Store) Delete(ctx context.Context, bucket string, key string) (err error) {
+	_, err = s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	return
+}
+
+func (s *S3Store) List(ctx context.Context, bucket string, prefix string, startAfter string, limit int) (objects []ObjectInfo, err error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket:  aws.String(bucket),
+		Prefix:  aws.String(prefix),
+		MaxKeys: aws.Int64(int64(limit)),
+	}
+
+	if !strings.EqualFold(startAfter, "") {
+		input.StartAfter = aws.String(startAfter)
+	}
+
+	output, err := s.client.ListObjectsV2WithContext(ctx, input)
+	if err != nil {
+		err = mapS3Error(err)
+		return
+	}
+
+	for _, object := range output.Contents {
+		objects = append(objects, ObjectInfo{
+			Key:          aws.StringValue(object.Key),
+			Size:         aws.Int64Value(object.Size),
+			ETag:         aws.StringValue(object.ETag),
+			LastModified: aws.TimeValue(object.LastModified),
+		})
+	}
+
+	return
+}
+
+func (s *S3Store) PresignPut(ctx context.Context, bucket string, key string, contentType string, expiry time.Duration) (url *neturl.URL, err error) {
+	s3Request, _ := s.client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+	})
+
+	return presignS3Request(s3Request.Presign(expiry))
+}
+
+func (s *S3Store) PresignGet(ctx context.Context, bucket string, key string, expiry time.Duration) (url *neturl.URL, err error) {
+	s3Request, _ := s.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+
+	return presignS3Request(s3Request.Presign(expiry))
+}
+
+// Objects are served from the custom endpoint with path style addressing or from the virtual hosted AWS URL.
+func (s *S3Store) BucketURL(bucket string) (url *neturl.URL) {
+	if !strings.EqualFold(s.endpoint.Host, "") {
+		return s.endpoint.JoinPath(bucket)
+	}
+
+	return &neturl.URL{
+		Scheme: "https",
+		Host:   fmt.Sprintf("%s.s3.%s.amazonaws.com", bucket, s.region),
+	}
+}
+
+func (s *S3Store) CreateMultipartUpload(ctx context.Context, bucket string, key string, contentType string) (uploadID string, err error) {
+	output, err := s.client.CreateMultipartUploadWithContext(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+	})
+
+	if err != nil {
+		return
+	}
+
+	uploadID = aws.StringValue(output.UploadId)
+	return
+}
+
+func (s *S3Store) PresignUploadPart(ctx context.Context, bucket string, key string, uploadID string, partNumber int64, expiry time.Duration) (url *neturl.URL, err error) {
+	s3Request, _ := s.client.UploadPartRequest(&s3.UploadPartInput{
+		Bucket:     aws.String(bucket),
+		Key:        aws.String(key),
+		UploadId:   aws.String(uploadID),
+		PartNumber: aws.Int64(partNumber),
+	})
+
+	return presignS3Request(s3Request.Presign(expiry))
+}
+
+func (s *S3Store) UploadPart(ctx context.Context, bucket string, key string, uploadID string, partNumber int64, body io.ReadSeeker) (etag string, err error) {
+	output, err := s.client.UploadPartWithContext(ctx, &s3.UploadPartInput{
+		Bucket:     aws.String(bucket),
+		Key:        aws.String(key),
+		UploadId:   aws.String(uploadID),
+		PartNumber: aws.Int64(partNumber),
+		Body:       body,
+	})
+
+	if err != nil {
+		err = mapS3Error(err)
+		return
+	}
+
+	etag = aws.StringValue(output.ETag)
+	return
+}
+
+func (s *S3Store) ListParts(ctx context.Context, bucket string, key string, uploadID string) (parts []Part, err error) {
+	err = s.client.ListPartsPagesWithContext(ctx, &s3.ListPartsInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	}, func(page *s3.ListPartsOutput, lastPage bool) bool {
+		for _, part := range page.Parts {
+			parts = append(parts, Part{
+				Number: aws.Int64Value(part.PartNumber),
+				ETag:   aws.StringValue(part.ETag),
+				Size:   aws.Int64Value(part.Size),
+			})
+		}
+		return true
+	})
+
+	err = mapS3Error(err)
+	return
+}
+
+func (s *S3Store) CompleteMultipartUpload(ctx context.Context, bucket string, key string, uploadID string, parts []Part) (err error) {
+	completedParts := make([]*s3.CompletedPart, 0, len(parts))
+	for _, part := range parts {
+		completedParts = append(completedParts, &s3.CompletedPart{
+			ETag:       aws.String(part.ETag),
+			PartNumber: aws.Int64(part.Number),
+		})
+	}
+
+	_, err = s.client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{
+			Parts: completedParts,
+		},
+	})
+
+	err = mapS3Error(err)
+	return
+}
+
+func (s *S3Store) AbortMultipartUpload(ctx context.Context, bucket string, key string, uploadID string) (err error) {
+	_, err = s.client.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+
+	err = mapS3Error(err)
+	return
+}
+
+func presignS3Request(rawURL string, presignErr error) (url *neturl.URL, err error) {
+	if presignErr != nil {
+		err = presignErr
+		return
+	}
+
+	return neturl.Parse(rawURL)
+}
+
+// Converts the not found errors of S3 into the storage errors.
+func mapS3Error(err error) error {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return err
+	}
+
+	switch awsErr.Code() {
+	case s3.ErrCodeNoSuchKey, "NotFound":
+		return fluxerrors.ErrObjectNotFound
+	case s3.ErrCodeNoSuchUpload:
+		return fluxerrors.ErrMultipartUploadNotFound
+	}
+
+	return err
+}
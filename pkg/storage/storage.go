@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	DriverS3    = "s3"
+	DriverLocal = "local"
+)
+
+// Metadata of a stored object.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// A part of a multipart upload which reached the storage.
+type Part struct {
+	Number int64
+	ETag   string
+	Size   int64
+}
+
+// ObjectStore is the object storage the video files, thumbnails and packaged streams are kept in.
+// Presigned URLs let the clients and ffmpeg talk to the storage without going through the API.
+type ObjectStore interface {
+	Put(ctx context.Context, bucket string, key string, body io.Reader, contentType string) (err error)
+	Get(ctx context.Context, bucket string, key string) (body io.ReadCloser, info ObjectInfo, err error)
+	Head(ctx context.Context, bucket string, key string) (info ObjectInfo, err error)
+	Delete(ctx context.Context, bucket string, key string) (err error)
+	// Lists up to limit objects with the prefix in key order, starting after the given key.
+	List(ctx context.Context, bucket string, prefix string, startAfter string, limit int) (objects []ObjectInfo, err error)
+
+	PresignPut(ctx context.Context, bucket string, key string, contentType string, expiry time.Duration) (url *url.URL, err error)
+	PresignGet(ctx context.Context, bucket string, key string, expiry time.Duration) (url *url.URL, err error)
+
+	// Returns the base URL unsigned reads of a public bucket are served from.
+	BucketURL(bucket string) (url *url.URL)
+
+	MultipartStore
+}
+
+// MultipartStore uploads large objects in parts which can be sent independently and retried.
+type MultipartStore interface {
+	CreateMultipartUpload(ctx context.Context, bucket string, key string, contentType string) (uploadID string, err error)
+	PresignUploadPart(ctx context.Context, bucket string, key string, uploadID string, partNumber int64, expiry time.Duration) (url *url.URL, err error)
+	UploadPart(ctx context.Context, bucket string, key string, uploadID string, partNumber int64, body io.ReadSeeker) (etag string, err error)
+	ListParts(ctx context.Context, bucket string, key string, uploadID string) (parts []Part, err error)
+	CompleteMultipartUpload(ctx context.Context, bucket string, key string, uploadID string, parts []Part) (err error)
+	AbortMultipartUpload(ctx context.Context, bucket string, key string, uploadID string) (err error)
+}
+
+type Config struct {
+	Driver string
+	S3     S3Config
+	Local  LocalConfig
+}
+
+// Builds the object store of the configured driver.
+func New(cfg Config) (store ObjectStore, err error) {
+	switch strings.ToLower(cfg.Driver) {
+	case "", DriverS3:
+		return NewS3Store(cfg.S3)
+	case DriverLocal:
+		return NewLocalStore(cfg.Local)
+	default:
+		err = fluxerrors.ErrUnsupportedStorageDriver
+		return
+	}
+}
@@ -0,0 +1,24 @@
+package controller
+
+import (
+	"fluxio-backend/pkg/constants"
+	"fluxio-backend/pkg/model"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Returns the user set by the auth middleware.
+func getRequestUser(c *gin.Context) (user model.User, ok bool) {
+	rawUser, exists := c.Get(constants.GinUserContextKey)
+	if !exists {
+		return
+	}
+
+	user, ok = rawUser.(model.User)
+	if ok && strings.EqualFold(user.ID.String(), "") {
+		ok = false
+	}
+
+	return
+}
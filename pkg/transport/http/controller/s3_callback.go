@@ -1,36 +1,31 @@
 package controller
 
 import (
-	"context"
 	"fluxio-backend/pkg/common/schema"
-	"fluxio-backend/pkg/model"
-	"fluxio-backend/pkg/service"
+	"fluxio-backend/pkg/eventsource"
 	"fluxio-backend/pkg/transport/http/response"
 	"fmt"
-	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/gin-gonic/gin"
 )
 
 type S3CallbackController struct {
-	bucketName string
-	vidSvc     *service.VideoService
+	handler *eventsource.Handler
 
 	l schema.Logger
 }
 
-func NewS3CallbackController(bucketName string, videoSvc *service.VideoService, logger schema.Logger) *S3CallbackController {
+func NewS3CallbackController(handler *eventsource.Handler, logger schema.Logger) *S3CallbackController {
 	return &S3CallbackController{
-		bucketName: bucketName,
-		vidSvc:     videoSvc,
+		handler: handler,
 
 		l: logger,
 	}
 }
 
 func (s *S3CallbackController) HandleVideoUploadEvent(c *gin.Context) {
-	logger := s.l.With("bucket", s.bucketName)
+	logger := s.l
 
 	logger.Info("Received S3 event notification")
 
@@ -42,53 +37,12 @@ func (s *S3CallbackController) HandleVideoUploadEvent(c *gin.Context) {
 		return
 	}
 
-	logger.Info("Processing S3 event", "record_count", len(event.Records))
+	failedCount := s.handler.HandleRecords(c.Request.Context(), event.Records)
 
-	// TODO: Add Concurrent handling of events
-	for _, record := range event.Records {
-		recordLogger := logger.With("event_name", record.EventName)
-
-		// Check if the event if object put and the bucket matches
-		if strings.EqualFold(record.EventName, "s3:ObjectCreated:Put") && strings.EqualFold(record.S3.Bucket.Name, s.bucketName) {
-			// Clean the object name to get the key with ID.
-			videoSlug := strings.Replace(record.S3.Object.Key, fmt.Sprintf("%s/", s.bucketName), "", 1)
-			objectSize := record.S3.Object.Size
-
-			recordLogger = recordLogger.With("video_slug", videoSlug).With("object_size", objectSize)
-			recordLogger.Info("Processing video upload")
-
-			err := s.vidSvc.UpdateUploadStatus(c.Request.Context(), videoSlug, model.Video{
-				StoragePath: record.S3.Object.Key,
-			})
-
-			if err != nil {
-				recordLogger.Error("Failed to update upload status", err)
-				continue
-			}
-
-			recordLogger.Info("Starting asynchronous video processing")
-
-			// Create a background context for the goroutine
-			bgCtx := context.Background()
-
-			// Use a goroutine for async processing
-			go func(ctx context.Context, slug string) {
-				processingLogger := s.l.With("video_slug", slug).With("processing", "async")
-				processingLogger.Info("Beginning post-upload processing")
-
-				err := s.vidSvc.PerformPostUploadProcessing(ctx, slug)
-				if err != nil {
-					processingLogger.Error("Post-upload processing failed", err)
-					return
-				}
-
-				processingLogger.Info("Post-upload processing completed successfully")
-			}(bgCtx, videoSlug)
-		} else {
-			recordLogger.Info("Skipping non-matching event",
-				"event_bucket", record.S3.Bucket.Name,
-				"expected_bucket", s.bucketName)
-		}
+	// Let the storage redeliver the event. The records which were handled are skipped by the ledger.
+	if failedCount > 0 {
+		response.Error(c, response.StatusInternalServerError, "Event handling failed", fmt.Sprintf("%d records could not be handled.", failedCount))
+		return
 	}
 
 	logger.Info("S3 event handling completed")
@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"fluxio-backend/pkg/common/schema"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/service"
+	"fluxio-backend/pkg/storage"
+	"fluxio-backend/pkg/transport/http/response"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Serves the signed URLs handed out by the local storage driver. An upload to the raw bucket is handled
+// like the storage notification of S3 so that the whole pipeline runs without an object storage.
+type LocalStorageController struct {
+	store         *storage.LocalStore
+	rawBucketName string
+	vidSvc        *service.VideoService
+
+	l schema.Logger
+}
+
+func NewLocalStorageController(store *storage.LocalStore, rawBucketName string, videoSvc *service.VideoService, logger schema.Logger) *LocalStorageController {
+	return &LocalStorageController{
+		store:         store,
+		rawBucketName: rawBucketName,
+		vidSvc:        videoSvc,
+
+		l: logger,
+	}
+}
+
+// Serves an object. Range requests are supported since ffmpeg seeks inside the raw video.
+func (s *LocalStorageController) GetObject(c *gin.Context) {
+	bucket, key := c.Param("bucket"), strings.TrimPrefix(c.Param("key"), "/")
+
+	if !s.store.IsPublicBucket(bucket) {
+		err := s.store.VerifySignedRequest(c.Request.Method, bucket, key, c.Request.URL.Query())
+		if err != nil {
+			response.Error(c, response.StatusForbidden, "Access denied", err.Error())
+			return
+		}
+	}
+
+	file, info, err := s.store.Open(bucket, key)
+	if err != nil {
+		response.Error(c, response.StatusNotFound, "Object not found", fluxerrors.ErrObjectNotFound.Error())
+		return
+	}
+
+	defer file.Close()
+
+	if !strings.EqualFold(info.ContentType, "") {
+		c.Header("Content-Type", info.ContentType)
+	}
+	c.Header("ETag", info.ETag)
+
+	http.ServeContent(c.Writer, c.Request, "", info.LastModified, file)
+}
+
+// Stores an uploaded object or a part of a multipart upload.
+func (s *LocalStorageController) PutObject(c *gin.Context) {
+	bucket, key := c.Param("bucket"), strings.TrimPrefix(c.Param("key"), "/")
+	logger := s.l.With("bucket", bucket).With("key", key)
+
+	query := c.Request.URL.Query()
+
+	err := s.store.VerifySignedRequest(http.MethodPut, bucket, key, query)
+	if err != nil {
+		response.Error(c, response.StatusForbidden, "Access denied", err.Error())
+		return
+	}
+
+	if query.Has(storage.LocalUploadIDParam) {
+		partNumber, parseErr := strconv.ParseInt(query.Get(storage.LocalPartNumberParam), 10, 64)
+		if parseErr != nil || partNumber < 1 || partNumber > constants.MaxMultipartUploadParts {
+			response.Error(c, response.StatusBadRequest, "Invalid request payload", fluxerrors.ErrInvalidUploadPartNumber.Error())
+			return
+		}
+
+		etag, partErr := s.store.WritePart(bucket, key, query.Get(storage.LocalUploadIDParam), partNumber, c.Request.Body)
+		if partErr != nil {
+			logger.Error("Failed to store the upload part", partErr)
+			response.Error(c, response.StatusNotFound, "Upload not found", partErr.Error())
+			return
+		}
+
+		c.Header("ETag", etag)
+		c.Status(response.StatusOK)
+		return
+	}
+
+	err = s.store.Put(c.Request.Context(), bucket, key, c.Request.Body, c.GetHeader("Content-Type"))
+	if err != nil {
+		logger.Error("Failed to store the object", err)
+		response.Error(c, response.StatusInternalServerError, "Upload failed", err.Error())
+		return
+	}
+
+	if strings.EqualFold(bucket, s.rawBucketName) {
+		s.handleVideoUpload(c, key)
+	}
+
+	c.Status(response.StatusOK)
+}
+
+// Moves the uploaded video to processing the same way the S3 upload callback does.
+func (s *LocalStorageController) handleVideoUpload(c *gin.Context, key string) {
+	logger := s.l.With("video_slug", key)
+
+	// A video whose processing fails to queue here is queued by the stalled video sweep.
+	err := s.vidSvc.HandleVideoUpload(c.Request.Context(), key, key)
+	if err != nil {
+		logger.Error("Failed to queue post-upload processing", err)
+		return
+	}
+
+	logger.Info("Post-upload processing queued")
+}
@@ -0,0 +1,253 @@
+package controller
+
+import (
+	"encoding/base64"
+	"fluxio-backend/pkg/common/schema"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fluxio-backend/pkg/service"
+	"fluxio-backend/pkg/transport/http/response"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const tusOffsetContentType = "application/offset+octet-stream"
+
+// Handles the tus 1.0 resumable upload protocol with the creation, termination and expiration extensions.
+type TusController struct {
+	videoService *service.VideoService
+
+	l schema.Logger
+}
+
+func NewTusController(videoService *service.VideoService, logger schema.Logger) *TusController {
+	return &TusController{
+		videoService: videoService,
+		l:            logger,
+	}
+}
+
+// Rejects the requests of clients which do not speak the supported protocol version.
+func (t *TusController) RequireTusVersion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Tus-Resumable", constants.TusVersion)
+
+		if c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		if c.GetHeader("Tus-Resumable") != constants.TusVersion {
+			c.Header("Tus-Version", constants.TusVersion)
+			c.AbortWithStatus(http.StatusPreconditionFailed)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Describes the protocol version and the extensions supported by the server.
+func (t *TusController) Options(c *gin.Context) {
+	c.Header("Tus-Version", constants.TusVersion)
+	c.Header("Tus-Extension", "creation,termination,expiration")
+	c.Header("Tus-Max-Size", strconv.FormatInt(constants.MaxTusUploadSize, 10))
+	c.Status(response.StatusNoContent)
+}
+
+// Creates an upload for a pending video. The video slug and the file type are read from the Upload-Metadata header.
+func (t *TusController) Create(c *gin.Context) {
+	logger := t.l
+
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil {
+		response.Error(c, response.StatusBadRequest, "Invalid request payload", "The Upload-Length header is not valid.")
+		return
+	}
+
+	if length > constants.MaxTusUploadSize {
+		response.Error(c, http.StatusRequestEntityTooLarge, "Invalid request payload", fmt.Sprintf("The upload must not exceed %d bytes.", constants.MaxTusUploadSize))
+		return
+	}
+
+	metadata, err := parseTusMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		response.Error(c, response.StatusBadRequest, "Invalid request payload", "The Upload-Metadata header is not valid.")
+		return
+	}
+
+	slug := metadata["slug"]
+	mimeType := metadata["filetype"]
+
+	if strings.EqualFold(slug, "") || strings.EqualFold(mimeType, "") {
+		response.Error(c, response.StatusBadRequest, "Invalid request payload", "The slug and filetype upload metadata are required.")
+		return
+	}
+
+	logger = logger.With("slug", slug)
+
+	user, ok := getRequestUser(c)
+	if !ok {
+		logger.Warn("Authenticated user not found in the request context")
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	upload, err := t.videoService.CreateTusUpload(c, slug, user, mimeType, length)
+	if err != nil {
+		t.handleTusError(c, err)
+		return
+	}
+
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+upload.ID.String())
+	setTusExpiry(c, upload)
+	c.Status(response.StatusCreated)
+}
+
+// Returns the offset the client has to resume the upload from.
+func (t *TusController) Head(c *gin.Context) {
+	user, ok := getRequestUser(c)
+	if !ok {
+		c.Status(response.StatusUnauthorized)
+		return
+	}
+
+	upload, err := t.videoService.GetTusUpload(c, model.VideoUploadID(c.Param("id")), user)
+	if err != nil {
+		c.Header("Cache-Control", "no-store")
+		c.Status(tusErrorStatus(err))
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	setTusExpiry(c, upload)
+	c.Status(response.StatusOK)
+}
+
+// Appends the request body to the upload at the offset given by the client.
+func (t *TusController) Patch(c *gin.Context) {
+	id := c.Param("id")
+	logger := t.l.With("video_upload_id", id)
+
+	if !strings.EqualFold(c.ContentType(), tusOffsetContentType) {
+		response.Error(c, http.StatusUnsupportedMediaType, "Invalid request payload", fmt.Sprintf("The content type must be %s.", tusOffsetContentType))
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		response.Error(c, response.StatusBadRequest, "Invalid request payload", "The Upload-Offset header is not valid.")
+		return
+	}
+
+	user, ok := getRequestUser(c)
+	if !ok {
+		logger.Warn("Authenticated user not found in the request context")
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	upload, err := t.videoService.WriteTusChunk(c, model.VideoUploadID(id), user, offset, c.Request.Body)
+	if err != nil {
+		t.handleTusError(c, err)
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	setTusExpiry(c, upload)
+	c.Status(response.StatusNoContent)
+}
+
+// Terminates the upload so that the stored parts are freed.
+func (t *TusController) Terminate(c *gin.Context) {
+	user, ok := getRequestUser(c)
+	if !ok {
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	err := t.videoService.TerminateTusUpload(c, model.VideoUploadID(c.Param("id")), user)
+	if err != nil {
+		t.handleTusError(c, err)
+		return
+	}
+
+	c.Status(response.StatusNoContent)
+}
+
+func (t *TusController) handleTusError(c *gin.Context, err error) {
+	status := tusErrorStatus(err)
+
+	switch status {
+	case response.StatusNotFound:
+		response.Error(c, status, "Upload not found", err.Error())
+	case http.StatusGone:
+		response.Error(c, status, "Upload expired", err.Error())
+	case response.StatusConflict:
+		response.Error(c, status, response.MsgVideoUploadNotAllowed, err.Error())
+	case http.StatusUnsupportedMediaType:
+		supportedTypes := strings.Join(constants.ValidVideoMimes, ",")
+		response.Error(c, status, "Invalid Video Format", fmt.Sprintf("Video Format is not supported. Supported video types are - %s", supportedTypes))
+	case response.StatusBadRequest:
+		response.Error(c, status, "Invalid request payload", err.Error())
+	default:
+		t.l.Error("Tus upload request failed", err)
+		response.Error(c, status, response.MsgVideoUploadFailed, err.Error())
+	}
+}
+
+func tusErrorStatus(err error) int {
+	switch err {
+	case fluxerrors.ErrVideoNotFound, fluxerrors.ErrInvalidVideoSlug, fluxerrors.ErrVideoUploadNotFound:
+		return response.StatusNotFound
+	case fluxerrors.ErrVideoUploadExpired:
+		return http.StatusGone
+	case fluxerrors.ErrUploadOffsetMismatch, fluxerrors.ErrVideoUploadNotAllowed, fluxerrors.ErrVideoUploadAlreadyActive:
+		return response.StatusConflict
+	case fluxerrors.ErrInvalidVideoExtension:
+		return http.StatusUnsupportedMediaType
+	case fluxerrors.ErrInvalidUploadLength:
+		return response.StatusBadRequest
+	default:
+		return response.StatusUnprocessableEntity
+	}
+}
+
+func setTusExpiry(c *gin.Context, upload model.VideoUpload) {
+	if upload.ExpiresAt == nil {
+		return
+	}
+
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+// Parses the Upload-Metadata header which holds comma separated pairs of a key and a base64 encoded value.
+func parseTusMetadata(header string) (metadata map[string]string, err error) {
+	metadata = map[string]string{}
+
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if strings.EqualFold(pair, "") {
+			continue
+		}
+
+		key, encoded, _ := strings.Cut(pair, " ")
+
+		var value []byte
+		value, err = base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return
+		}
+
+		metadata[key] = string(value)
+	}
+
+	return
+}
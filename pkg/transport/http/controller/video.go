@@ -8,16 +8,29 @@ import (
 	"fluxio-backend/pkg/service"
 	"fluxio-backend/pkg/transport/http/response"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type createVidRequest struct {
 	model.Video
 }
 
+type importVideoRequest struct {
+	model.Video
+	SourceURL string `json:"source_url" binding:"required"`
+	MimeType  string `json:"mime_type"` // Optional since the downloaded file is sniffed
+}
+
+type regenerateThumbnailsRequest struct {
+	Timestamps []uint64 `json:"timestamps"` // Optional positions in seconds. Random positions are used when empty
+}
+
 type VideoController struct {
 	videoService *service.VideoService
 
@@ -53,6 +66,23 @@ func (v *VideoController) CreateNewVideo(c *gin.Context) {
 		return
 	}
 
+	user, ok := getRequestUser(c)
+	if !ok {
+		logger.Warn("Authenticated user not found in the request context")
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	userID, err := uuid.Parse(user.ID.String())
+	if err != nil {
+		logger.Error("Authenticated user has an invalid ID", err)
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	// The owner is always the authenticated user irrespective of the payload.
+	video.UserID = userID
+
 	logger = logger.With("title", video.Title)
 
 	video, uploadURL, err := v.videoService.AddVideo(c, video, mimeType)
@@ -83,3 +113,277 @@ func (v *VideoController) CreateNewVideo(c *gin.Context) {
 		"upload_url": uploadURL.String(),
 	})
 }
+
+// Creates a video whose file is downloaded from the given URL in the background.
+func (v *VideoController) ImportVideo(c *gin.Context) {
+	logger := v.l
+
+	var req importVideoRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Warn("Invalid video import payload found")
+		logger.Debug("Invalid video import payload", err)
+		response.Error(c, response.StatusBadRequest, "Invalid request payload", "The payload is not valid.")
+		return
+	}
+
+	user, ok := getRequestUser(c)
+	if !ok {
+		logger.Warn("Authenticated user not found in the request context")
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	userID, err := uuid.Parse(user.ID.String())
+	if err != nil {
+		logger.Error("Authenticated user has an invalid ID", err)
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	video := req.Video
+	video.UserID = userID
+
+	logger = logger.With("title", video.Title)
+
+	video, err = v.videoService.ImportVideo(c, video, req.SourceURL, req.MimeType)
+	if err != nil {
+		switch err {
+		case fluxerrors.ErrInvalidVideoImportURL:
+			response.Error(c, response.StatusBadRequest, "Invalid request payload", "The source URL must be an absolute http or https URL.")
+		case fluxerrors.ErrDuplicateVideoTitle:
+			logger.Info("Video import failed - duplicate title")
+			response.Error(c, response.StatusConflict, response.MsgDuplicateVideoTitle, err.Error())
+		case fluxerrors.ErrInvalidVideoExtension:
+			supportedTypes := strings.Join(constants.ValidVideoMimes, ",")
+			response.Error(c, http.StatusUnsupportedMediaType, "Invalid Video Format", fmt.Sprintf("Video Format is not supported. Supported video types are - %s", supportedTypes))
+		default:
+			logger.Error("Video import failed", err)
+			response.Error(c, response.StatusUnprocessableEntity, response.MsgVideoCreationFailed, err.Error())
+		}
+		return
+	}
+
+	logger.With("video_id", video.ID.String()).With("slug", video.Slug).Info("Video import queued")
+
+	response.Success(c, response.StatusAccepted, "Video import queued", video)
+}
+
+func (v *VideoController) GetVideo(c *gin.Context) {
+	slug := c.Param("slug")
+	logger := v.l.With("slug", slug)
+
+	user, ok := getRequestUser(c)
+	if !ok {
+		logger.Warn("Authenticated user not found in the request context")
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	video, err := v.videoService.GetVideoDetails(c, slug, user)
+	if err != nil {
+		if err == fluxerrors.ErrVideoNotFound || err == fluxerrors.ErrInvalidVideoSlug {
+			response.Error(c, response.StatusNotFound, response.MsgVideoNotFound, err.Error())
+			return
+		}
+
+		logger.Error("Failed to get video details", err)
+		response.Error(c, response.StatusInternalServerError, "Internal server error", err.Error())
+		return
+	}
+
+	response.Success(c, response.StatusOK, "", video)
+}
+
+// Streams the status, processing stage, progress and thumbnail events of a video of the user as Server-Sent Events.
+// The current state is sent first and the stream ends once the video reaches a final status.
+func (v *VideoController) StreamVideoEvents(c *gin.Context) {
+	slug := c.Param("slug")
+	logger := v.l.With("slug", slug)
+
+	user, ok := getRequestUser(c)
+	if !ok {
+		logger.Warn("Authenticated user not found in the request context")
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	video, sub, err := v.videoService.SubscribeVideoEvents(c, slug, user)
+	if err != nil {
+		if err == fluxerrors.ErrVideoNotFound || err == fluxerrors.ErrInvalidVideoSlug {
+			response.Error(c, response.StatusNotFound, response.MsgVideoNotFound, err.Error())
+			return
+		}
+
+		logger.Error("Failed to subscribe to the video events", err)
+		response.Error(c, response.StatusInternalServerError, "Internal server error", err.Error())
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Stop nginx from buffering the stream
+
+	current := model.NewVideoStatusEvent(video)
+
+	c.SSEvent(current.Type.String(), current)
+	c.Writer.Flush()
+
+	if current.IsFinal() {
+		return
+	}
+
+	keepAlive := time.NewTicker(constants.VideoEventKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+
+			trackVideoEvent(&current, event)
+
+			c.SSEvent(event.Type.String(), event)
+			return !event.IsFinal()
+		case <-keepAlive.C:
+			// Catch up from the stored state in case events were missed, like while the listening connection was down.
+			stored, err := v.videoService.GetVideoStatusEvent(c.Request.Context(), video.Slug)
+			if err != nil {
+				logger.With("error", err.Error()).Warn("Failed to read the video state for the event stream")
+			} else if stored.Status != current.Status || stored.Stage != current.Stage {
+				current = stored
+
+				c.SSEvent(stored.Type.String(), stored)
+				return !stored.IsFinal()
+			} else if stored.StageProgress != nil && (current.StageProgress == nil || !sameVideoProgress(*stored.StageProgress, *current.StageProgress)) {
+				current.StageProgress = stored.StageProgress
+
+				progress := model.VideoEvent{
+					Type:          model.VideoEventStageProgress,
+					VideoID:       stored.VideoID,
+					Slug:          stored.Slug,
+					StageProgress: stored.StageProgress,
+					Message:       stored.StageProgress.String(),
+					CreatedAt:     stored.CreatedAt,
+				}
+
+				c.SSEvent(progress.Type.String(), progress)
+				return true
+			}
+
+			_, writeErr := io.WriteString(w, ": keep-alive\n\n")
+			return writeErr == nil
+		}
+	})
+}
+
+func sameVideoProgress(a model.VideoProgress, b model.VideoProgress) bool {
+	return a.Stage == b.Stage && a.Step == b.Step && a.Percent == b.Percent
+}
+
+// Keeps the state the stream last reported up to date with a received event.
+func trackVideoEvent(current *model.VideoEvent, event model.VideoEvent) {
+	switch event.Type {
+	case model.VideoEventStatus:
+		current.Status = event.Status
+	case model.VideoEventStage:
+		current.Stage = event.Stage
+		current.StageProgress = nil // Every stage outcome clears the stored progress
+	case model.VideoEventStageProgress:
+		current.StageProgress = event.StageProgress
+	}
+}
+
+// Issues a new upload URL for a pending or abandoned video of the user.
+func (v *VideoController) RegenerateUploadURL(c *gin.Context) {
+	slug := c.Param("slug")
+	logger := v.l.With("slug", slug)
+
+	mimeType := c.GetHeader("X-Upload-Mime-Type")
+
+	if strings.EqualFold(mimeType, "") {
+		logger.Debug("Invalid video mimeType", mimeType)
+		response.Error(c, response.StatusBadRequest, "Invalid request payload", "The X-Upload-Mime-Type header is not found.")
+		return
+	}
+
+	user, ok := getRequestUser(c)
+	if !ok {
+		logger.Warn("Authenticated user not found in the request context")
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	video, uploadURL, err := v.videoService.RegenerateUploadURL(c, slug, user, mimeType)
+	if err != nil {
+		switch err {
+		case fluxerrors.ErrVideoNotFound, fluxerrors.ErrInvalidVideoSlug:
+			response.Error(c, response.StatusNotFound, response.MsgVideoNotFound, err.Error())
+		case fluxerrors.ErrInvalidVideoExtension:
+			supportedTypes := strings.Join(constants.ValidVideoMimes, ",")
+			response.Error(c, http.StatusUnsupportedMediaType, "Invalid Video Format", fmt.Sprintf("Video Format is not supported. Supported video types are - %s", supportedTypes))
+		case fluxerrors.ErrVideoUploadNotAllowed:
+			response.Error(c, response.StatusConflict, response.MsgVideoUploadNotAllowed, err.Error())
+		case fluxerrors.ErrVideoUploadRetryExceeded:
+			response.Error(c, http.StatusTooManyRequests, response.MsgVideoUploadRetryExceeded, err.Error())
+		default:
+			logger.Error("Failed to regenerate the upload URL", err)
+			response.Error(c, response.StatusUnprocessableEntity, response.MsgVideoURLGenerationFailed, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, response.StatusOK, "Video upload URL generated successfully", gin.H{
+		"video":      video,
+		"upload_url": uploadURL.String(),
+	})
+}
+
+// Queues the regeneration of the thumbnails of a completed video of the user.
+func (v *VideoController) RegenerateThumbnails(c *gin.Context) {
+	slug := c.Param("slug")
+	logger := v.l.With("slug", slug)
+
+	var req regenerateThumbnailsRequest
+
+	// The body is optional.
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			logger.Debug("Invalid thumbnail regeneration payload", err)
+			response.Error(c, response.StatusBadRequest, "Invalid request payload", "The payload is not valid.")
+			return
+		}
+	}
+
+	user, ok := getRequestUser(c)
+	if !ok {
+		logger.Warn("Authenticated user not found in the request context")
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	err := v.videoService.RequestThumbnailRegeneration(c, slug, user, req.Timestamps)
+	if err != nil {
+		switch err {
+		case fluxerrors.ErrVideoNotFound, fluxerrors.ErrInvalidVideoSlug:
+			response.Error(c, response.StatusNotFound, response.MsgVideoNotFound, err.Error())
+		case fluxerrors.ErrInvalidThumbnailTimestamp:
+			response.Error(c, response.StatusBadRequest, "Invalid request payload", fmt.Sprintf("Up to %d distinct timestamps within the video length are allowed.", constants.TotalThumbnailCount))
+		case fluxerrors.ErrInvalidVideoStatus, fluxerrors.ErrThumbnailAlreadyQueued, fluxerrors.ErrVideoRawFileMissing:
+			response.Error(c, response.StatusConflict, "Thumbnail regeneration not allowed", err.Error())
+		case fluxerrors.ErrThumbnailRetryExceeded:
+			response.Error(c, http.StatusTooManyRequests, "Thumbnail regeneration not allowed", err.Error())
+		default:
+			logger.Error("Failed to queue the thumbnail regeneration", err)
+			response.Error(c, response.StatusInternalServerError, "Internal server error", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, response.StatusAccepted, "Thumbnail regeneration queued", nil)
+}
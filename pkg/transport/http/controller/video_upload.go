@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/transport/http/response"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Starts or resumes the multipart upload of a pending video.
+func (v *VideoController) InitiateMultipartUpload(c *gin.Context) {
+	slug := c.Param("slug")
+	logger := v.l.With("slug", slug)
+
+	mimeType := c.GetHeader("X-Upload-Mime-Type")
+
+	if strings.EqualFold(mimeType, "") {
+		logger.Debug("Invalid video mimeType", mimeType)
+		response.Error(c, response.StatusBadRequest, "Invalid request payload", "The X-Upload-Mime-Type header is not found.")
+		return
+	}
+
+	user, ok := getRequestUser(c)
+	if !ok {
+		logger.Warn("Authenticated user not found in the request context")
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	upload, err := v.videoService.InitiateMultipartUpload(c, slug, user, mimeType)
+	if err != nil {
+		v.handleMultipartUploadError(c, err)
+		return
+	}
+
+	response.Success(c, response.StatusCreated, "Multipart upload started", upload)
+}
+
+// Returns the multipart upload in progress so that the client can resume it.
+func (v *VideoController) GetMultipartUpload(c *gin.Context) {
+	slug := c.Param("slug")
+	logger := v.l.With("slug", slug)
+
+	user, ok := getRequestUser(c)
+	if !ok {
+		logger.Warn("Authenticated user not found in the request context")
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	upload, err := v.videoService.GetMultipartUpload(c, slug, user)
+	if err != nil {
+		v.handleMultipartUploadError(c, err)
+		return
+	}
+
+	response.Success(c, response.StatusOK, "", upload)
+}
+
+// Issues a presigned URL for a single part of the multipart upload.
+func (v *VideoController) GenerateMultipartPartURL(c *gin.Context) {
+	slug := c.Param("slug")
+	logger := v.l.With("slug", slug)
+
+	partNumber, err := strconv.ParseInt(c.Param("partNumber"), 10, 64)
+	if err != nil {
+		response.Error(c, response.StatusBadRequest, "Invalid request payload", fmt.Sprintf("The part number must be between 1 and %d.", constants.MaxMultipartUploadParts))
+		return
+	}
+
+	user, ok := getRequestUser(c)
+	if !ok {
+		logger.Warn("Authenticated user not found in the request context")
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	partURL, err := v.videoService.GenerateMultipartPartURL(c, slug, user, partNumber)
+	if err != nil {
+		v.handleMultipartUploadError(c, err)
+		return
+	}
+
+	response.Success(c, response.StatusOK, "", gin.H{
+		"part_number": partNumber,
+		"upload_url":  partURL.String(),
+	})
+}
+
+// Completes the multipart upload and starts processing the video.
+func (v *VideoController) CompleteMultipartUpload(c *gin.Context) {
+	slug := c.Param("slug")
+	logger := v.l.With("slug", slug)
+
+	user, ok := getRequestUser(c)
+	if !ok {
+		logger.Warn("Authenticated user not found in the request context")
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	err := v.videoService.CompleteMultipartUpload(c, slug, user)
+	if err != nil {
+		v.handleMultipartUploadError(c, err)
+		return
+	}
+
+	response.Success(c, response.StatusAccepted, "Video upload completed", nil)
+}
+
+// Aborts the multipart upload in progress.
+func (v *VideoController) AbortMultipartUpload(c *gin.Context) {
+	slug := c.Param("slug")
+	logger := v.l.With("slug", slug)
+
+	user, ok := getRequestUser(c)
+	if !ok {
+		logger.Warn("Authenticated user not found in the request context")
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	err := v.videoService.AbortMultipartUpload(c, slug, user)
+	if err != nil {
+		v.handleMultipartUploadError(c, err)
+		return
+	}
+
+	response.Success(c, response.StatusOK, "Multipart upload aborted", nil)
+}
+
+func (v *VideoController) handleMultipartUploadError(c *gin.Context, err error) {
+	switch err {
+	case fluxerrors.ErrVideoNotFound, fluxerrors.ErrInvalidVideoSlug:
+		response.Error(c, response.StatusNotFound, response.MsgVideoNotFound, err.Error())
+	case fluxerrors.ErrVideoUploadNotFound:
+		response.Error(c, response.StatusNotFound, "Upload not found", err.Error())
+	case fluxerrors.ErrInvalidVideoExtension:
+		supportedTypes := strings.Join(constants.ValidVideoMimes, ",")
+		response.Error(c, http.StatusUnsupportedMediaType, "Invalid Video Format", fmt.Sprintf("Video Format is not supported. Supported video types are - %s", supportedTypes))
+	case fluxerrors.ErrInvalidUploadPartNumber:
+		response.Error(c, response.StatusBadRequest, "Invalid request payload", fmt.Sprintf("The part number must be between 1 and %d.", constants.MaxMultipartUploadParts))
+	case fluxerrors.ErrVideoUploadNotAllowed, fluxerrors.ErrInvalidVideoStatus, fluxerrors.ErrVideoUploadPartsIncomplete, fluxerrors.ErrVideoUploadAlreadyActive:
+		response.Error(c, response.StatusConflict, response.MsgVideoUploadNotAllowed, err.Error())
+	default:
+		v.l.Error("Multipart upload request failed", err)
+		response.Error(c, response.StatusUnprocessableEntity, response.MsgVideoUploadFailed, err.Error())
+	}
+}
@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"fluxio-backend/pkg/common/schema"
+	"fluxio-backend/pkg/constants"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fluxio-backend/pkg/service"
+	"fluxio-backend/pkg/transport/http/response"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createWebhookRequest struct {
+	URL        string                   `json:"url" binding:"required"`
+	Secret     string                   `json:"secret"` // Optional, generated when empty
+	EventTypes []model.WebhookEventType `json:"event_types" binding:"required"`
+}
+
+type WebhookController struct {
+	webhookService *service.WebhookService
+
+	l schema.Logger
+}
+
+func NewWebhookController(webhookService *service.WebhookService, logger schema.Logger) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+		l:              logger,
+	}
+}
+
+// Registers a webhook endpoint of the user. The response holds the signing secret which is not returned again.
+func (w *WebhookController) CreateSubscription(c *gin.Context) {
+	logger := w.l
+
+	var req createWebhookRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Debug("Invalid webhook payload", err)
+		response.Error(c, response.StatusBadRequest, "Invalid request payload", "The payload is not valid.")
+		return
+	}
+
+	user, ok := getRequestUser(c)
+	if !ok {
+		logger.Warn("Authenticated user not found in the request context")
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	subscription, err := w.webhookService.CreateSubscription(c, user, model.WebhookSubscription{
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+	})
+	if err != nil {
+		switch err {
+		case fluxerrors.ErrInvalidWebhookURL, fluxerrors.ErrWebhookURLNotAllowed, fluxerrors.ErrInvalidWebhookEventType:
+			response.Error(c, response.StatusBadRequest, "Invalid request payload", err.Error())
+		case fluxerrors.ErrWebhookSecretTooShort:
+			response.Error(c, response.StatusBadRequest, "Invalid request payload", fmt.Sprintf("The secret must have at least %d characters.", constants.MinWebhookSecretLength))
+		case fluxerrors.ErrWebhookLimitReached:
+			response.Error(c, response.StatusConflict, "Webhook not created", err.Error())
+		default:
+			logger.Error("Failed to create the webhook subscription", err)
+			response.Error(c, response.StatusInternalServerError, "Internal server error", err.Error())
+		}
+		return
+	}
+
+	response.Success(c, response.StatusCreated, "Webhook created successfully", subscription)
+}
+
+func (w *WebhookController) ListSubscriptions(c *gin.Context) {
+	user, ok := getRequestUser(c)
+	if !ok {
+		w.l.Warn("Authenticated user not found in the request context")
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	subscriptions, err := w.webhookService.ListSubscriptions(c, user)
+	if err != nil {
+		w.l.Error("Failed to list the webhook subscriptions", err)
+		response.Error(c, response.StatusInternalServerError, "Internal server error", err.Error())
+		return
+	}
+
+	response.Success(c, response.StatusOK, "", subscriptions)
+}
+
+func (w *WebhookController) DeleteSubscription(c *gin.Context) {
+	id := c.Param("id")
+	logger := w.l.With("subscription_id", id)
+
+	user, ok := getRequestUser(c)
+	if !ok {
+		logger.Warn("Authenticated user not found in the request context")
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	err := w.webhookService.DeleteSubscription(c, user, model.WebhookSubscriptionID(id))
+	if err != nil {
+		if err == fluxerrors.ErrWebhookSubscriptionNotFound {
+			response.Error(c, response.StatusNotFound, "Webhook not found", err.Error())
+			return
+		}
+
+		logger.Error("Failed to delete the webhook subscription", err)
+		response.Error(c, response.StatusInternalServerError, "Internal server error", err.Error())
+		return
+	}
+
+	c.Status(response.StatusNoContent)
+}
+
+// Returns the delivery log of a subscription, newest first. Paged with the limit and offset query parameters.
+func (w *WebhookController) ListDeliveries(c *gin.Context) {
+	id := c.Param("id")
+	logger := w.l.With("subscription_id", id)
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil {
+		response.Error(c, response.StatusBadRequest, "Invalid request payload", "The limit is not valid.")
+		return
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		response.Error(c, response.StatusBadRequest, "Invalid request payload", "The offset is not valid.")
+		return
+	}
+
+	user, ok := getRequestUser(c)
+	if !ok {
+		logger.Warn("Authenticated user not found in the request context")
+		response.Error(c, response.StatusUnauthorized, "Unauthroized user access.", "User is not authenticated.")
+		return
+	}
+
+	deliveries, err := w.webhookService.ListDeliveries(c, user, model.WebhookSubscriptionID(id), limit, offset)
+	if err != nil {
+		if err == fluxerrors.ErrWebhookSubscriptionNotFound {
+			response.Error(c, response.StatusNotFound, "Webhook not found", err.Error())
+			return
+		}
+
+		logger.Error("Failed to list the webhook deliveries", err)
+		response.Error(c, response.StatusInternalServerError, "Internal server error", err.Error())
+		return
+	}
+
+	response.Success(c, response.StatusOK, "", deliveries)
+}
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fluxio-backend/pkg/common/schema"
+	"fluxio-backend/pkg/constants"
+	"fluxio-backend/pkg/transport/http/response"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CallbackAuthMiddleware authenticates the storage event callbacks. A request is accepted when it carries
+// the shared secret as a bearer token (MinIO webhooks) or an HMAC signature of its timestamp and body.
+// Signed requests are only accepted within a time window and a signature cannot be replayed inside it.
+type CallbackAuthMiddleware struct {
+	secret []byte
+	l      schema.Logger
+
+	mu   sync.Mutex
+	seen map[string]time.Time // Signatures accepted within the window mapped to the time they expire
+}
+
+func NewCallbackAuthMiddleware(secret string, logger schema.Logger) *CallbackAuthMiddleware {
+	if strings.EqualFold(secret, "") {
+		logger.Warn("Storage callback secret is not set, every callback will be rejected")
+	}
+
+	return &CallbackAuthMiddleware{
+		secret: []byte(secret),
+		l:      logger,
+		seen:   map[string]time.Time{},
+	}
+}
+
+func (m *CallbackAuthMiddleware) Add() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := m.l.With("client_ip", c.ClientIP())
+
+		if len(m.secret) == 0 {
+			logger.Warn("Rejected storage callback since no secret is configured")
+			response.Error(c, response.StatusUnauthorized, "Unauthorized callback.", "Callback authentication is not configured.")
+			c.Abort()
+			return
+		}
+
+		if m.hasValidToken(c.GetHeader("Authorization")) {
+			c.Next()
+			return
+		}
+
+		signature := c.GetHeader(constants.CallbackSignatureHeader)
+		if strings.EqualFold(signature, "") {
+			logger.Warn("Rejected unauthenticated storage callback")
+			response.Error(c, response.StatusUnauthorized, "Unauthorized callback.", "The callback is not authenticated.")
+			c.Abort()
+			return
+		}
+
+		// Read the body to verify it and hand a copy to the handler.
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, constants.MaxCallbackBodySize))
+		if err != nil {
+			response.Error(c, response.StatusBadRequest, "Malformed data", "The callback body could not be read.")
+			c.Abort()
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		if !m.hasValidSignature(c.GetHeader(constants.CallbackTimestampHeader), signature, body) {
+			logger.Warn("Rejected storage callback with an invalid signature")
+			response.Error(c, response.StatusUnauthorized, "Unauthorized callback.", "The callback signature is not valid.")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Accepts the secret with or without the Bearer scheme since MinIO sends the configured token as is.
+func (m *CallbackAuthMiddleware) hasValidToken(header string) bool {
+	token := strings.TrimSpace(header)
+	if strings.EqualFold(token, "") {
+		return false
+	}
+
+	if scheme, value, found := strings.Cut(token, " "); found && strings.EqualFold(scheme, "Bearer") {
+		token = strings.TrimSpace(value)
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), m.secret) == 1
+}
+
+// The signature is the hex encoded HMAC-SHA256 of "<timestamp>.<body>" with an optional "sha256=" prefix.
+func (m *CallbackAuthMiddleware) hasValidSignature(rawTimestamp string, signature string, body []byte) bool {
+	timestamp, err := strconv.ParseInt(rawTimestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	signedAt := time.Unix(timestamp, 0)
+	age := time.Since(signedAt)
+	if age > constants.CallbackSignatureMaxAge || age < -constants.CallbackSignatureMaxAge {
+		return false
+	}
+
+	received, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(rawTimestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	if !hmac.Equal(received, mac.Sum(nil)) {
+		return false
+	}
+
+	return m.markSeen(hex.EncodeToString(received), signedAt.Add(constants.CallbackSignatureMaxAge))
+}
+
+// Records an accepted signature and reports whether it was seen before. Expired entries are dropped
+// since their timestamp is rejected anyway.
+func (m *CallbackAuthMiddleware) markSeen(signature string, expiresAt time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for seenSignature, seenExpiry := range m.seen {
+		if now.After(seenExpiry) {
+			delete(m.seen, seenSignature)
+		}
+	}
+
+	if _, replayed := m.seen[signature]; replayed {
+		return false
+	}
+
+	m.seen[signature] = expiresAt
+	return true
+}
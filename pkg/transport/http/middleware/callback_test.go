@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fluxio-backend/pkg/constants"
+	"fluxio-backend/pkg/logger"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	testCallbackSecret = "callback-secret"
+	testCallbackBody   = `{"Records":[{"eventName":"s3:ObjectCreated:Put"}]}`
+)
+
+// Returns a router whose callback route answers with the body it received once the middleware let it through.
+func newCallbackTestRouter(secret string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/callback", NewCallbackAuthMiddleware(secret, logger.NewDefaultLogger()).Add(), func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	})
+
+	return router
+}
+
+func signCallback(secret string, timestamp string, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func sendCallback(router *gin.Engine, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(body))
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// Returns the headers of a request signed at the given time.
+func signedHeaders(secret string, signedAt time.Time, body string) map[string]string {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+
+	return map[string]string{
+		constants.CallbackTimestampHeader: timestamp,
+		constants.CallbackSignatureHeader: "sha256=" + signCallback(secret, timestamp, body),
+	}
+}
+
+func TestCallbackAuthMiddleware(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		secret     string
+		headers    map[string]string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "accepts a bearer token",
+			secret:     testCallbackSecret,
+			headers:    map[string]string{"Authorization": "Bearer " + testCallbackSecret},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "accepts a token without the bearer scheme",
+			secret:     testCallbackSecret,
+			headers:    map[string]string{"Authorization": testCallbackSecret},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "rejects a wrong token",
+			secret:     testCallbackSecret,
+			headers:    map[string]string{"Authorization": "Bearer not-the-secret"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "rejects a request without credentials",
+			secret:     testCallbackSecret,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "accepts a valid signature",
+			secret:     testCallbackSecret,
+			headers:    signedHeaders(testCallbackSecret, now, testCallbackBody),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "accepts a signature without the scheme prefix",
+			secret: testCallbackSecret,
+			headers: map[string]string{
+				constants.CallbackTimestampHeader: strconv.FormatInt(now.Unix(), 10),
+				constants.CallbackSignatureHeader: signCallback(testCallbackSecret, strconv.FormatInt(now.Unix(), 10), testCallbackBody),
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "rejects a stale signature",
+			secret:     testCallbackSecret,
+			headers:    signedHeaders(testCallbackSecret, now.Add(-constants.CallbackSignatureMaxAge-time.Minute), testCallbackBody),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "rejects a signature from the future",
+			secret:     testCallbackSecret,
+			headers:    signedHeaders(testCallbackSecret, now.Add(constants.CallbackSignatureMaxAge+time.Minute), testCallbackBody),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "rejects a tampered body",
+			secret:     testCallbackSecret,
+			headers:    signedHeaders(testCallbackSecret, now, testCallbackBody),
+			body:       strings.Replace(testCallbackBody, "Put", "Copy", 1),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "rejects a signature made with another secret",
+			secret:     testCallbackSecret,
+			headers:    signedHeaders("another-secret", now, testCallbackBody),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "rejects a signature without a timestamp",
+			secret: testCallbackSecret,
+			headers: map[string]string{
+				constants.CallbackSignatureHeader: signCallback(testCallbackSecret, "", testCallbackBody),
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "rejects an empty token without a secret",
+			secret:     "",
+			headers:    map[string]string{"Authorization": "Bearer "},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "rejects a signature without a secret",
+			secret:     "",
+			headers:    signedHeaders("", now, testCallbackBody),
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.body
+			if strings.EqualFold(body, "") {
+				body = testCallbackBody
+			}
+
+			recorder := sendCallback(newCallbackTestRouter(tt.secret), body, tt.headers)
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d", recorder.Code, tt.wantStatus)
+			}
+
+			// The handler gets the body the middleware read to verify the signature.
+			if tt.wantStatus == http.StatusOK && recorder.Body.String() != body {
+				t.Errorf("handler got body %q, want %q", recorder.Body.String(), body)
+			}
+		})
+	}
+}
+
+func TestCallbackAuthMiddlewareRejectsReplayedSignatures(t *testing.T) {
+	router := newCallbackTestRouter(testCallbackSecret)
+	headers := signedHeaders(testCallbackSecret, time.Now(), testCallbackBody)
+
+	if recorder := sendCallback(router, testCallbackBody, headers); recorder.Code != http.StatusOK {
+		t.Fatalf("got status %d for the first delivery", recorder.Code)
+	}
+
+	if recorder := sendCallback(router, testCallbackBody, headers); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d for the replayed delivery, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+
+	// Another request signed in the same second has its own signature.
+	otherBody := strings.Replace(testCallbackBody, "Put", "Copy", 1)
+	if recorder := sendCallback(router, otherBody, signedHeaders(testCallbackSecret, time.Now(), otherBody)); recorder.Code != http.StatusOK {
+		t.Errorf("got status %d for another signed delivery", recorder.Code)
+	}
+}
+
+func TestCallbackAuthMiddlewareForgetsExpiredSignatures(t *testing.T) {
+	middleware := NewCallbackAuthMiddleware(testCallbackSecret, logger.NewDefaultLogger())
+
+	if !middleware.markSeen("expired", time.Now().Add(-time.Second)) {
+		t.Fatalf("first signature was reported as seen")
+	}
+
+	if !middleware.markSeen("current", time.Now().Add(time.Minute)) {
+		t.Fatalf("second signature was reported as seen")
+	}
+
+	if _, kept := middleware.seen["expired"]; kept {
+		t.Errorf("expired signature is still kept")
+	}
+
+	if middleware.markSeen("current", time.Now().Add(time.Minute)) {
+		t.Errorf("signature within the window was not reported as seen")
+	}
+}
@@ -1,7 +1,8 @@
 package middleware
 
 type MiddlewareList struct {
-	Auth *AuthMiddleware
+	Auth         *AuthMiddleware
+	CallbackAuth *CallbackAuthMiddleware
 }
 
 // For now we keep it the same as middleware. In future if we need any private variable then we can detach/append it.
@@ -33,6 +33,7 @@ const (
 	MsgVideoUploadNotAllowed    = "Video upload not allowed"
 	MsgVideoURLGenerationFailed = "Failed to generate video upload URL"
 	MsgDuplicateVideoTitle      = "The video title already exists."
+	MsgVideoUploadRetryExceeded = "Video upload retry limit reached"
 )
 
 // ErrorResponse represents a standardized error response
@@ -1,12 +1,16 @@
 package http
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+const shutdownTimeout = 10 * time.Second
+
 type RouterConfig struct {
 	Address string
 	Port    string
@@ -45,7 +49,26 @@ func NewRouter(cfg RouterConfig, routeRegistrars ...RouteRegistrar) *Router {
 	}
 }
 
-// Start starts the HTTP server
-func (r *Router) Start() error {
-	return r.engine.Run(fmt.Sprintf("%s:%s", r.address, r.port))
+// Start starts the HTTP server and gracefully shuts it down once the context is cancelled.
+func (r *Router) Start(ctx context.Context) error {
+	server := &http.Server{
+		Addr:    fmt.Sprintf("%s:%s", r.address, r.port),
+		Handler: r.engine,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	err := server.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+
+	return err
 }
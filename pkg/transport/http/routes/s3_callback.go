@@ -22,7 +22,7 @@ func NewAWSCallbackRouter(s3CbController *controller.S3CallbackController, middl
 func (r *AWSCallbackRouter) RegisterRoutes(router *gin.Engine) {
 	AwsGroup := router.Group("/api/v1/aws-cb")
 	{
-		AwsGroup.POST("/s3/upload-object", r.s3CbHandler.HandleVideoUploadEvent)
+		AwsGroup.POST("/s3/upload-object", r.middleware.CallbackAuth.Add(), r.s3CbHandler.HandleVideoUploadEvent)
 
 	}
 }
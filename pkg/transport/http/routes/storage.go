@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"fluxio-backend/pkg/transport/http/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LocalStorageRouter struct {
+	storageController *controller.LocalStorageController
+	basePath          string
+}
+
+// The base path has to match the path of the base URL the local storage signs its URLs with.
+func NewLocalStorageRouter(storageController *controller.LocalStorageController, basePath string) *LocalStorageRouter {
+	return &LocalStorageRouter{
+		storageController: storageController,
+		basePath:          basePath,
+	}
+}
+
+func (r *LocalStorageRouter) RegisterRoutes(router *gin.Engine) {
+	StorageGroup := router.Group(r.basePath)
+	{
+		StorageGroup.GET("/:bucket/*key", r.storageController.GetObject)
+		StorageGroup.HEAD("/:bucket/*key", r.storageController.GetObject)
+		StorageGroup.PUT("/:bucket/*key", r.storageController.PutObject)
+	}
+}
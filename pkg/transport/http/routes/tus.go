@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"fluxio-backend/pkg/transport/http/controller"
+	"fluxio-backend/pkg/transport/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TusRouter struct {
+	TusController *controller.TusController
+	middleware    *middleware.Middleware
+}
+
+func NewTusRouter(tusController *controller.TusController, middleware *middleware.Middleware) *TusRouter {
+	return &TusRouter{
+		TusController: tusController,
+		middleware:    middleware,
+	}
+}
+
+// RegisterRoutes registers the tus resumable upload routes
+func (r *TusRouter) RegisterRoutes(router *gin.Engine) {
+	TusGroup := router.Group("/api/v1/video/tus/files", r.TusController.RequireTusVersion())
+	{
+		TusGroup.OPTIONS("", r.TusController.Options)
+		TusGroup.POST("", r.middleware.Auth.Add(), r.TusController.Create)
+		TusGroup.HEAD("/:id", r.middleware.Auth.Add(), r.TusController.Head)
+		TusGroup.PATCH("/:id", r.middleware.Auth.Add(), r.TusController.Patch)
+		TusGroup.DELETE("/:id", r.middleware.Auth.Add(), r.TusController.Terminate)
+	}
+}
@@ -24,6 +24,18 @@ func (r *VideoRouter) RegisterRoutes(router *gin.Engine) {
 	VideoGroup := router.Group("/api/v1/video")
 	{
 		VideoGroup.POST("/upload-init", r.middleware.Auth.Add(), r.VideoController.CreateNewVideo)
+		VideoGroup.POST("/import", r.middleware.Auth.Add(), r.VideoController.ImportVideo)
+		VideoGroup.GET("/:slug", r.middleware.Auth.Add(), r.VideoController.GetVideo)
+		VideoGroup.GET("/:slug/events", r.middleware.Auth.Add(), r.VideoController.StreamVideoEvents)
+		VideoGroup.POST("/:slug/upload-url", r.middleware.Auth.Add(), r.VideoController.RegenerateUploadURL)
+		VideoGroup.POST("/:slug/thumbnails/regenerate", r.middleware.Auth.Add(), r.VideoController.RegenerateThumbnails)
+
+		// Multipart uploads for large files
+		VideoGroup.POST("/:slug/multipart", r.middleware.Auth.Add(), r.VideoController.InitiateMultipartUpload)
+		VideoGroup.GET("/:slug/multipart", r.middleware.Auth.Add(), r.VideoController.GetMultipartUpload)
+		VideoGroup.POST("/:slug/multipart/parts/:partNumber/url", r.middleware.Auth.Add(), r.VideoController.GenerateMultipartPartURL)
+		VideoGroup.POST("/:slug/multipart/complete", r.middleware.Auth.Add(), r.VideoController.CompleteMultipartUpload)
+		VideoGroup.DELETE("/:slug/multipart", r.middleware.Auth.Add(), r.VideoController.AbortMultipartUpload)
 
 	}
 }
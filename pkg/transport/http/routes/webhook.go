@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"fluxio-backend/pkg/transport/http/controller"
+	"fluxio-backend/pkg/transport/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookRouter struct {
+	WebhookController *controller.WebhookController
+	middleware        *middleware.Middleware
+}
+
+func NewWebhookRouter(webhookController *controller.WebhookController, middleware *middleware.Middleware) *WebhookRouter {
+	return &WebhookRouter{
+		WebhookController: webhookController,
+		middleware:        middleware,
+	}
+}
+
+// RegisterRoutes registers the webhook subscription routes
+func (r *WebhookRouter) RegisterRoutes(router *gin.Engine) {
+	WebhookGroup := router.Group("/api/v1/webhooks", r.middleware.Auth.Add())
+	{
+		WebhookGroup.POST("", r.WebhookController.CreateSubscription)
+		WebhookGroup.GET("", r.WebhookController.ListSubscriptions)
+		WebhookGroup.DELETE("/:id", r.WebhookController.DeleteSubscription)
+		WebhookGroup.GET("/:id/deliveries", r.WebhookController.ListDeliveries)
+	}
+}
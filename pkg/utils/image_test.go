@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// Returns an image whose colour at every position is given by the function.
+func testImage(width int, height int, pixel func(x, y int) color.RGBA) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, pixel(x, y))
+		}
+	}
+
+	return img
+}
+
+func solidImage(width int, height int, c color.RGBA) image.Image {
+	return testImage(width, height, func(x, y int) color.RGBA { return c })
+}
+
+// The expected hashes are the output of the reference encoder of https://github.com/woltapp/blurhash.
+func TestEncodeBlurHash(t *testing.T) {
+	tests := []struct {
+		name        string
+		img         image.Image
+		xComponents int
+		yComponents int
+		want        string
+	}{
+		{
+			name:        "black",
+			img:         solidImage(4, 3, color.RGBA{0, 0, 0, 255}),
+			xComponents: 4,
+			yComponents: 3,
+			want:        "L00000fQfQfQfQfQfQfQfQfQfQfQ",
+		},
+		{
+			name:        "white",
+			img:         solidImage(4, 3, color.RGBA{255, 255, 255, 255}),
+			xComponents: 4,
+			yComponents: 3,
+			want:        "L~TSUA~qfQ~q~q%MfQ%MfQfQfQfQ",
+		},
+		{
+			name:        "red with the average colour only",
+			img:         solidImage(8, 8, color.RGBA{255, 0, 0, 255}),
+			xComponents: 1,
+			yComponents: 1,
+			want:        "00TI:j",
+		},
+		{
+			name: "gradient",
+			img: testImage(32, 18, func(x, y int) color.RGBA {
+				return color.RGBA{uint8(x * 8), uint8(y * 14), 128, 255}
+			}),
+			xComponents: 4,
+			yComponents: 3,
+			want:        "LxH2M}2swxX8qRWDjte;gJfjfQfj",
+		},
+		{
+			name: "split",
+			img: testImage(32, 18, func(x, y int) color.RGBA {
+				if x < 16 {
+					return color.RGBA{255, 255, 255, 255}
+				}
+				return color.RGBA{20, 40, 200, 255}
+			}),
+			xComponents: 5,
+			yComponents: 4,
+			want:        "V~Lqhw~ot6IWfQt8ofj@ayfQfQfQfQfQfQt8ofj@ayfQ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := EncodeBlurHash(tt.img, tt.xComponents, tt.yComponents)
+			if err != nil {
+				t.Fatalf("encode failed: %v", err)
+			}
+
+			if hash != tt.want {
+				t.Errorf("got hash %q, want %q", hash, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeBlurHashRejectsInvalidComponents(t *testing.T) {
+	img := solidImage(4, 4, color.RGBA{128, 128, 128, 255})
+
+	for _, components := range [][2]int{{0, 3}, {4, 0}, {10, 3}, {4, 10}} {
+		_, err := EncodeBlurHash(img, components[0], components[1])
+		if err != errInvalidBlurHashComponents {
+			t.Errorf("got error %v for %d x %d components", err, components[0], components[1])
+		}
+	}
+}
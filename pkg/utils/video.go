@@ -4,6 +4,7 @@ import (
 	"fluxio-backend/pkg/constants"
 	"fmt"
 	"math/rand"
+	"net/http"
 	"slices"
 	"strings"
 	"time"
@@ -119,6 +120,23 @@ func CreateURLSafeThumbnailFileName(videoID string, timestamp string) (fileName
 	return "thumbnail-" + fileName
 }
 
+// Detects the type of a video file from its leading bytes. An empty string is returned when the content
+// cannot be identified, which is common for containers the standard sniffer does not know.
+func SniffVideoMimeType(head []byte) (mimeType string) {
+	detected, _, _ := strings.Cut(http.DetectContentType(head), ";")
+
+	switch detected {
+	case "video/avi":
+		return "video/x-msvideo"
+	case "application/ogg":
+		return "video/ogg"
+	case "application/octet-stream":
+		return ""
+	}
+
+	return detected
+}
+
 func CheckVideoMimeTypeValidity(mimeType string) (valid bool) {
 	valid = slices.Contains(constants.ValidVideoMimes, mimeType)
 	return
@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"context"
+	"fluxio-backend/pkg/common/schema"
+	"sync"
+	"time"
+)
+
+// TaskFunc is run periodically by the scheduler.
+type TaskFunc func(ctx context.Context) error
+
+type scheduledTask struct {
+	name     string
+	interval time.Duration
+	run      TaskFunc
+}
+
+// Scheduler runs maintenance tasks at a fixed interval. Tasks have to be safe to run from several
+// processes at the same time since every worker process runs its own scheduler.
+type Scheduler struct {
+	tasks []scheduledTask
+	l     schema.Logger
+}
+
+func NewScheduler(logger schema.Logger) *Scheduler {
+	return &Scheduler{
+		l: logger,
+	}
+}
+
+// Registers a task to run at the interval. Tasks with a non positive interval are ignored.
+func (s *Scheduler) Every(name string, interval time.Duration, task TaskFunc) {
+	if interval <= 0 {
+		s.l.Info("Scheduled task disabled", "task", name)
+		return
+	}
+
+	s.tasks = append(s.tasks, scheduledTask{
+		name:     name,
+		interval: interval,
+		run:      task,
+	})
+}
+
+// Start runs the registered tasks and blocks until the context is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, task := range s.tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.runTask(ctx, task)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (s *Scheduler) runTask(ctx context.Context, task scheduledTask) {
+	logger := s.l.With("task", task.name)
+
+	ticker := time.NewTicker(task.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := task.run(ctx)
+			if err != nil && ctx.Err() == nil {
+				logger.Error("Scheduled task failed", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fluxio-backend/pkg/common/schema"
+	fluxerrors "fluxio-backend/pkg/errors"
+	"fluxio-backend/pkg/model"
+	"fluxio-backend/pkg/service"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HandlerFunc executes a single job. Returning an error schedules a retry until the job runs out of attempts.
+type HandlerFunc func(ctx context.Context, job model.Job) error
+
+type WorkerConfig struct {
+	Concurrency   int
+	PollInterval  time.Duration
+	LeaseDuration time.Duration
+	RetryDelay    time.Duration
+}
+
+// Worker claims jobs from the persistent queue and executes them with the registered handlers.
+type Worker struct {
+	id       string
+	cfg      WorkerConfig
+	jobSvc   *service.JobService
+	handlers map[model.JobType]HandlerFunc
+	l        schema.Logger
+}
+
+func NewWorker(cfg WorkerConfig, jobSvc *service.JobService, logger schema.Logger) *Worker {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+
+	hostname, _ := os.Hostname()
+	id := fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8])
+
+	return &Worker{
+		id:       id,
+		cfg:      cfg,
+		jobSvc:   jobSvc,
+		handlers: map[model.JobType]HandlerFunc{},
+		l:        logger.With("worker_id", id),
+	}
+}
+
+// Registers the handler for a job type. Only job types with a handler are claimed.
+func (w *Worker) RegisterHandler(jobType model.JobType, handler HandlerFunc) {
+	w.handlers[jobType] = handler
+}
+
+// Start runs the worker loops and blocks until the context is cancelled and the running jobs return.
+func (w *Worker) Start(ctx context.Context) {
+	jobTypes := make([]model.JobType, 0, len(w.handlers))
+	for jobType := range w.handlers {
+		jobTypes = append(jobTypes, jobType)
+	}
+
+	w.l.Info("Starting worker", "concurrency", w.cfg.Concurrency)
+
+	var wg sync.WaitGroup
+	for range w.cfg.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx, jobTypes)
+		}()
+	}
+
+	wg.Wait()
+	w.l.Info("Worker stopped")
+}
+
+func (w *Worker) loop(ctx context.Context, jobTypes []model.JobType) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		job, err := w.jobSvc.Claim(ctx, w.id, jobTypes, w.cfg.LeaseDuration)
+		if err != nil {
+			// Back off when the queue is empty or the database is unavailable.
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(w.cfg.PollInterval):
+			}
+			continue
+		}
+
+		w.execute(ctx, job)
+	}
+}
+
+// Executes a claimed job while keeping its lease alive and records the outcome.
+func (w *Worker) execute(ctx context.Context, job model.Job) {
+	logger := w.l.With("job_id", job.ID.String()).With("job_type", job.Type.String()).With("attempt", job.Attempts)
+	logger.Info("Executing job")
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go w.keepLeaseAlive(jobCtx, cancel, job, logger)
+
+	err := w.runHandler(jobCtx, job)
+
+	// Use a fresh context so that the outcome is still recorded while the worker shuts down.
+	stateCtx, stateCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer stateCancel()
+
+	if err == nil {
+		stateErr := w.jobSvc.Complete(stateCtx, job)
+		if stateErr != nil {
+			logger.Error("Failed to mark the job as completed", stateErr)
+			return
+		}
+
+		logger.Info("Job completed")
+		return
+	}
+
+	logger.Error("Job execution failed", err)
+
+	// The lease was lost so another worker owns the job now.
+	if jobCtx.Err() != nil && ctx.Err() == nil {
+		return
+	}
+
+	// The worker is shutting down so hand the job back to the queue right away.
+	if ctx.Err() != nil {
+		stateErr := w.jobSvc.Retry(stateCtx, job, time.Now(), err)
+		if stateErr != nil {
+			logger.Error("Failed to release the job", stateErr)
+		}
+		return
+	}
+
+	// The handler scheduled its own retry.
+	retryErr := &model.JobRetryError{}
+	if errors.As(err, &retryErr) {
+		logger.Info("Job rescheduled by the handler", "run_at", retryErr.RunAt)
+		stateErr := w.jobSvc.Reschedule(stateCtx, job, retryErr.RunAt, retryErr.Err)
+		if stateErr != nil {
+			logger.Error("Failed to reschedule the job", stateErr)
+		}
+		return
+	}
+
+	permanentErr := &model.JobPermanentError{}
+	if errors.As(err, &permanentErr) || job.Attempts >= job.MaxAttempts {
+		stateErr := w.jobSvc.Fail(stateCtx, job, err)
+		if stateErr != nil {
+			logger.Error("Failed to mark the job as failed", stateErr)
+		}
+		return
+	}
+
+	stateErr := w.jobSvc.Retry(stateCtx, job, time.Now().Add(w.cfg.RetryDelay), err)
+	if stateErr != nil {
+		logger.Error("Failed to schedule the job retry", stateErr)
+	}
+}
+
+// Runs the job handler and converts a panic into a job failure so it does not take down the worker.
+func (w *Worker) runHandler(ctx context.Context, job model.Job) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("job handler panicked: %v", rec)
+		}
+	}()
+
+	return w.handlers[job.Type](ctx, job)
+}
+
+// Periodically extends the job lease. The job context is cancelled if the lease is lost.
+func (w *Worker) keepLeaseAlive(ctx context.Context, cancel context.CancelFunc, job model.Job, logger schema.Logger) {
+	ticker := time.NewTicker(w.cfg.LeaseDuration / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := w.jobSvc.ExtendLease(ctx, job, w.cfg.LeaseDuration)
+			if err == fluxerrors.ErrJobLeaseLost {
+				logger.Warn("Job lease lost, cancelling the job")
+				cancel()
+				return
+			}
+
+			if err != nil {
+				logger.Error("Failed to extend the job lease", err)
+			}
+		}
+	}
+}